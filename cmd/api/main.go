@@ -2,17 +2,22 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"url-crawler/internal/config"
 	"url-crawler/internal/server"
 )
 
-func gracefulShutdown(apiServer *http.Server, done chan bool) {
+// gracefulShutdown waits for an interrupt, then stops the HTTP server and the
+// background services, giving running requests and crawls gracePeriod to finish
+func gracefulShutdown(apiServer *http.Server, shutdown func(context.Context), gracePeriod time.Duration, done chan bool) {
 	// Create context that listens for the interrupt signal from the OS.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -23,29 +28,72 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 	log.Println("shutting down gracefully, press Ctrl+C again to force")
 	stop() // Allow Ctrl+C to force shutdown
 
-	// The context is used to inform the server it has 5 seconds to finish
-	// the request it is currently handling
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// The context is used to inform the server and the crawl queue how long they
+	// have to finish the requests and crawls they are currently handling
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
 	defer cancel()
+
+	log.Printf("Shutdown: draining HTTP requests (grace period %s)", gracePeriod)
 	if err := apiServer.Shutdown(ctx); err != nil {
 		log.Printf("Server forced to shutdown with error: %v", err)
 	}
 
+	shutdown(ctx)
+
 	log.Println("Server exiting")
 
 	// Notify the main goroutine that the shutdown is complete
 	done <- true
 }
 
+// runWorker processes crawl jobs until the process is interrupted, then calls
+// stop, giving running crawls gracePeriod to finish
+func runWorker(stop func(context.Context), gracePeriod time.Duration) {
+	ctx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	<-ctx.Done()
+
+	log.Println("shutting down worker, press Ctrl+C again to force")
+	stopSignals() // Allow Ctrl+C to force shutdown
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	stop(shutdownCtx)
+	log.Println("Worker exiting")
+}
+
 func main() {
+	mode := flag.String("mode", "", "run mode: api, worker or both (overrides APP_MODE)")
+	flag.Parse()
+
+	// Load configuration
+	cfg := config.Load()
+	if *mode != "" {
+		cfg.Server.Mode = strings.ToLower(*mode)
+	}
+
+	// Validate configuration
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Configuration validation failed: %v", err)
+	}
+
+	// Log configuration (without sensitive data)
+	cfg.LogConfig()
+
+	if !cfg.Server.RunsAPI() {
+		runWorker(server.NewWorker(cfg), cfg.Server.ShutdownGracePeriod)
+		return
+	}
 
-	server := server.NewServer()
+	server, shutdown := server.NewServer(cfg)
 
 	// Create a done channel to signal when the shutdown is complete
 	done := make(chan bool, 1)
 
 	// Run graceful shutdown in a separate goroutine
-	go gracefulShutdown(server, done)
+	go gracefulShutdown(server, shutdown, cfg.Server.ShutdownGracePeriod, done)
 
 	err := server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
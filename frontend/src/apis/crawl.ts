@@ -22,9 +22,17 @@ export interface StatsResponse {
   database: CrawlStats;
   queue: {
     queue_length: number;
+    queue_depth?: Record<"high" | "normal" | "low", number>;
     active_tasks: number;
     workers: number;
     running: boolean;
+    instances?: {
+      id: string;
+      hostname: string;
+      concurrency: number;
+      activeTasks: number;
+      lastHeartbeatAt: string;
+    }[];
   };
   timestamp: string;
 }
@@ -74,6 +74,8 @@ export const CrawlResultDetail: React.FC<CrawlResultDetailProps> = ({
         return `${baseClasses} bg-yellow-100 text-yellow-800`;
       case "error":
         return `${baseClasses} bg-red-100 text-red-800`;
+      case "cancelled":
+        return `${baseClasses} bg-gray-100 text-gray-800`;
       default:
         return baseClasses;
     }
@@ -68,6 +68,8 @@ export const CrawlResultsTable: React.FC<CrawlResultsTableProps> = ({
         return `${baseClasses} bg-yellow-100 text-yellow-800`;
       case "error":
         return `${baseClasses} bg-red-100 text-red-800`;
+      case "cancelled":
+        return `${baseClasses} bg-gray-100 text-gray-800`;
       default:
         return baseClasses;
     }
@@ -209,6 +211,7 @@ export const CrawlResultsTable: React.FC<CrawlResultsTableProps> = ({
                 <option value="running">Running</option>
                 <option value="queued">Queued</option>
                 <option value="error">Error</option>
+                <option value="cancelled">Cancelled</option>
               </select>
             </div>
           </div>
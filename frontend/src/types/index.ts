@@ -7,6 +7,7 @@ export interface CrawlResult {
   htmlVersion: string;
   internalLinksCount: number;
   externalLinksCount: number;
+  otherLinksCount?: number;
   inaccessibleLinksCount: number;
   hasLoginForm: boolean;
   headingCounts: {
@@ -20,6 +21,9 @@ export interface CrawlResult {
   brokenLinks: BrokenLink[];
   externalLinks?: string[];
   status: CrawlStatus;
+  errorMessage?: string;
+  attempts?: number;
+  lastError?: string;
   createdAt: Date;
   updatedAt: Date;
 }
@@ -30,10 +34,18 @@ export interface BrokenLink {
   statusText: string;
 }
 
-export type CrawlStatus = "queued" | "running" | "completed" | "error";
+export type CrawlStatus =
+  | "queued"
+  | "running"
+  | "completed"
+  | "error"
+  | "cancelled";
+
+export type CrawlPriority = "high" | "normal" | "low";
 
 export interface CrawlRequest {
   url: string;
+  priority?: CrawlPriority;
 }
 
 export interface TableFilter {
@@ -68,4 +80,5 @@ export interface CrawlStats {
   running: number;
   completed: number;
   error: number;
+  cancelled: number;
 }
@@ -12,19 +12,26 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Crawler  CrawlerConfig
-	Queue    QueueConfig
-	Auth     AuthConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Crawler   CrawlerConfig
+	Queue     QueueConfig
+	Scheduler SchedulerConfig
+	Webhooks  WebhookConfig
+	Events    EventsConfig
+	Auth      AuthConfig
 }
 
 type ServerConfig struct {
+	Mode         string // which roles this process runs: api, worker or both
 	Port         int
 	Host         string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+
+	// How long running requests and crawls are given to finish on shutdown
+	ShutdownGracePeriod time.Duration
 }
 
 type DatabaseConfig struct {
@@ -39,26 +46,98 @@ type DatabaseConfig struct {
 }
 
 type CrawlerConfig struct {
+	Backend          string
 	Timeout          time.Duration
 	UserAgent        string
 	MaxRedirects     int
 	MaxLinksToCheck  int
+	LinkCheckWorkers int
 	RequestDelay     time.Duration
 	MaxContentSize   int64
 	AllowedDomains   []string
 	BlockedDomains   []string
 	RespectRobotsTxt bool
 
+	// Link classification: treat other hosts under the same registrable domain as internal
+	SubdomainsInternal bool
+
+	// SSRF protection
+	BlockPrivateNetworks    bool
+	PrivateNetworkAllowlist []string
+
 	// Firecrawl configuration
 	FirecrawlAPIKey string
 	FirecrawlAPIURL string
 }
 
+// Supported run modes
+const (
+	RunModeAPI    = "api"
+	RunModeWorker = "worker"
+	RunModeBoth   = "both"
+)
+
+// RunsAPI reports whether the process serves the HTTP API
+func (c ServerConfig) RunsAPI() bool {
+	return c.Mode == RunModeAPI || c.Mode == RunModeBoth
+}
+
+// RunsWorkers reports whether the process runs crawl workers
+func (c ServerConfig) RunsWorkers() bool {
+	return c.Mode == RunModeWorker || c.Mode == RunModeBoth
+}
+
+// Supported crawler backends
+const (
+	CrawlerBackendAuto      = "auto"
+	CrawlerBackendHTTP      = "http"
+	CrawlerBackendFirecrawl = "firecrawl"
+)
+
+// Supported rate limit backends
+const (
+	RateLimitBackendMemory = "memory"
+	RateLimitBackendMySQL  = "mysql"
+)
+
+// TaskTimeoutRequests is how many crawler request timeouts a crawl task gets
+// when QUEUE_TASK_TIMEOUT is unset
+const TaskTimeoutRequests = 4
+
 type QueueConfig struct {
-	Workers    int
-	BufferSize int
-	MaxRetries int
-	RetryDelay time.Duration
+	Workers     int
+	BufferSize  int
+	MaxRetries  int
+	RetryDelay  time.Duration
+	TaskTimeout time.Duration // deadline of a whole crawl, including its link checks
+
+	// Durable job queue: how long a claimed job stays leased to a worker
+	// without a heartbeat, and how often idle workers poll for new jobs
+	LeaseDuration time.Duration
+	PollInterval  time.Duration
+}
+
+type SchedulerConfig struct {
+	// How often recurring crawl schedules are checked for due runs
+	Interval time.Duration
+}
+
+type WebhookConfig struct {
+	// Delivery attempts per notification before it is marked failed, and the
+	// base delay of the exponential backoff between them
+	MaxAttempts int
+	RetryDelay  time.Duration
+
+	// Timeout of a single delivery request, and how often pending deliveries are polled
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+type EventsConfig struct {
+	// How long crawl state changes are kept for streams resuming with Last-Event-ID,
+	// and how often streams poll for changes made by other instances
+	Retention    time.Duration
+	PollInterval time.Duration
 }
 
 type AuthConfig struct {
@@ -67,15 +146,28 @@ type AuthConfig struct {
 	RateLimitEnabled  bool
 	RequestsPerMinute int
 	RateLimitWindow   time.Duration
+
+	// Separate, usually lower, limit for requests that create or change state
+	CreateRequestsPerMinute int
+
+	// Where request counts are kept: in memory per instance, or in MySQL so
+	// that the limits hold across all API instances
+	RateLimitBackend string
+
+	// How long a response is kept for replay to retries sending the same Idempotency-Key
+	IdempotencyKeyTTL time.Duration
 }
 
 func Load() *Config {
 	return &Config{
-		Server:   loadServerConfig(),
-		Database: loadDatabaseConfig(),
-		Crawler:  loadCrawlerConfig(),
-		Queue:    loadQueueConfig(),
-		Auth:     loadAuthConfig(),
+		Server:    loadServerConfig(),
+		Database:  loadDatabaseConfig(),
+		Crawler:   loadCrawlerConfig(),
+		Queue:     loadQueueConfig(),
+		Scheduler: loadSchedulerConfig(),
+		Webhooks:  loadWebhookConfig(),
+		Events:    loadEventsConfig(),
+		Auth:      loadAuthConfig(),
 	}
 }
 
@@ -85,13 +177,17 @@ func loadServerConfig() ServerConfig {
 	readTimeout, _ := time.ParseDuration(getEnv("SERVER_READ_TIMEOUT", "10s"))
 	writeTimeout, _ := time.ParseDuration(getEnv("SERVER_WRITE_TIMEOUT", "30s"))
 	idleTimeout, _ := time.ParseDuration(getEnv("SERVER_IDLE_TIMEOUT", "60s"))
+	shutdownGracePeriod, _ := time.ParseDuration(getEnv("SHUTDOWN_GRACE_PERIOD", "20s"))
 
 	return ServerConfig{
+		Mode:         strings.ToLower(getEnv("APP_MODE", RunModeBoth)),
 		Port:         port,
 		Host:         getEnv("HOST", ""),
 		ReadTimeout:  readTimeout,
 		WriteTimeout: writeTimeout,
 		IdleTimeout:  idleTimeout,
+
+		ShutdownGracePeriod: shutdownGracePeriod,
 	}
 }
 
@@ -116,28 +212,42 @@ func loadCrawlerConfig() CrawlerConfig {
 	timeout, _ := time.ParseDuration(getEnv("CRAWLER_TIMEOUT", "30s"))
 	maxRedirects, _ := strconv.Atoi(getEnv("CRAWLER_MAX_REDIRECTS", "10"))
 	maxLinksToCheck, _ := strconv.Atoi(getEnv("CRAWLER_MAX_LINKS_CHECK", "10"))
+	linkCheckWorkers, _ := strconv.Atoi(getEnv("CRAWLER_LINK_CHECK_WORKERS", "5"))
+	subdomainsInternal, _ := strconv.ParseBool(getEnv("CRAWLER_SUBDOMAINS_INTERNAL", "true"))
 	requestDelay, _ := time.ParseDuration(getEnv("CRAWLER_REQUEST_DELAY", "100ms"))
 	maxContentSize, _ := strconv.ParseInt(getEnv("CRAWLER_MAX_CONTENT_SIZE", "10485760"), 10, 64) // 10MB
 	respectRobots, _ := strconv.ParseBool(getEnv("CRAWLER_RESPECT_ROBOTS", "true"))
+	blockPrivateNetworks, _ := strconv.ParseBool(getEnv("CRAWLER_BLOCK_PRIVATE_NETWORKS", "true"))
 
 	allowedDomains := strings.Split(getEnv("CRAWLER_ALLOWED_DOMAINS", ""), ",")
 	blockedDomains := strings.Split(getEnv("CRAWLER_BLOCKED_DOMAINS", ""), ",")
+	privateAllowlist := strings.Split(getEnv("CRAWLER_PRIVATE_ALLOWLIST", ""), ",")
 
 	// Clean up empty strings from domain lists
 	allowedDomains = filterEmptyStrings(allowedDomains)
 	blockedDomains = filterEmptyStrings(blockedDomains)
+	privateAllowlist = filterEmptyStrings(privateAllowlist)
 
 	return CrawlerConfig{
+		Backend:          strings.ToLower(getEnv("CRAWLER_BACKEND", CrawlerBackendAuto)),
 		Timeout:          timeout,
 		UserAgent:        getEnv("CRAWLER_USER_AGENT", "URL-Crawler-Bot/1.0"),
 		MaxRedirects:     maxRedirects,
 		MaxLinksToCheck:  maxLinksToCheck,
+		LinkCheckWorkers: linkCheckWorkers,
 		RequestDelay:     requestDelay,
 		MaxContentSize:   maxContentSize,
 		AllowedDomains:   allowedDomains,
 		BlockedDomains:   blockedDomains,
 		RespectRobotsTxt: respectRobots,
 
+		// Link classification
+		SubdomainsInternal: subdomainsInternal,
+
+		// SSRF protection
+		BlockPrivateNetworks:    blockPrivateNetworks,
+		PrivateNetworkAllowlist: privateAllowlist,
+
 		// Firecrawl configuration
 		FirecrawlAPIKey: getEnv("FIRECRAWL_API_KEY", ""),
 		FirecrawlAPIURL: getEnv("FIRECRAWL_API_URL", ""),
@@ -149,12 +259,53 @@ func loadQueueConfig() QueueConfig {
 	bufferSize, _ := strconv.Atoi(getEnv("QUEUE_BUFFER_SIZE", "100"))
 	maxRetries, _ := strconv.Atoi(getEnv("QUEUE_MAX_RETRIES", "3"))
 	retryDelay, _ := time.ParseDuration(getEnv("QUEUE_RETRY_DELAY", "5s"))
+	// CRAWLER_TIMEOUT bounds a single HTTP request, while a task covers the page
+	// fetch and every link check, so by default it gets several request timeouts
+	crawlerTimeout, _ := time.ParseDuration(getEnv("CRAWLER_TIMEOUT", "30s"))
+	taskTimeout, _ := time.ParseDuration(getEnv("QUEUE_TASK_TIMEOUT", (TaskTimeoutRequests * crawlerTimeout).String()))
+	leaseDuration, _ := time.ParseDuration(getEnv("QUEUE_LEASE_DURATION", "1m"))
+	pollInterval, _ := time.ParseDuration(getEnv("QUEUE_POLL_INTERVAL", "1s"))
 
 	return QueueConfig{
-		Workers:    workers,
-		BufferSize: bufferSize,
-		MaxRetries: maxRetries,
-		RetryDelay: retryDelay,
+		Workers:       workers,
+		BufferSize:    bufferSize,
+		MaxRetries:    maxRetries,
+		RetryDelay:    retryDelay,
+		TaskTimeout:   taskTimeout,
+		LeaseDuration: leaseDuration,
+		PollInterval:  pollInterval,
+	}
+}
+
+func loadSchedulerConfig() SchedulerConfig {
+	interval, _ := time.ParseDuration(getEnv("SCHEDULER_INTERVAL", "15s"))
+
+	return SchedulerConfig{
+		Interval: interval,
+	}
+}
+
+func loadWebhookConfig() WebhookConfig {
+	maxAttempts, _ := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "5"))
+	retryDelay, _ := time.ParseDuration(getEnv("WEBHOOK_RETRY_DELAY", "10s"))
+	timeout, _ := time.ParseDuration(getEnv("WEBHOOK_TIMEOUT", "10s"))
+	pollInterval, _ := time.ParseDuration(getEnv("WEBHOOK_POLL_INTERVAL", "5s"))
+
+	return WebhookConfig{
+		MaxAttempts:  maxAttempts,
+		RetryDelay:   retryDelay,
+		Timeout:      timeout,
+		PollInterval: pollInterval,
+	}
+}
+
+func loadEventsConfig() EventsConfig {
+	retention, _ := time.ParseDuration(getEnv("EVENTS_RETENTION", "1h"))
+	pollInterval, _ := time.ParseDuration(getEnv("EVENTS_POLL_INTERVAL", "1s"))
+
+	return EventsConfig{
+		Retention:    retention,
+		PollInterval: pollInterval,
 	}
 }
 
@@ -163,6 +314,9 @@ func loadAuthConfig() AuthConfig {
 	rateLimitEnabled, _ := strconv.ParseBool(getEnv("RATE_LIMIT_ENABLED", "true"))
 	requestsPerMinute, _ := strconv.Atoi(getEnv("RATE_LIMIT_REQUESTS_PER_MINUTE", "60"))
 	rateLimitWindow, _ := time.ParseDuration(getEnv("RATE_LIMIT_WINDOW", "1m"))
+	createRequestsPerMinute, _ := strconv.Atoi(getEnv("RATE_LIMIT_CREATE_REQUESTS_PER_MINUTE", "20"))
+	rateLimitBackend := strings.ToLower(getEnv("RATE_LIMIT_BACKEND", RateLimitBackendMemory))
+	idempotencyKeyTTL, _ := time.ParseDuration(getEnv("IDEMPOTENCY_KEY_TTL", "24h"))
 
 	// Load API keys from environment
 	apiKeys := make(map[string]string)
@@ -189,6 +343,10 @@ func loadAuthConfig() AuthConfig {
 		RateLimitEnabled:  rateLimitEnabled,
 		RequestsPerMinute: requestsPerMinute,
 		RateLimitWindow:   rateLimitWindow,
+		IdempotencyKeyTTL: idempotencyKeyTTL,
+
+		CreateRequestsPerMinute: createRequestsPerMinute,
+		RateLimitBackend:        rateLimitBackend,
 	}
 }
 
@@ -212,6 +370,12 @@ func filterEmptyStrings(slice []string) []string {
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
+	switch c.Server.Mode {
+	case RunModeAPI, RunModeWorker, RunModeBoth:
+	default:
+		return ErrInvalidRunMode
+	}
+
 	if c.Server.Port <= 0 || c.Server.Port > 65535 {
 		return ErrInvalidPort
 	}
@@ -224,6 +388,22 @@ func (c *Config) Validate() error {
 		return ErrMissingDBUsername
 	}
 
+	switch c.Crawler.Backend {
+	case CrawlerBackendAuto, CrawlerBackendHTTP:
+	case CrawlerBackendFirecrawl:
+		if c.Crawler.FirecrawlAPIKey == "" {
+			return ErrMissingFirecrawlKey
+		}
+	default:
+		return ErrInvalidCrawlerBackend
+	}
+
+	switch c.Auth.RateLimitBackend {
+	case RateLimitBackendMemory, RateLimitBackendMySQL:
+	default:
+		return ErrInvalidRateLimitBackend
+	}
+
 	if c.Queue.Workers <= 0 {
 		return ErrInvalidWorkerCount
 	}
@@ -237,21 +417,36 @@ func (c *Config) Validate() error {
 
 // Configuration errors
 var (
+	ErrInvalidRunMode     = fmt.Errorf("run mode must be one of api, worker or both")
 	ErrInvalidPort        = fmt.Errorf("invalid port number")
 	ErrMissingDBHost      = fmt.Errorf("database host is required")
 	ErrMissingDBUsername  = fmt.Errorf("database username is required")
 	ErrInvalidWorkerCount = fmt.Errorf("worker count must be greater than 0")
 	ErrInvalidBufferSize  = fmt.Errorf("buffer size must be greater than 0")
+
+	ErrInvalidCrawlerBackend = fmt.Errorf("crawler backend must be one of auto, http or firecrawl")
+	ErrMissingFirecrawlKey   = fmt.Errorf("FIRECRAWL_API_KEY is required for the firecrawl backend")
+
+	ErrInvalidRateLimitBackend = fmt.Errorf("rate limit backend must be one of memory or mysql")
 )
 
 // LogConfig logs the current configuration (without sensitive data)
 func (c *Config) LogConfig() {
 	log.Println("=== URL Crawler Configuration ===")
+	log.Printf("Run Mode: %s", c.Server.Mode)
 	log.Printf("Server: %s:%d", c.Server.Host, c.Server.Port)
+	log.Printf("Shutdown Grace Period: %s", c.Server.ShutdownGracePeriod)
 	log.Printf("Database: %s:%s@%s:%s/%s", c.Database.Username, "***", c.Database.Host, c.Database.Port, c.Database.Database)
 	log.Printf("Queue Workers: %d", c.Queue.Workers)
+	log.Printf("Queue Task Timeout: %s", c.Queue.TaskTimeout)
+	log.Printf("Queue Lease Duration: %s", c.Queue.LeaseDuration)
+	log.Printf("Scheduler Interval: %s", c.Scheduler.Interval)
+	log.Printf("Webhook Max Attempts: %d", c.Webhooks.MaxAttempts)
+	log.Printf("Event Retention: %s", c.Events.Retention)
 	log.Printf("Auth Required: %t", c.Auth.RequireAuth)
-	log.Printf("Rate Limiting: %t", c.Auth.RateLimitEnabled)
+	log.Printf("Rate Limiting: %t (%s backend)", c.Auth.RateLimitEnabled, c.Auth.RateLimitBackend)
+	log.Printf("Idempotency Key TTL: %s", c.Auth.IdempotencyKeyTTL)
+	log.Printf("Crawler Backend: %s", c.Crawler.Backend)
 	log.Printf("Crawler Timeout: %s", c.Crawler.Timeout)
 	log.Printf("Crawler User Agent: %s", c.Crawler.UserAgent)
 	log.Println("=================================")
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"url-crawler/internal/models"
+)
+
+// SaveBatch inserts a new batch record
+func (cs *CrawlStorage) SaveBatch(ctx context.Context, batch *models.Batch) error {
+	query := `
+		INSERT INTO crawl_batches (id, owner, source, created_at)
+		VALUES (?, ?, ?, ?)
+	`
+
+	_, err := cs.db.ExecContext(ctx, query, batch.ID, batch.Owner, batch.Source, batch.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save batch: %w", err)
+	}
+
+	return nil
+}
+
+// GetBatch retrieves a batch submitted with the given API key
+func (cs *CrawlStorage) GetBatch(ctx context.Context, id, owner string) (*models.Batch, error) {
+	query := `
+		SELECT id, owner, source, created_at
+		FROM crawl_batches
+		WHERE id = ? AND owner = ?
+	`
+
+	batch := &models.Batch{}
+	err := cs.db.QueryRowContext(ctx, query, id, owner).Scan(
+		&batch.ID,
+		&batch.Owner,
+		&batch.Source,
+		&batch.CreatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("batch not found")
+		}
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	return batch, nil
+}
+
+// GetBatchProgress counts the crawls of a batch by status
+func (cs *CrawlStorage) GetBatchProgress(ctx context.Context, batchID string) (*models.BatchProgress, error) {
+	query := `
+		SELECT
+			COUNT(*) as total,
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END), 0) as completed,
+			COALESCE(SUM(CASE WHEN status = 'error' THEN 1 ELSE 0 END), 0) as error,
+			COALESCE(SUM(CASE WHEN status = 'cancelled' THEN 1 ELSE 0 END), 0) as cancelled,
+			COALESCE(SUM(CASE WHEN status IN ('queued', 'running') THEN 1 ELSE 0 END), 0) as pending
+		FROM crawl_results
+		WHERE batch_id = ?
+	`
+
+	progress := &models.BatchProgress{}
+	err := cs.db.QueryRowContext(ctx, query, batchID).Scan(
+		&progress.Total,
+		&progress.Completed,
+		&progress.Error,
+		&progress.Cancelled,
+		&progress.Pending,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch progress: %w", err)
+	}
+
+	return progress, nil
+}
+
+// GetBatchMemberIDs returns the IDs of the crawls in a batch, oldest first
+func (cs *CrawlStorage) GetBatchMemberIDs(ctx context.Context, batchID string) ([]string, error) {
+	query := `
+		SELECT id
+		FROM crawl_results
+		WHERE batch_id = ?
+		ORDER BY created_at ASC, id ASC
+	`
+
+	rows, err := cs.db.QueryContext(ctx, query, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch members: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan batch member: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating batch members: %w", err)
+	}
+
+	return ids, nil
+}
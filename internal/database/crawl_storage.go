@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
@@ -14,48 +15,114 @@ type CrawlStorage struct {
 	db *sql.DB
 }
 
+// crawlResultColumns lists the crawl_results columns in the order scanCrawlResult reads them
+const crawlResultColumns = `id, url, title, html_version, internal_links_count, external_links_count,
+	other_links_count, inaccessible_links_count, has_login_form, heading_counts, broken_links,
+	external_links, checked_links, status, error_message, attempts, last_error, parent_id, site_crawl_id,
+	depth, owner, webhook_id, batch_id, created_at, updated_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCrawlResult scans a row selected with crawlResultColumns
+func scanCrawlResult(row rowScanner, result *models.CrawlResult) error {
+	return row.Scan(
+		&result.ID,
+		&result.URL,
+		&result.Title,
+		&result.HTMLVersion,
+		&result.InternalLinksCount,
+		&result.ExternalLinksCount,
+		&result.OtherLinksCount,
+		&result.InaccessibleLinksCount,
+		&result.HasLoginForm,
+		&result.HeadingCounts,
+		&result.BrokenLinks,
+		&result.ExternalLinks,
+		&result.CheckedLinks,
+		&result.Status,
+		&result.ErrorMessage,
+		&result.Attempts,
+		&result.LastError,
+		&result.ParentID,
+		&result.SiteCrawlID,
+		&result.Depth,
+		&result.Owner,
+		&result.WebhookID,
+		&result.BatchID,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+	)
+}
+
 // NewCrawlStorage creates a new crawl storage instance
 func NewCrawlStorage(db *sql.DB) *CrawlStorage {
 	return &CrawlStorage{db: db}
 }
 
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
 // SaveCrawlResult saves or updates a crawl result in the database
-func (cs *CrawlStorage) SaveCrawlResult(result *models.CrawlResult) error {
+func (cs *CrawlStorage) SaveCrawlResult(ctx context.Context, result *models.CrawlResult) error {
+	return saveCrawlResult(ctx, cs.db, result)
+}
+
+// saveCrawlResult upserts a crawl result using the given connection or transaction
+func saveCrawlResult(ctx context.Context, db execer, result *models.CrawlResult) error {
 	query := `
 		INSERT INTO crawl_results (
 			id, url, title, html_version, internal_links_count, external_links_count,
-			inaccessible_links_count, has_login_form, heading_counts, broken_links,
-			external_links, status, error_message, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			other_links_count, inaccessible_links_count, has_login_form, heading_counts, broken_links,
+			external_links, checked_links, status, error_message, attempts, last_error, parent_id, site_crawl_id,
+			depth, owner, webhook_id, batch_id, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
 			title = VALUES(title),
 			html_version = VALUES(html_version),
 			internal_links_count = VALUES(internal_links_count),
 			external_links_count = VALUES(external_links_count),
+			other_links_count = VALUES(other_links_count),
 			inaccessible_links_count = VALUES(inaccessible_links_count),
 			has_login_form = VALUES(has_login_form),
 			heading_counts = VALUES(heading_counts),
 			broken_links = VALUES(broken_links),
 			external_links = VALUES(external_links),
+			checked_links = VALUES(checked_links),
 			status = VALUES(status),
 			error_message = VALUES(error_message),
+			attempts = VALUES(attempts),
 			updated_at = VALUES(updated_at)
 	`
 
-	_, err := cs.db.Exec(query,
+	_, err := db.ExecContext(ctx, query,
 		result.ID,
 		result.URL,
 		result.Title,
 		result.HTMLVersion,
 		result.InternalLinksCount,
 		result.ExternalLinksCount,
+		result.OtherLinksCount,
 		result.InaccessibleLinksCount,
 		result.HasLoginForm,
 		result.HeadingCounts,
 		result.BrokenLinks,
 		result.ExternalLinks,
+		result.CheckedLinks,
 		result.Status,
 		result.ErrorMessage,
+		result.Attempts,
+		result.LastError,
+		result.ParentID,
+		result.SiteCrawlID,
+		result.Depth,
+		result.Owner,
+		result.WebhookID,
+		result.BatchID,
 		result.CreatedAt,
 		result.UpdatedAt,
 	)
@@ -68,14 +135,14 @@ func (cs *CrawlStorage) SaveCrawlResult(result *models.CrawlResult) error {
 }
 
 // UpdateCrawlStatus updates only the status and error message of a crawl result
-func (cs *CrawlStorage) UpdateCrawlStatus(id string, status models.CrawlStatus, errorMsg *string) error {
+func (cs *CrawlStorage) UpdateCrawlStatus(ctx context.Context, id string, status models.CrawlStatus, errorMsg *string) error {
 	query := `
 		UPDATE crawl_results 
 		SET status = ?, error_message = ?, updated_at = ? 
 		WHERE id = ?
 	`
 
-	_, err := cs.db.Exec(query, status, errorMsg, time.Now(), id)
+	_, err := cs.db.ExecContext(ctx, query, status, errorMsg, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to update crawl status: %w", err)
 	}
@@ -83,37 +150,35 @@ func (cs *CrawlStorage) UpdateCrawlStatus(id string, status models.CrawlStatus,
 	return nil
 }
 
+// UpdateCrawlAttempts records how many attempts a crawl has made and why the last one failed
+func (cs *CrawlStorage) UpdateCrawlAttempts(ctx context.Context, id string, attempts int, lastError *string) error {
+	query := `
+		UPDATE crawl_results
+		SET attempts = ?, last_error = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	_, err := cs.db.ExecContext(ctx, query, attempts, lastError, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update crawl attempts: %w", err)
+	}
+
+	return nil
+}
+
 // GetCrawlResult retrieves a single crawl result by ID
-func (cs *CrawlStorage) GetCrawlResult(id string) (*models.CrawlResult, error) {
+func (cs *CrawlStorage) GetCrawlResult(ctx context.Context, id string) (*models.CrawlResult, error) {
 	query := `
-		SELECT id, url, title, html_version, internal_links_count, external_links_count,
-			   inaccessible_links_count, has_login_form, heading_counts, broken_links,
-			   external_links, status, error_message, created_at, updated_at
+		SELECT ` + crawlResultColumns + `
 		FROM crawl_results 
 		WHERE id = ?
 	`
 
-	row := cs.db.QueryRow(query, id)
+	row := cs.db.QueryRowContext(ctx, query, id)
 
 	result := &models.CrawlResult{}
 
-	err := row.Scan(
-		&result.ID,
-		&result.URL,
-		&result.Title,
-		&result.HTMLVersion,
-		&result.InternalLinksCount,
-		&result.ExternalLinksCount,
-		&result.InaccessibleLinksCount,
-		&result.HasLoginForm,
-		&result.HeadingCounts,
-		&result.BrokenLinks,
-		&result.ExternalLinks,
-		&result.Status,
-		&result.ErrorMessage,
-		&result.CreatedAt,
-		&result.UpdatedAt,
-	)
+	err := scanCrawlResult(row, result)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -126,7 +191,7 @@ func (cs *CrawlStorage) GetCrawlResult(id string) (*models.CrawlResult, error) {
 }
 
 // GetCrawlResults retrieves crawl results with filtering, sorting, and pagination
-func (cs *CrawlStorage) GetCrawlResults(filters models.CrawlFilters) (*models.PaginatedCrawlResults, error) {
+func (cs *CrawlStorage) GetCrawlResults(ctx context.Context, filters models.CrawlFilters) (*models.PaginatedCrawlResults, error) {
 	// Validate filters
 	if err := filters.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid filters: %w", err)
@@ -160,7 +225,7 @@ func (cs *CrawlStorage) GetCrawlResults(filters models.CrawlFilters) (*models.Pa
 	`, whereClause)
 
 	var total int
-	err := cs.db.QueryRow(countQuery, args...).Scan(&total)
+	err := cs.db.QueryRowContext(ctx, countQuery, args...).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count results: %w", err)
 	}
@@ -171,19 +236,17 @@ func (cs *CrawlStorage) GetCrawlResults(filters models.CrawlFilters) (*models.Pa
 
 	// Build main query
 	query := fmt.Sprintf(`
-		SELECT id, url, title, html_version, internal_links_count, external_links_count,
-			   inaccessible_links_count, has_login_form, heading_counts, broken_links,
-			   external_links, status, error_message, created_at, updated_at
+		SELECT %s
 		FROM crawl_results 
 		%s
 		ORDER BY %s %s
 		LIMIT ? OFFSET ?
-	`, whereClause, filters.SortBy, filters.SortDir)
+	`, crawlResultColumns, whereClause, filters.SortBy, filters.SortDir)
 
 	// Add pagination parameters
 	args = append(args, filters.PageSize, offset)
 
-	rows, err := cs.db.Query(query, args...)
+	rows, err := cs.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query crawl results: %w", err)
 	}
@@ -193,23 +256,7 @@ func (cs *CrawlStorage) GetCrawlResults(filters models.CrawlFilters) (*models.Pa
 	for rows.Next() {
 		result := models.CrawlResult{}
 
-		err := rows.Scan(
-			&result.ID,
-			&result.URL,
-			&result.Title,
-			&result.HTMLVersion,
-			&result.InternalLinksCount,
-			&result.ExternalLinksCount,
-			&result.InaccessibleLinksCount,
-			&result.HasLoginForm,
-			&result.HeadingCounts,
-			&result.BrokenLinks,
-			&result.ExternalLinks,
-			&result.Status,
-			&result.ErrorMessage,
-			&result.CreatedAt,
-			&result.UpdatedAt,
-		)
+		err := scanCrawlResult(rows, &result)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan crawl result: %w", err)
 		}
@@ -231,7 +278,7 @@ func (cs *CrawlStorage) GetCrawlResults(filters models.CrawlFilters) (*models.Pa
 }
 
 // DeleteCrawlResults deletes multiple crawl results by their IDs
-func (cs *CrawlStorage) DeleteCrawlResults(ids []string) error {
+func (cs *CrawlStorage) DeleteCrawlResults(ctx context.Context, ids []string) error {
 	if len(ids) == 0 {
 		return nil
 	}
@@ -249,42 +296,51 @@ func (cs *CrawlStorage) DeleteCrawlResults(ids []string) error {
 		WHERE id IN (%s)
 	`, strings.Join(placeholders, ","))
 
-	result, err := cs.db.Exec(query, args...)
-	if err != nil {
-		return fmt.Errorf("failed to delete crawl results: %w", err)
-	}
+	// The run history and queue entries go with the crawl, so a queued crawl is
+	// never claimed again and a running one loses its lease
+	return cs.withTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete crawl results: %w", err)
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get affected rows: %w", err)
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("no crawl results were deleted")
-	}
+		if rowsAffected == 0 {
+			return fmt.Errorf("no crawl results were deleted")
+		}
 
-	return nil
+		if err := deleteCrawlRuns(ctx, tx, args); err != nil {
+			return err
+		}
+		return deleteCrawlJobs(ctx, tx, args)
+	})
 }
 
 // GetCrawlStats returns statistics about crawl results
-func (cs *CrawlStorage) GetCrawlStats() (*models.CrawlStats, error) {
+func (cs *CrawlStorage) GetCrawlStats(ctx context.Context) (*models.CrawlStats, error) {
 	query := `
 		SELECT 
 			COUNT(*) as total,
 			SUM(CASE WHEN status = 'queued' THEN 1 ELSE 0 END) as queued,
 			SUM(CASE WHEN status = 'running' THEN 1 ELSE 0 END) as running,
 			SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END) as completed,
-			SUM(CASE WHEN status = 'error' THEN 1 ELSE 0 END) as error
+			SUM(CASE WHEN status = 'error' THEN 1 ELSE 0 END) as error,
+			SUM(CASE WHEN status = 'cancelled' THEN 1 ELSE 0 END) as cancelled
 		FROM crawl_results
 	`
 
 	stats := &models.CrawlStats{}
-	err := cs.db.QueryRow(query).Scan(
+	err := cs.db.QueryRowContext(ctx, query).Scan(
 		&stats.Total,
 		&stats.Queued,
 		&stats.Running,
 		&stats.Completed,
 		&stats.Error,
+		&stats.Cancelled,
 	)
 
 	if err != nil {
@@ -295,7 +351,7 @@ func (cs *CrawlStorage) GetCrawlStats() (*models.CrawlStats, error) {
 }
 
 // UpdateCrawlResultsBulkStatus updates the status of multiple crawl results
-func (cs *CrawlStorage) UpdateCrawlResultsBulkStatus(ids []string, status models.CrawlStatus) error {
+func (cs *CrawlStorage) UpdateCrawlResultsBulkStatus(ctx context.Context, ids []string, status models.CrawlStatus) error {
 	if len(ids) == 0 {
 		return nil
 	}
@@ -314,11 +370,11 @@ func (cs *CrawlStorage) UpdateCrawlResultsBulkStatus(ids []string, status models
 
 	query := fmt.Sprintf(`
 		UPDATE crawl_results 
-		SET status = ?, updated_at = ?, error_message = NULL
+		SET status = ?, updated_at = ?, error_message = NULL, attempts = 0, last_error = NULL
 		WHERE id IN (%s)
 	`, strings.Join(placeholders, ","))
 
-	result, err := cs.db.Exec(query, args...)
+	result, err := cs.db.ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update crawl results status: %w", err)
 	}
@@ -335,23 +391,42 @@ func (cs *CrawlStorage) UpdateCrawlResultsBulkStatus(ids []string, status models
 	return nil
 }
 
-// CleanupOldCrawlResults removes crawl results older than the specified duration
-func (cs *CrawlStorage) CleanupOldCrawlResults(olderThan time.Duration) (int64, error) {
+// CleanupOldCrawlResults removes finished crawl results older than the
+// specified duration along with their run history, and prunes finished queue
+// entries whose crawl result no longer exists
+func (cs *CrawlStorage) CleanupOldCrawlResults(ctx context.Context, olderThan time.Duration) (int64, error) {
 	cutoffTime := time.Now().Add(-olderThan)
+	var rowsAffected int64
+
+	err := cs.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM crawl_runs
+			WHERE crawl_id IN (
+				SELECT id FROM crawl_results
+				WHERE created_at < ? AND status IN ('completed', 'error')
+			)
+		`, cutoffTime)
+		if err != nil {
+			return fmt.Errorf("failed to cleanup old crawl runs: %w", err)
+		}
 
-	query := `
-		DELETE FROM crawl_results 
-		WHERE created_at < ? AND status IN ('completed', 'error')
-	`
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM crawl_results 
+			WHERE created_at < ? AND status IN ('completed', 'error')
+		`, cutoffTime)
+		if err != nil {
+			return fmt.Errorf("failed to cleanup old crawl results: %w", err)
+		}
 
-	result, err := cs.db.Exec(query, cutoffTime)
-	if err != nil {
-		return 0, fmt.Errorf("failed to cleanup old crawl results: %w", err)
-	}
+		rowsAffected, err = result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
 
-	rowsAffected, err := result.RowsAffected()
+		return pruneFinishedCrawlJobs(ctx, tx)
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to get affected rows: %w", err)
+		return 0, err
 	}
 
 	return rowsAffected, nil
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"url-crawler/internal/models"
+)
+
+// SaveCrawlEvent appends an event to the crawl event log, assigning its ID
+func (cs *CrawlStorage) SaveCrawlEvent(ctx context.Context, event *models.CrawlEvent) error {
+	query := `
+		INSERT INTO crawl_events (crawl_id, type, status, phase, attempt, message, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	res, err := cs.db.ExecContext(ctx, query,
+		event.CrawlID,
+		event.Type,
+		event.Status,
+		event.Phase,
+		event.Attempt,
+		event.Message,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save crawl event: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get crawl event ID: %w", err)
+	}
+	event.ID = id
+
+	return nil
+}
+
+// GetCrawlEvents returns up to limit events after the given ID, oldest first,
+// for a single crawl or for every crawl of an API key
+func (cs *CrawlStorage) GetCrawlEvents(ctx context.Context, filter models.CrawlEventFilter, afterID int64, limit int) ([]models.CrawlEvent, error) {
+	query := `
+		SELECT e.id, e.crawl_id, e.type, e.status, e.phase, e.attempt, e.message, e.created_at
+		FROM crawl_events e
+		WHERE e.crawl_id = ? AND e.id > ?
+		ORDER BY e.id ASC
+		LIMIT ?
+	`
+	args := []interface{}{filter.CrawlID, afterID, limit}
+	if filter.CrawlID == "" {
+		query = `
+			SELECT e.id, e.crawl_id, e.type, e.status, e.phase, e.attempt, e.message, e.created_at
+			FROM crawl_events e
+			JOIN crawl_results r ON r.id = e.crawl_id
+			WHERE r.owner = ? AND e.id > ?
+			ORDER BY e.id ASC
+			LIMIT ?
+		`
+		args = []interface{}{filter.Owner, afterID, limit}
+	}
+
+	rows, err := cs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.CrawlEvent{}
+	for rows.Next() {
+		var event models.CrawlEvent
+		if err := rows.Scan(
+			&event.ID,
+			&event.CrawlID,
+			&event.Type,
+			&event.Status,
+			&event.Phase,
+			&event.Attempt,
+			&event.Message,
+			&event.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl event: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating crawl events: %w", err)
+	}
+
+	return events, nil
+}
+
+// GetLatestCrawlEventID returns the ID of the most recent crawl event, or 0 when there is none
+func (cs *CrawlStorage) GetLatestCrawlEventID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := cs.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM crawl_events").Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get latest crawl event: %w", err)
+	}
+
+	return id, nil
+}
+
+// PruneCrawlEvents deletes events older than maxAge
+func (cs *CrawlStorage) PruneCrawlEvents(ctx context.Context, maxAge time.Duration) (int, error) {
+	res, err := cs.db.ExecContext(ctx,
+		"DELETE FROM crawl_events WHERE created_at < NOW(3) - INTERVAL ? MICROSECOND",
+		maxAge.Microseconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune crawl events: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"url-crawler/internal/models"
+)
+
+// ClaimIdempotencyKey reserves an idempotency key of an API key for a new request.
+// It returns nil when the key was free, or the existing record when the key is
+// held by an earlier request. Expired keys, and keys whose request has been
+// pending for longer than pendingTimeout, are treated as free.
+func (cs *CrawlStorage) ClaimIdempotencyKey(ctx context.Context, owner, key, requestHash string, ttl, pendingTimeout time.Duration) (*models.IdempotencyRecord, error) {
+	_, err := cs.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE owner = ? AND idem_key = ?
+		  AND (expires_at <= NOW(3)
+		   OR (response_status IS NULL AND created_at <= NOW(3) - INTERVAL ? MICROSECOND))
+	`, owner, key, pendingTimeout.Microseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire idempotency key: %w", err)
+	}
+
+	res, err := cs.db.ExecContext(ctx, `
+		INSERT IGNORE INTO idempotency_keys (owner, idem_key, request_hash, created_at, expires_at)
+		VALUES (?, ?, ?, NOW(3), NOW(3) + INTERVAL ? MICROSECOND)
+	`, owner, key, requestHash, ttl.Microseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 1 {
+		return nil, nil
+	}
+
+	return cs.getIdempotencyRecord(ctx, owner, key)
+}
+
+// getIdempotencyRecord retrieves the record of an idempotency key
+func (cs *CrawlStorage) getIdempotencyRecord(ctx context.Context, owner, key string) (*models.IdempotencyRecord, error) {
+	query := `
+		SELECT owner, idem_key, request_hash, response_status, content_type, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE owner = ? AND idem_key = ?
+	`
+
+	record := &models.IdempotencyRecord{}
+	err := cs.db.QueryRowContext(ctx, query, owner, key).Scan(
+		&record.Owner,
+		&record.Key,
+		&record.RequestHash,
+		&record.ResponseStatus,
+		&record.ContentType,
+		&record.ResponseBody,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("idempotency key not found")
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return record, nil
+}
+
+// CompleteIdempotencyKey stores the response to the request holding an idempotency key
+func (cs *CrawlStorage) CompleteIdempotencyKey(ctx context.Context, owner, key string, status int, contentType string, body []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET response_status = ?, content_type = ?, response_body = ?
+		WHERE owner = ? AND idem_key = ?
+	`
+
+	_, err := cs.db.ExecContext(ctx, query, status, contentType, body, owner, key)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey frees a pending idempotency key, so the request can be retried
+func (cs *CrawlStorage) ReleaseIdempotencyKey(ctx context.Context, owner, key string) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE owner = ? AND idem_key = ? AND response_status IS NULL
+	`
+
+	_, err := cs.db.ExecContext(ctx, query, owner, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// PruneIdempotencyKeys deletes expired idempotency keys
+func (cs *CrawlStorage) PruneIdempotencyKeys(ctx context.Context) (int, error) {
+	res, err := cs.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= NOW(3)")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune idempotency keys: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
@@ -0,0 +1,638 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"url-crawler/internal/models"
+)
+
+// crawlJobColumns lists the crawl_jobs columns in the order scanCrawlJob reads them
+const crawlJobColumns = `id, url, site_crawl_id, parent_id, depth, priority, status, attempts, last_error,
+	available_at, lease_owner, lease_expires_at, cancel_requested, created_at, updated_at`
+
+// scanCrawlJob scans a row selected with crawlJobColumns
+func scanCrawlJob(row rowScanner, job *models.CrawlJob) error {
+	return row.Scan(
+		&job.ID,
+		&job.URL,
+		&job.SiteCrawlID,
+		&job.ParentID,
+		&job.Depth,
+		&job.Priority,
+		&job.Status,
+		&job.Attempts,
+		&job.LastError,
+		&job.AvailableAt,
+		&job.LeaseOwner,
+		&job.LeaseExpiresAt,
+		&job.CancelRequested,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+}
+
+// withTx runs fn in a transaction, committing only when it succeeds
+func (cs *CrawlStorage) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := cs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// insertCrawlJob queues a job, resetting an existing job with the same ID
+func insertCrawlJob(ctx context.Context, db execer, job *models.CrawlJob) error {
+	query := `
+		INSERT INTO crawl_jobs (
+			id, url, site_crawl_id, parent_id, depth, priority, status, attempts, available_at, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, 'queued', 0, NOW(3), ?, ?)
+		ON DUPLICATE KEY UPDATE
+			url = VALUES(url),
+			priority = VALUES(priority),
+			status = 'queued',
+			attempts = 0,
+			last_error = NULL,
+			available_at = NOW(3),
+			lease_owner = NULL,
+			lease_expires_at = NULL,
+			cancel_requested = FALSE,
+			updated_at = VALUES(updated_at)
+	`
+
+	_, err := db.ExecContext(ctx, query,
+		job.ID,
+		job.URL,
+		job.SiteCrawlID,
+		job.ParentID,
+		job.Depth,
+		job.Priority.OrDefault(),
+		job.CreatedAt,
+		time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue crawl job: %w", err)
+	}
+
+	return nil
+}
+
+// EnqueueJob adds a job to the durable queue. Re-enqueuing an existing job
+// (e.g. for a rerun) resets its attempts and makes it available immediately.
+func (cs *CrawlStorage) EnqueueJob(ctx context.Context, job *models.CrawlJob) error {
+	return insertCrawlJob(ctx, cs.db, job)
+}
+
+// ClaimJob leases an available queued job to owner. Lanes are tried in the
+// given order and the oldest job of the first non-empty lane wins. Rows locked
+// by other workers are skipped, so concurrent claims never return the same job.
+// It returns nil when no job is available.
+func (cs *CrawlStorage) ClaimJob(ctx context.Context, owner string, lease time.Duration, lanes []models.CrawlPriority) (*models.CrawlJob, error) {
+	var job *models.CrawlJob
+
+	if len(lanes) == 0 {
+		lanes = models.CrawlPriorities
+	}
+	placeholders := strings.Repeat("?,", len(lanes)-1) + "?"
+	args := make([]interface{}, 0, 2*len(lanes))
+	for _, lane := range lanes {
+		args = append(args, lane)
+	}
+	args = append(args, args...)
+
+	err := cs.withTx(ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT ` + crawlJobColumns + `
+			FROM crawl_jobs
+			WHERE status = 'queued' AND available_at <= NOW(3) AND priority IN (` + placeholders + `)
+			ORDER BY FIELD(priority, ` + placeholders + `), available_at ASC, created_at ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		`
+
+		claimed := &models.CrawlJob{}
+		if err := scanCrawlJob(tx.QueryRowContext(ctx, query, args...), claimed); err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return fmt.Errorf("failed to select crawl job: %w", err)
+		}
+
+		update := `
+			UPDATE crawl_jobs
+			SET status = 'running', attempts = attempts + 1, lease_owner = ?,
+				lease_expires_at = NOW(3) + INTERVAL ? MICROSECOND, updated_at = ?
+			WHERE id = ?
+		`
+		if _, err := tx.ExecContext(ctx, update, owner, lease.Microseconds(), time.Now(), claimed.ID); err != nil {
+			return fmt.Errorf("failed to lease crawl job: %w", err)
+		}
+
+		claimed.Status = models.CrawlStatusRunning
+		claimed.Attempts++
+		claimed.LeaseOwner = &owner
+		job = claimed
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// ExtendJobLease renews the lease owner holds on a running job. held is false
+// when the lease has been lost, e.g. because the job was reclaimed;
+// cancelRequested is true once the job has been asked to stop.
+func (cs *CrawlStorage) ExtendJobLease(ctx context.Context, id, owner string, lease time.Duration) (held bool, cancelRequested bool, err error) {
+	query := `
+		UPDATE crawl_jobs
+		SET lease_expires_at = NOW(3) + INTERVAL ? MICROSECOND
+		WHERE id = ? AND lease_owner = ? AND status = 'running'
+	`
+
+	res, err := cs.db.ExecContext(ctx, query, lease.Microseconds(), id, owner)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to extend job lease: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return false, false, nil
+	}
+
+	err = cs.db.QueryRowContext(ctx, "SELECT cancel_requested FROM crawl_jobs WHERE id = ?", id).Scan(&cancelRequested)
+	if err != nil {
+		return true, false, fmt.Errorf("failed to check job cancellation: %w", err)
+	}
+
+	return true, cancelRequested, nil
+}
+
+// finishJob moves a job leased by owner to a terminal status.
+// It reports false when owner no longer holds the lease.
+func (cs *CrawlStorage) finishJob(ctx context.Context, id, owner string, status models.CrawlStatus, lastError *string) (bool, error) {
+	query := `
+		UPDATE crawl_jobs
+		SET status = ?, last_error = COALESCE(?, last_error), lease_owner = NULL, lease_expires_at = NULL,
+			cancel_requested = FALSE, updated_at = ?
+		WHERE id = ? AND lease_owner = ?
+	`
+
+	res, err := cs.db.ExecContext(ctx, query, status, lastError, time.Now(), id, owner)
+	if err != nil {
+		return false, fmt.Errorf("failed to update crawl job: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// CompleteJob marks a job leased by owner as completed
+func (cs *CrawlStorage) CompleteJob(ctx context.Context, id, owner string) error {
+	_, err := cs.finishJob(ctx, id, owner, models.CrawlStatusCompleted, nil)
+	return err
+}
+
+// FailJob marks a job leased by owner as permanently failed
+func (cs *CrawlStorage) FailJob(ctx context.Context, id, owner string, errorMsg string) error {
+	_, err := cs.finishJob(ctx, id, owner, models.CrawlStatusError, &errorMsg)
+	return err
+}
+
+// AbortJob marks a job leased by owner as cancelled. It reports false when
+// owner no longer holds the lease.
+func (cs *CrawlStorage) AbortJob(ctx context.Context, id, owner string) (bool, error) {
+	return cs.finishJob(ctx, id, owner, models.CrawlStatusCancelled, nil)
+}
+
+// requeueLeasedJob runs an UPDATE that returns a job leased by owner to the queue.
+// It reports false when the lease is gone or the job has been asked to stop.
+func (cs *CrawlStorage) requeueLeasedJob(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	res, err := cs.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RetryJob releases a job leased by owner back to the queue, available again after delay.
+// It reports false, leaving the job untouched, when the lease is gone or a cancel was requested.
+func (cs *CrawlStorage) RetryJob(ctx context.Context, id, owner string, delay time.Duration, lastError string) (bool, error) {
+	query := `
+		UPDATE crawl_jobs
+		SET status = 'queued', last_error = ?, available_at = NOW(3) + INTERVAL ? MICROSECOND,
+			lease_owner = NULL, lease_expires_at = NULL, updated_at = ?
+		WHERE id = ? AND lease_owner = ? AND cancel_requested = FALSE
+	`
+
+	requeued, err := cs.requeueLeasedJob(ctx, query, lastError, delay.Microseconds(), time.Now(), id, owner)
+	if err != nil {
+		return false, fmt.Errorf("failed to schedule job retry: %w", err)
+	}
+
+	return requeued, nil
+}
+
+// ReleaseJob returns a job leased by owner to the queue without counting the
+// interrupted attempt, so another worker can pick it up straight away.
+// It reports false, leaving the job untouched, when the lease is gone or a cancel was requested.
+func (cs *CrawlStorage) ReleaseJob(ctx context.Context, id, owner string) (bool, error) {
+	query := `
+		UPDATE crawl_jobs
+		SET status = 'queued', attempts = GREATEST(attempts - 1, 0), available_at = NOW(3),
+			lease_owner = NULL, lease_expires_at = NULL, updated_at = ?
+		WHERE id = ? AND lease_owner = ? AND cancel_requested = FALSE
+	`
+
+	released, err := cs.requeueLeasedJob(ctx, query, time.Now(), id, owner)
+	if err != nil {
+		return false, fmt.Errorf("failed to release crawl job: %w", err)
+	}
+
+	return released, nil
+}
+
+// RequestJobCancel cancels a job. A queued job is cancelled immediately; a running
+// job is flagged so that its worker aborts it at the next lease renewal. It returns
+// the updated job, or nil when the job is missing or already finished.
+func (cs *CrawlStorage) RequestJobCancel(ctx context.Context, id string) (*models.CrawlJob, error) {
+	var job *models.CrawlJob
+
+	err := cs.withTx(ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT ` + crawlJobColumns + `
+			FROM crawl_jobs
+			WHERE id = ? AND status IN ('queued', 'running')
+			FOR UPDATE
+		`
+
+		active := &models.CrawlJob{}
+		if err := scanCrawlJob(tx.QueryRowContext(ctx, query, id), active); err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return fmt.Errorf("failed to select crawl job: %w", err)
+		}
+
+		update := `
+			UPDATE crawl_jobs
+			SET cancel_requested = TRUE, updated_at = ?
+			WHERE id = ?
+		`
+		if active.Status == models.CrawlStatusQueued {
+			update = `
+				UPDATE crawl_jobs
+				SET status = 'cancelled', updated_at = ?
+				WHERE id = ?
+			`
+		}
+		if _, err := tx.ExecContext(ctx, update, time.Now(), id); err != nil {
+			return fmt.Errorf("failed to cancel crawl job: %w", err)
+		}
+
+		if active.Status == models.CrawlStatusQueued {
+			active.Status = models.CrawlStatusCancelled
+		} else {
+			active.CancelRequested = true
+		}
+		job = active
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// ReclaimExpiredJobs requeues running jobs whose lease has expired, typically
+// because their worker crashed or was killed. Jobs that were asked to stop are
+// cancelled and jobs that have already used maxAttempts claims are failed instead.
+// The failed and cancelled jobs are returned so their outcome can be reported.
+func (cs *CrawlStorage) ReclaimExpiredJobs(ctx context.Context, maxAttempts int) (*models.ReclaimedJobs, error) {
+	reclaimed := &models.ReclaimedJobs{}
+
+	err := cs.withTx(ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT id, site_crawl_id, attempts, cancel_requested
+			FROM crawl_jobs
+			WHERE status = 'running' AND lease_expires_at < NOW(3)
+			FOR UPDATE SKIP LOCKED
+		`
+
+		rows, err := tx.QueryContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to query expired jobs: %w", err)
+		}
+
+		errorMsg := "Worker lease expired too many times"
+		var requeue, fail, cancel []interface{}
+		var failed, cancelled []models.CrawlJob
+		for rows.Next() {
+			var job models.CrawlJob
+			if err := rows.Scan(&job.ID, &job.SiteCrawlID, &job.Attempts, &job.CancelRequested); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan expired job: %w", err)
+			}
+			switch {
+			case job.CancelRequested:
+				job.Status = models.CrawlStatusCancelled
+				cancel = append(cancel, job.ID)
+				cancelled = append(cancelled, job)
+			case job.Attempts >= maxAttempts:
+				job.Status = models.CrawlStatusError
+				job.LastError = &errorMsg
+				fail = append(fail, job.ID)
+				failed = append(failed, job)
+			default:
+				requeue = append(requeue, job.ID)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating expired jobs: %w", err)
+		}
+
+		now := time.Now()
+		if len(requeue) > 0 {
+			placeholders := strings.Repeat("?,", len(requeue)-1) + "?"
+			args := append([]interface{}{now}, requeue...)
+
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE crawl_jobs
+				SET status = 'queued', available_at = NOW(3), lease_owner = NULL, lease_expires_at = NULL, updated_at = ?
+				WHERE id IN (`+placeholders+`)`, args...); err != nil {
+				return fmt.Errorf("failed to requeue expired jobs: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE crawl_results
+				SET status = 'queued', updated_at = ?
+				WHERE id IN (`+placeholders+`)`, args...); err != nil {
+				return fmt.Errorf("failed to requeue expired crawl results: %w", err)
+			}
+		}
+
+		if len(fail) > 0 {
+			placeholders := strings.Repeat("?,", len(fail)-1) + "?"
+			args := append([]interface{}{errorMsg, now}, fail...)
+
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE crawl_jobs
+				SET status = 'error', last_error = ?, lease_owner = NULL, lease_expires_at = NULL, updated_at = ?
+				WHERE id IN (`+placeholders+`)`, args...); err != nil {
+				return fmt.Errorf("failed to fail expired jobs: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE crawl_results
+				SET status = 'error', error_message = ?, updated_at = ?
+				WHERE id IN (`+placeholders+`)`, args...); err != nil {
+				return fmt.Errorf("failed to fail expired crawl results: %w", err)
+			}
+			for _, id := range fail {
+				if err := insertCrawlRun(ctx, tx, id.(string)); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(cancel) > 0 {
+			placeholders := strings.Repeat("?,", len(cancel)-1) + "?"
+			args := append([]interface{}{now}, cancel...)
+
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE crawl_jobs
+				SET status = 'cancelled', lease_owner = NULL, lease_expires_at = NULL, cancel_requested = FALSE, updated_at = ?
+				WHERE id IN (`+placeholders+`)`, args...); err != nil {
+				return fmt.Errorf("failed to cancel expired jobs: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE crawl_results
+				SET status = 'cancelled', updated_at = ?
+				WHERE id IN (`+placeholders+`)`, args...); err != nil {
+				return fmt.Errorf("failed to cancel expired crawl results: %w", err)
+			}
+			for _, id := range cancel {
+				if err := insertCrawlRun(ctx, tx, id.(string)); err != nil {
+					return err
+				}
+			}
+		}
+
+		reclaimed = &models.ReclaimedJobs{Requeued: len(requeue), Failed: failed, Cancelled: cancelled}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reclaimed, nil
+}
+
+// CountQueuedJobs returns the number of jobs waiting to be claimed in each priority lane
+func (cs *CrawlStorage) CountQueuedJobs(ctx context.Context) (map[models.CrawlPriority]int, error) {
+	rows, err := cs.db.QueryContext(ctx, "SELECT priority, COUNT(*) FROM crawl_jobs WHERE status = 'queued' GROUP BY priority")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count queued jobs: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[models.CrawlPriority]int, len(models.CrawlPriorities))
+	for _, lane := range models.CrawlPriorities {
+		counts[lane] = 0
+	}
+	for rows.Next() {
+		var lane models.CrawlPriority
+		var count int
+		if err := rows.Scan(&lane, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan queued job count: %w", err)
+		}
+		counts[lane] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating queued job counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetJob retrieves a single job by ID
+func (cs *CrawlStorage) GetJob(ctx context.Context, id string) (*models.CrawlJob, error) {
+	query := `
+		SELECT ` + crawlJobColumns + `
+		FROM crawl_jobs
+		WHERE id = ?
+	`
+
+	job := &models.CrawlJob{}
+	if err := scanCrawlJob(cs.db.QueryRowContext(ctx, query, id), job); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("crawl job not found")
+		}
+		return nil, fmt.Errorf("failed to get crawl job: %w", err)
+	}
+
+	return job, nil
+}
+
+// EnqueueSiteCrawlJobs adds discovered pages to a site crawl, skipping URLs the
+// crawl already contains and stopping at maxPages. The site crawl row is locked
+// for the duration, so workers expanding pages concurrently cannot exceed the
+// page budget or queue a URL twice. It returns the number of pages added.
+func (cs *CrawlStorage) EnqueueSiteCrawlJobs(ctx context.Context, siteCrawlID string, maxPages int, jobs []*models.CrawlJob) (int, error) {
+	added := 0
+
+	err := cs.withTx(ctx, func(tx *sql.Tx) error {
+		// Pages belong to the API key that started the site crawl
+		var owner sql.NullString
+		if err := tx.QueryRowContext(ctx, "SELECT owner FROM site_crawls WHERE id = ? FOR UPDATE", siteCrawlID).Scan(&owner); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("site crawl not found")
+			}
+			return fmt.Errorf("failed to lock site crawl: %w", err)
+		}
+
+		rows, err := tx.QueryContext(ctx, "SELECT url FROM crawl_results WHERE site_crawl_id = ?", siteCrawlID)
+		if err != nil {
+			return fmt.Errorf("failed to query site crawl pages: %w", err)
+		}
+		visited := make(map[string]bool)
+		for rows.Next() {
+			var pageURL string
+			if err := rows.Scan(&pageURL); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan site crawl page: %w", err)
+			}
+			visited[pageURL] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating site crawl pages: %w", err)
+		}
+
+		pages := len(visited)
+		for _, job := range jobs {
+			if pages >= maxPages {
+				break
+			}
+			if visited[job.URL] {
+				continue
+			}
+
+			result := &models.CrawlResult{
+				ID:            job.ID,
+				URL:           job.URL,
+				Status:        models.CrawlStatusQueued,
+				HeadingCounts: models.HeadingCounts{},
+				BrokenLinks:   models.BrokenLinks{},
+				ParentID:      job.ParentID,
+				SiteCrawlID:   job.SiteCrawlID,
+				Depth:         job.Depth,
+				CreatedAt:     job.CreatedAt,
+				UpdatedAt:     job.CreatedAt,
+			}
+			if owner.Valid {
+				result.Owner = &owner.String
+			}
+			if err := saveCrawlResult(ctx, tx, result); err != nil {
+				return err
+			}
+			if err := insertCrawlJob(ctx, tx, job); err != nil {
+				return err
+			}
+
+			visited[job.URL] = true
+			pages++
+			added++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return added, nil
+}
+
+// deleteCrawlJobs removes the queue entries of the given crawls
+func deleteCrawlJobs(ctx context.Context, db execer, crawlIDs []interface{}) error {
+	placeholders := strings.Repeat("?,", len(crawlIDs)-1) + "?"
+	if _, err := db.ExecContext(ctx, "DELETE FROM crawl_jobs WHERE id IN ("+placeholders+")", crawlIDs...); err != nil {
+		return fmt.Errorf("failed to delete crawl jobs: %w", err)
+	}
+
+	return nil
+}
+
+// pruneFinishedCrawlJobs removes finished queue entries whose crawl result is
+// gone. Entries of existing crawls are kept, since reruns reuse their lane.
+func pruneFinishedCrawlJobs(ctx context.Context, db execer) error {
+	query := `
+		DELETE FROM crawl_jobs
+		WHERE status IN ('completed', 'error', 'cancelled')
+			AND NOT EXISTS (SELECT 1 FROM crawl_results WHERE crawl_results.id = crawl_jobs.id)
+	`
+
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to prune finished crawl jobs: %w", err)
+	}
+
+	return nil
+}
+
+// CompleteSiteCrawlIfDone finishes a running site crawl once none of its jobs
+// are queued or running: as cancelled when its seed page was cancelled, and as
+// completed otherwise. It reports whether the crawl was finished.
+func (cs *CrawlStorage) CompleteSiteCrawlIfDone(ctx context.Context, siteCrawlID string) (bool, error) {
+	query := `
+		UPDATE site_crawls
+		SET status = IF(EXISTS (
+				SELECT 1 FROM crawl_jobs
+				WHERE site_crawl_id = ? AND depth = 0 AND status = 'cancelled'
+			), 'cancelled', 'completed'),
+			updated_at = ?
+		WHERE id = ? AND status = 'running'
+			AND NOT EXISTS (
+				SELECT 1 FROM crawl_jobs
+				WHERE site_crawl_id = ? AND status IN ('queued', 'running')
+			)
+	`
+
+	res, err := cs.db.ExecContext(ctx, query, siteCrawlID, time.Now(), siteCrawlID, siteCrawlID)
+	if err != nil {
+		return false, fmt.Errorf("failed to complete site crawl: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
@@ -8,13 +8,23 @@ CREATE TABLE IF NOT EXISTS crawl_results (
     html_version VARCHAR(50),
     internal_links_count INT DEFAULT 0,
     external_links_count INT DEFAULT 0,
+    other_links_count INT DEFAULT 0,
     inaccessible_links_count INT DEFAULT 0,
     has_login_form BOOLEAN DEFAULT FALSE,
     heading_counts JSON,
     broken_links JSON,
     external_links JSON,
-    status ENUM('queued', 'running', 'completed', 'error') NOT NULL DEFAULT 'queued',
+    checked_links JSON,
+    status ENUM('queued', 'running', 'completed', 'error', 'cancelled') NOT NULL DEFAULT 'queued',
     error_message TEXT,
+    attempts INT DEFAULT 0,
+    last_error TEXT,
+    parent_id VARCHAR(36) NULL,
+    site_crawl_id VARCHAR(36) NULL,
+    depth INT DEFAULT 0,
+    owner VARCHAR(128) NULL,
+    webhook_id VARCHAR(36) NULL,
+    batch_id VARCHAR(36) NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     
@@ -24,7 +34,313 @@ CREATE TABLE IF NOT EXISTS crawl_results (
     INDEX idx_crawl_updated_at (updated_at),
     INDEX idx_crawl_url_hash (url(255)),
     INDEX idx_crawl_status_updated (status, updated_at),
+    INDEX idx_crawl_site_crawl (site_crawl_id, depth),
+    INDEX idx_crawl_parent (parent_id),
+    INDEX idx_crawl_batch (batch_id, status),
     FULLTEXT KEY idx_url_title_fulltext (url, title)
 );
 
-SHOW TABLES; 
\ No newline at end of file
+-- Create site_crawls table (multi-page crawls discovered from a seed URL)
+CREATE TABLE IF NOT EXISTS site_crawls (
+    id VARCHAR(36) PRIMARY KEY,
+    seed_url TEXT NOT NULL,
+    max_depth INT NOT NULL DEFAULT 2,
+    max_pages INT NOT NULL DEFAULT 50,
+    same_domain BOOLEAN DEFAULT TRUE,
+    status ENUM('queued', 'running', 'completed', 'error', 'cancelled') NOT NULL DEFAULT 'queued',
+    owner VARCHAR(128) NULL, -- API key that started the crawl, inherited by its pages
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_site_crawl_status (status),
+    INDEX idx_site_crawl_created_at (created_at)
+);
+
+-- Create crawl_jobs table (durable work queue, one job per crawl result).
+-- Workers claim queued jobs with SELECT ... FOR UPDATE SKIP LOCKED and hold
+-- a lease that they renew while crawling; expired leases are reclaimed.
+CREATE TABLE IF NOT EXISTS crawl_jobs (
+    id VARCHAR(36) PRIMARY KEY,
+    url TEXT NOT NULL,
+    site_crawl_id VARCHAR(36) NULL,
+    parent_id VARCHAR(36) NULL,
+    depth INT DEFAULT 0,
+    priority ENUM('high', 'normal', 'low') NOT NULL DEFAULT 'normal',
+    status ENUM('queued', 'running', 'completed', 'error', 'cancelled') NOT NULL DEFAULT 'queued',
+    attempts INT DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    lease_owner VARCHAR(128) NULL,
+    lease_expires_at TIMESTAMP(3) NULL,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_job_claim (status, priority, available_at),
+    INDEX idx_job_lease (status, lease_expires_at),
+    INDEX idx_job_site_crawl (site_crawl_id, status)
+);
+
+-- Create crawl_runs table (history of finished runs, one row per run of a crawl).
+-- crawl_results holds only the latest outcome; every finished run is copied here.
+CREATE TABLE IF NOT EXISTS crawl_runs (
+    crawl_id VARCHAR(36) NOT NULL,
+    run_number INT NOT NULL,
+    url TEXT NOT NULL,
+    title TEXT,
+    html_version VARCHAR(50),
+    internal_links_count INT DEFAULT 0,
+    external_links_count INT DEFAULT 0,
+    other_links_count INT DEFAULT 0,
+    inaccessible_links_count INT DEFAULT 0,
+    has_login_form BOOLEAN DEFAULT FALSE,
+    heading_counts JSON,
+    broken_links JSON,
+    external_links JSON,
+    checked_links JSON,
+    status ENUM('queued', 'running', 'completed', 'error', 'cancelled') NOT NULL,
+    error_message TEXT,
+    attempts INT DEFAULT 0,
+    finished_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (crawl_id, run_number)
+);
+
+-- Create crawl_workers table (live worker processes, kept current by heartbeats)
+CREATE TABLE IF NOT EXISTS crawl_workers (
+    id VARCHAR(128) PRIMARY KEY,
+    hostname VARCHAR(255) NOT NULL,
+    pid INT NOT NULL,
+    concurrency INT NOT NULL DEFAULT 0,
+    active_tasks INT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP(3) NOT NULL,
+    last_heartbeat_at TIMESTAMP(3) NOT NULL,
+
+    INDEX idx_worker_heartbeat (last_heartbeat_at)
+);
+
+-- Create crawl_schedules table (recurring crawls driven by cron expressions).
+-- Replicas claim a due tick by advancing next_run_at with a compare-and-set,
+-- so each tick runs exactly once.
+CREATE TABLE IF NOT EXISTS crawl_schedules (
+    id VARCHAR(36) PRIMARY KEY,
+    url TEXT NOT NULL,
+    cron VARCHAR(255) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    priority ENUM('high', 'normal', 'low') NOT NULL DEFAULT 'normal',
+    owner VARCHAR(128) NOT NULL DEFAULT '',
+    next_run_at TIMESTAMP(3) NOT NULL,
+    last_run_at TIMESTAMP(3) NULL,
+    last_crawl_id VARCHAR(36) NULL,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_schedule_next_run (next_run_at),
+    INDEX idx_schedule_owner (owner, created_at)
+);
+
+-- Create webhooks table (endpoints notified when crawls finish). Key-scoped
+-- webhooks receive every crawl requested with the owning API key; crawl-scoped
+-- webhooks are referenced by crawl_results.webhook_id.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id VARCHAR(36) PRIMARY KEY,
+    owner VARCHAR(128) NOT NULL,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    scope ENUM('key', 'crawl') NOT NULL DEFAULT 'key',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_webhook_owner (owner, scope)
+);
+
+-- Create webhook_deliveries table (durable outbox of webhook notifications).
+-- Senders claim due deliveries with SELECT ... FOR UPDATE SKIP LOCKED and push
+-- next_attempt_at forward while the request is in flight.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id VARCHAR(36) PRIMARY KEY,
+    webhook_id VARCHAR(36) NOT NULL,
+    crawl_id VARCHAR(36) NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    payload JSON NOT NULL,
+    status ENUM('pending', 'succeeded', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    last_status_code INT NULL,
+    last_error TEXT,
+    delivered_at TIMESTAMP(3) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_delivery_due (status, next_attempt_at),
+    INDEX idx_delivery_webhook (webhook_id, created_at)
+);
+
+-- Create webhook_delivery_attempts table (log of every delivery request and its response code)
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    delivery_id VARCHAR(36) NOT NULL,
+    attempt INT NOT NULL,
+    status_code INT NULL,
+    error TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+
+    INDEX idx_attempt_delivery (delivery_id, id)
+);
+
+-- Create crawl_events table (recent crawl state changes, streamed to clients).
+-- The auto-increment ID orders events and lets streams resume after a reconnect.
+CREATE TABLE IF NOT EXISTS crawl_events (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    crawl_id VARCHAR(36) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    status ENUM('queued', 'running', 'completed', 'error', 'cancelled') NOT NULL,
+    phase VARCHAR(32) NOT NULL DEFAULT '',
+    attempt INT NOT NULL DEFAULT 0,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+
+    INDEX idx_event_crawl (crawl_id, id),
+    INDEX idx_event_created_at (created_at)
+);
+
+-- Create crawl_batches table (URLs submitted together, progress is aggregated
+-- from the crawl_results rows that reference the batch)
+CREATE TABLE IF NOT EXISTS crawl_batches (
+    id VARCHAR(36) PRIMARY KEY,
+    owner VARCHAR(128) NOT NULL DEFAULT '',
+    source VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_batch_owner (owner, created_at)
+);
+
+-- Create idempotency_keys table (responses replayed to retried requests, per API key).
+-- A row without a response status belongs to a request that is still being handled.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    owner VARCHAR(128) NOT NULL,
+    idem_key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    response_status INT NULL,
+    content_type VARCHAR(255) NOT NULL DEFAULT '',
+    response_body MEDIUMBLOB NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    expires_at TIMESTAMP(3) NOT NULL,
+
+    PRIMARY KEY (owner, idem_key),
+    INDEX idx_idempotency_expires (expires_at)
+);
+
+-- Create rate_limit_windows table (request counts per rate limit key and fixed
+-- window, shared by all API instances). window_start is in Unix milliseconds.
+CREATE TABLE IF NOT EXISTS rate_limit_windows (
+    rate_key VARCHAR(255) NOT NULL,
+    window_start BIGINT NOT NULL,
+    hits INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP(3) NOT NULL,
+
+    PRIMARY KEY (rate_key, window_start),
+    INDEX idx_rate_limit_expires (expires_at)
+);
+
+-- Upgrades of databases created by earlier versions. MySQL has no ADD COLUMN
+-- IF NOT EXISTS, so these helpers check information_schema first and the
+-- upgrades can run any number of times.
+DROP PROCEDURE IF EXISTS add_column_if_missing;
+DROP PROCEDURE IF EXISTS add_index_if_missing;
+
+DELIMITER //
+CREATE PROCEDURE add_column_if_missing(IN tbl VARCHAR(64), IN col VARCHAR(64), IN definition TEXT)
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.COLUMNS
+        WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = tbl AND COLUMN_NAME = col
+    ) THEN
+        SET @ddl = CONCAT('ALTER TABLE ', tbl, ' ADD COLUMN ', col, ' ', definition);
+        PREPARE stmt FROM @ddl;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+    END IF;
+END //
+
+CREATE PROCEDURE add_index_if_missing(IN tbl VARCHAR(64), IN idx VARCHAR(64), IN cols TEXT)
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.STATISTICS
+        WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = tbl AND INDEX_NAME = idx
+    ) THEN
+        SET @ddl = CONCAT('ALTER TABLE ', tbl, ' ADD INDEX ', idx, ' (', cols, ')');
+        PREPARE stmt FROM @ddl;
+        EXECUTE stmt;
+        DEALLOCATE PREPARE stmt;
+    END IF;
+END //
+DELIMITER ;
+
+-- Upgrade databases created before non-navigational links were counted
+CALL add_column_if_missing('crawl_results', 'other_links_count', 'INT DEFAULT 0 AFTER external_links_count');
+
+-- Upgrade databases created before site crawls
+CALL add_column_if_missing('crawl_results', 'parent_id', 'VARCHAR(36) NULL AFTER error_message');
+CALL add_column_if_missing('crawl_results', 'site_crawl_id', 'VARCHAR(36) NULL AFTER parent_id');
+CALL add_column_if_missing('crawl_results', 'depth', 'INT DEFAULT 0 AFTER site_crawl_id');
+CALL add_index_if_missing('crawl_results', 'idx_crawl_site_crawl', 'site_crawl_id, depth');
+CALL add_index_if_missing('crawl_results', 'idx_crawl_parent', 'parent_id');
+
+-- Upgrade databases created before transient failures were retried
+CALL add_column_if_missing('crawl_results', 'attempts', 'INT DEFAULT 0 AFTER error_message');
+CALL add_column_if_missing('crawl_results', 'last_error', 'TEXT AFTER attempts');
+
+-- Upgrade databases created before crawls could be cancelled
+ALTER TABLE crawl_results
+    MODIFY status ENUM('queued', 'running', 'completed', 'error', 'cancelled') NOT NULL DEFAULT 'queued';
+ALTER TABLE site_crawls
+    MODIFY status ENUM('queued', 'running', 'completed', 'error', 'cancelled') NOT NULL DEFAULT 'queued';
+ALTER TABLE crawl_jobs
+    MODIFY status ENUM('queued', 'running', 'completed', 'error', 'cancelled') NOT NULL DEFAULT 'queued';
+CALL add_column_if_missing('crawl_jobs', 'cancel_requested', 'BOOLEAN NOT NULL DEFAULT FALSE AFTER lease_expires_at');
+
+-- Upgrade databases created before priority lanes; the claim index gains the priority
+CALL add_column_if_missing('crawl_jobs', 'priority', "ENUM('high', 'normal', 'low') NOT NULL DEFAULT 'normal' AFTER depth");
+SET @ddl = IF((
+    SELECT COUNT(*) FROM information_schema.STATISTICS
+    WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'crawl_jobs' AND INDEX_NAME = 'idx_job_claim' AND COLUMN_NAME = 'priority'
+) = 0, 'ALTER TABLE crawl_jobs DROP INDEX idx_job_claim, ADD INDEX idx_job_claim (status, priority, available_at)', 'DO 0');
+PREPARE stmt FROM @ddl;
+EXECUTE stmt;
+DEALLOCATE PREPARE stmt;
+
+-- Upgrade databases created before crawls were owned by API keys and notified webhooks
+CALL add_column_if_missing('crawl_results', 'owner', 'VARCHAR(128) NULL AFTER depth');
+CALL add_column_if_missing('crawl_results', 'webhook_id', 'VARCHAR(36) NULL AFTER owner');
+CALL add_column_if_missing('site_crawls', 'owner', 'VARCHAR(128) NULL AFTER status');
+CALL add_column_if_missing('crawl_schedules', 'owner', "VARCHAR(128) NOT NULL DEFAULT '' AFTER priority");
+CALL add_index_if_missing('crawl_schedules', 'idx_schedule_owner', 'owner, created_at');
+
+-- Upgrade databases created before the links a crawl checked were recorded
+CALL add_column_if_missing('crawl_results', 'checked_links', 'JSON AFTER external_links');
+CALL add_column_if_missing('crawl_runs', 'checked_links', 'JSON AFTER external_links');
+
+-- Upgrade databases created before batch submission
+CALL add_column_if_missing('crawl_results', 'batch_id', 'VARCHAR(36) NULL AFTER webhook_id');
+CALL add_index_if_missing('crawl_results', 'idx_crawl_batch', 'batch_id, status');
+
+-- Record finished crawls from before run history was kept as their first run.
+-- This reads other_links_count and attempts, so it must stay below the column
+-- upgrades above, which add them to tables created before those columns.
+INSERT IGNORE INTO crawl_runs (
+    crawl_id, run_number, url, title, html_version, internal_links_count, external_links_count,
+    other_links_count, inaccessible_links_count, has_login_form, heading_counts, broken_links,
+    external_links, status, error_message, attempts, finished_at
+)
+SELECT id, 1, url, title, html_version, internal_links_count, external_links_count,
+    other_links_count, inaccessible_links_count, has_login_form, heading_counts, broken_links,
+    external_links, status, error_message, attempts, updated_at
+FROM crawl_results
+WHERE status IN ('completed', 'error');
+
+DROP PROCEDURE IF EXISTS add_column_if_missing;
+DROP PROCEDURE IF EXISTS add_index_if_missing;
+
+SHOW TABLES; 
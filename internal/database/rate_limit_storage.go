@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// IncrementRateLimitHits counts a request against a rate limit key in the window
+// starting at window (Unix milliseconds). It returns the hits of that window,
+// including this one, and of the window starting at previousWindow. The row of
+// the current window stays locked until both counts are read, so concurrent
+// requests from any instance see each other's hits.
+func (cs *CrawlStorage) IncrementRateLimitHits(ctx context.Context, key string, window, previousWindow int64, ttl time.Duration) (int, int, error) {
+	var current, previous int
+
+	err := cs.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO rate_limit_windows (rate_key, window_start, hits, expires_at)
+			VALUES (?, ?, 1, NOW(3) + INTERVAL ? MICROSECOND)
+			ON DUPLICATE KEY UPDATE hits = hits + 1
+		`, key, window, ttl.Microseconds())
+		if err != nil {
+			return fmt.Errorf("failed to count rate limit hit: %w", err)
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+			SELECT window_start, hits
+			FROM rate_limit_windows
+			WHERE rate_key = ? AND window_start IN (?, ?)
+		`, key, window, previousWindow)
+		if err != nil {
+			return fmt.Errorf("failed to get rate limit hits: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var start int64
+			var hits int
+			if err := rows.Scan(&start, &hits); err != nil {
+				return fmt.Errorf("failed to scan rate limit hits: %w", err)
+			}
+			if start == window {
+				current = hits
+			} else {
+				previous = hits
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return current, previous, nil
+}
+
+// DecrementRateLimitHits takes back a hit counted for a request that was denied
+func (cs *CrawlStorage) DecrementRateLimitHits(ctx context.Context, key string, window int64) error {
+	query := `
+		UPDATE rate_limit_windows
+		SET hits = hits - 1
+		WHERE rate_key = ? AND window_start = ? AND hits > 0
+	`
+
+	_, err := cs.db.ExecContext(ctx, query, key, window)
+	if err != nil {
+		return fmt.Errorf("failed to take back rate limit hit: %w", err)
+	}
+
+	return nil
+}
+
+// PruneRateLimitWindows deletes rate limit windows that no longer affect any limit
+func (cs *CrawlStorage) PruneRateLimitWindows(ctx context.Context) (int, error) {
+	res, err := cs.db.ExecContext(ctx, "DELETE FROM rate_limit_windows WHERE expires_at <= NOW(3)")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune rate limit windows: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
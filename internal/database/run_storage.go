@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"url-crawler/internal/models"
+)
+
+// crawlRunColumns lists the crawl_runs columns in the order scanCrawlRun reads them
+const crawlRunColumns = `crawl_id, run_number, url, title, html_version, internal_links_count,
+	external_links_count, other_links_count, inaccessible_links_count, has_login_form, heading_counts,
+	broken_links, external_links, checked_links, status, error_message, attempts, finished_at`
+
+// scanCrawlRun scans a row selected with crawlRunColumns
+func scanCrawlRun(row rowScanner, run *models.CrawlRun) error {
+	return row.Scan(
+		&run.CrawlID,
+		&run.RunNumber,
+		&run.URL,
+		&run.Title,
+		&run.HTMLVersion,
+		&run.InternalLinksCount,
+		&run.ExternalLinksCount,
+		&run.OtherLinksCount,
+		&run.InaccessibleLinksCount,
+		&run.HasLoginForm,
+		&run.HeadingCounts,
+		&run.BrokenLinks,
+		&run.ExternalLinks,
+		&run.CheckedLinks,
+		&run.Status,
+		&run.ErrorMessage,
+		&run.Attempts,
+		&run.FinishedAt,
+	)
+}
+
+// insertCrawlRun copies the current state of a crawl result into its run history
+// as the next run. It must run inside a transaction so run numbers stay unique.
+func insertCrawlRun(ctx context.Context, tx *sql.Tx, crawlID string) error {
+	var last int
+	err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(run_number), 0) FROM crawl_runs WHERE crawl_id = ? FOR UPDATE",
+		crawlID).Scan(&last)
+	if err != nil {
+		return fmt.Errorf("failed to number crawl run: %w", err)
+	}
+
+	query := `
+		INSERT INTO crawl_runs (
+			crawl_id, run_number, url, title, html_version, internal_links_count, external_links_count,
+			other_links_count, inaccessible_links_count, has_login_form, heading_counts, broken_links,
+			external_links, checked_links, status, error_message, attempts, finished_at
+		)
+		SELECT id, ?, url, title, html_version, internal_links_count, external_links_count,
+			other_links_count, inaccessible_links_count, has_login_form, heading_counts, broken_links,
+			external_links, checked_links, status, error_message, attempts, updated_at
+		FROM crawl_results
+		WHERE id = ?
+	`
+	if _, err := tx.ExecContext(ctx, query, last+1, crawlID); err != nil {
+		return fmt.Errorf("failed to record crawl run: %w", err)
+	}
+
+	return nil
+}
+
+// RecordCrawlRun adds the current outcome of a finished crawl to its run history
+func (cs *CrawlStorage) RecordCrawlRun(ctx context.Context, crawlID string) error {
+	return cs.withTx(ctx, func(tx *sql.Tx) error {
+		return insertCrawlRun(ctx, tx, crawlID)
+	})
+}
+
+// GetCrawlRuns returns up to limit runs of a crawl, most recent first
+func (cs *CrawlStorage) GetCrawlRuns(ctx context.Context, crawlID string, limit int) ([]models.CrawlRun, error) {
+	query := `
+		SELECT ` + crawlRunColumns + `
+		FROM crawl_runs
+		WHERE crawl_id = ?
+		ORDER BY run_number DESC
+		LIMIT ?
+	`
+
+	rows, err := cs.db.QueryContext(ctx, query, crawlID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crawl runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []models.CrawlRun{}
+	for rows.Next() {
+		var run models.CrawlRun
+		if err := scanCrawlRun(rows, &run); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating crawl runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// CountCrawlRuns returns how many runs a crawl has recorded
+func (cs *CrawlStorage) CountCrawlRuns(ctx context.Context, crawlID string) (int, error) {
+	var count int
+	err := cs.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM crawl_runs WHERE crawl_id = ?", crawlID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count crawl runs: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetCrawlRun retrieves a single run of a crawl by its run number
+func (cs *CrawlStorage) GetCrawlRun(ctx context.Context, crawlID string, runNumber int) (*models.CrawlRun, error) {
+	query := `
+		SELECT ` + crawlRunColumns + `
+		FROM crawl_runs
+		WHERE crawl_id = ? AND run_number = ?
+	`
+
+	run := &models.CrawlRun{}
+	if err := scanCrawlRun(cs.db.QueryRowContext(ctx, query, crawlID, runNumber), run); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("crawl run not found")
+		}
+		return nil, fmt.Errorf("failed to get crawl run: %w", err)
+	}
+
+	return run, nil
+}
+
+// deleteCrawlRuns removes the run history of the given crawls
+func deleteCrawlRuns(ctx context.Context, db execer, crawlIDs []interface{}) error {
+	placeholders := strings.Repeat("?,", len(crawlIDs)-1) + "?"
+	if _, err := db.ExecContext(ctx, "DELETE FROM crawl_runs WHERE crawl_id IN ("+placeholders+")", crawlIDs...); err != nil {
+		return fmt.Errorf("failed to delete crawl runs: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"url-crawler/internal/models"
+)
+
+// scheduleColumns lists the crawl_schedules columns in the order scanSchedule reads them
+const scheduleColumns = `id, url, cron, timezone, priority, owner, next_run_at, last_run_at, last_crawl_id,
+	last_error, created_at, updated_at`
+
+// scanSchedule scans a row selected with scheduleColumns
+func scanSchedule(row rowScanner, schedule *models.Schedule) error {
+	return row.Scan(
+		&schedule.ID,
+		&schedule.URL,
+		&schedule.Cron,
+		&schedule.Timezone,
+		&schedule.Priority,
+		&schedule.Owner,
+		&schedule.NextRunAt,
+		&schedule.LastRunAt,
+		&schedule.LastCrawlID,
+		&schedule.LastError,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+}
+
+// SaveSchedule inserts a new crawl schedule
+func (cs *CrawlStorage) SaveSchedule(ctx context.Context, schedule *models.Schedule) error {
+	query := `
+		INSERT INTO crawl_schedules (
+			id, url, cron, timezone, priority, owner, next_run_at, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := cs.db.ExecContext(ctx, query,
+		schedule.ID,
+		schedule.URL,
+		schedule.Cron,
+		schedule.Timezone,
+		schedule.Priority,
+		schedule.Owner,
+		schedule.NextRunAt,
+		schedule.CreatedAt,
+		schedule.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save schedule: %w", err)
+	}
+
+	return nil
+}
+
+// GetSchedule retrieves a single schedule by ID, if it belongs to owner
+func (cs *CrawlStorage) GetSchedule(ctx context.Context, id, owner string) (*models.Schedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM crawl_schedules
+		WHERE id = ? AND owner = ?
+	`
+
+	schedule := &models.Schedule{}
+	if err := scanSchedule(cs.db.QueryRowContext(ctx, query, id, owner), schedule); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("schedule not found")
+		}
+		return nil, fmt.Errorf("failed to get schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+// GetSchedules returns the schedules belonging to owner, newest first
+func (cs *CrawlStorage) GetSchedules(ctx context.Context, owner string) ([]models.Schedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM crawl_schedules
+		WHERE owner = ?
+		ORDER BY created_at DESC
+	`
+
+	return cs.querySchedules(ctx, query, owner)
+}
+
+// GetDueSchedules returns up to limit schedules whose next run is at or before now
+func (cs *CrawlStorage) GetDueSchedules(ctx context.Context, now time.Time, limit int) ([]models.Schedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM crawl_schedules
+		WHERE next_run_at <= ?
+		ORDER BY next_run_at ASC
+		LIMIT ?
+	`
+
+	return cs.querySchedules(ctx, query, now, limit)
+}
+
+// querySchedules runs a query selecting scheduleColumns
+func (cs *CrawlStorage) querySchedules(ctx context.Context, query string, args ...interface{}) ([]models.Schedule, error) {
+	rows, err := cs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []models.Schedule{}
+	for rows.Next() {
+		var schedule models.Schedule
+		if err := scanSchedule(rows, &schedule); err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// ClaimScheduleRun claims the run of a schedule due at tick by moving its next
+// run to next. The update only applies while next_run_at still equals tick, so
+// when several replicas see the same due schedule exactly one of them wins.
+// It reports whether this caller claimed the run.
+func (cs *CrawlStorage) ClaimScheduleRun(ctx context.Context, id string, tick, next time.Time) (bool, error) {
+	query := `
+		UPDATE crawl_schedules
+		SET last_run_at = ?, next_run_at = ?, updated_at = ?
+		WHERE id = ? AND next_run_at = ?
+	`
+
+	res, err := cs.db.ExecContext(ctx, query, tick, next, time.Now(), id, tick)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim schedule run: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RecordScheduleRun stores the outcome of a claimed run: the crawl it enqueued,
+// or why it could not enqueue one
+func (cs *CrawlStorage) RecordScheduleRun(ctx context.Context, id string, crawlID *string, lastError *string) error {
+	query := `
+		UPDATE crawl_schedules
+		SET last_crawl_id = COALESCE(?, last_crawl_id), last_error = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	_, err := cs.db.ExecContext(ctx, query, crawlID, lastError, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to record schedule run: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSchedule removes a schedule belonging to owner. Crawls it already enqueued are kept.
+func (cs *CrawlStorage) DeleteSchedule(ctx context.Context, id, owner string) error {
+	res, err := cs.db.ExecContext(ctx, "DELETE FROM crawl_schedules WHERE id = ? AND owner = ?", id, owner)
+	if err != nil {
+		return fmt.Errorf("failed to delete schedule: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("schedule not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"url-crawler/internal/models"
+)
+
+// SaveSiteCrawl inserts a new site crawl record
+func (cs *CrawlStorage) SaveSiteCrawl(ctx context.Context, siteCrawl *models.SiteCrawl) error {
+	query := `
+		INSERT INTO site_crawls (
+			id, seed_url, max_depth, max_pages, same_domain, status, owner, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)
+	`
+
+	_, err := cs.db.ExecContext(ctx, query,
+		siteCrawl.ID,
+		siteCrawl.SeedURL,
+		siteCrawl.MaxDepth,
+		siteCrawl.MaxPages,
+		siteCrawl.SameDomain,
+		siteCrawl.Status,
+		siteCrawl.Owner,
+		siteCrawl.CreatedAt,
+		siteCrawl.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save site crawl: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSiteCrawlStatus updates the status of a site crawl
+func (cs *CrawlStorage) UpdateSiteCrawlStatus(ctx context.Context, id string, status models.CrawlStatus) error {
+	query := `
+		UPDATE site_crawls
+		SET status = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	_, err := cs.db.ExecContext(ctx, query, status, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update site crawl status: %w", err)
+	}
+
+	return nil
+}
+
+// GetSiteCrawl retrieves a single site crawl by ID
+func (cs *CrawlStorage) GetSiteCrawl(ctx context.Context, id string) (*models.SiteCrawl, error) {
+	query := `
+		SELECT id, seed_url, max_depth, max_pages, same_domain, status, COALESCE(owner, ''), created_at, updated_at
+		FROM site_crawls
+		WHERE id = ?
+	`
+
+	siteCrawl := &models.SiteCrawl{}
+	err := cs.db.QueryRowContext(ctx, query, id).Scan(
+		&siteCrawl.ID,
+		&siteCrawl.SeedURL,
+		&siteCrawl.MaxDepth,
+		&siteCrawl.MaxPages,
+		&siteCrawl.SameDomain,
+		&siteCrawl.Status,
+		&siteCrawl.Owner,
+		&siteCrawl.CreatedAt,
+		&siteCrawl.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("site crawl not found")
+		}
+		return nil, fmt.Errorf("failed to get site crawl: %w", err)
+	}
+
+	return siteCrawl, nil
+}
+
+// GetSiteCrawlStats returns aggregate statistics for the pages of a site crawl
+func (cs *CrawlStorage) GetSiteCrawlStats(ctx context.Context, siteCrawlID string) (*models.SiteCrawlStats, error) {
+	query := `
+		SELECT
+			COUNT(*) as total,
+			COALESCE(SUM(CASE WHEN status = 'queued' THEN 1 ELSE 0 END), 0) as queued,
+			COALESCE(SUM(CASE WHEN status = 'running' THEN 1 ELSE 0 END), 0) as running,
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END), 0) as completed,
+			COALESCE(SUM(CASE WHEN status = 'error' THEN 1 ELSE 0 END), 0) as error,
+			COALESCE(SUM(CASE WHEN status = 'cancelled' THEN 1 ELSE 0 END), 0) as cancelled,
+			COALESCE(SUM(CASE WHEN inaccessible_links_count > 0 THEN 1 ELSE 0 END), 0) as with_broken_links,
+			COALESCE(SUM(CASE WHEN has_login_form THEN 1 ELSE 0 END), 0) as with_login_forms
+		FROM crawl_results
+		WHERE site_crawl_id = ?
+	`
+
+	stats := &models.SiteCrawlStats{}
+	err := cs.db.QueryRowContext(ctx, query, siteCrawlID).Scan(
+		&stats.TotalPages,
+		&stats.Queued,
+		&stats.Running,
+		&stats.Completed,
+		&stats.Error,
+		&stats.Cancelled,
+		&stats.PagesWithBrokenLinks,
+		&stats.PagesWithLoginForms,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get site crawl stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetSiteCrawlPages retrieves the per-page results of a site crawl ordered by depth
+func (cs *CrawlStorage) GetSiteCrawlPages(ctx context.Context, siteCrawlID string) ([]models.CrawlResult, error) {
+	query := `
+		SELECT ` + crawlResultColumns + `
+		FROM crawl_results
+		WHERE site_crawl_id = ?
+		ORDER BY depth ASC, created_at ASC
+	`
+
+	rows, err := cs.db.QueryContext(ctx, query, siteCrawlID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query site crawl pages: %w", err)
+	}
+	defer rows.Close()
+
+	pages := []models.CrawlResult{}
+	for rows.Next() {
+		page := models.CrawlResult{}
+		if err := scanCrawlResult(rows, &page); err != nil {
+			return nil, fmt.Errorf("failed to scan crawl result: %w", err)
+		}
+		pages = append(pages, page)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating results: %w", err)
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,410 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"url-crawler/internal/models"
+)
+
+// webhookColumns lists the webhooks columns in the order scanWebhook reads them
+const webhookColumns = `id, owner, url, secret, scope, created_at`
+
+// scanWebhook scans a row selected with webhookColumns
+func scanWebhook(row rowScanner, webhook *models.Webhook) error {
+	return row.Scan(
+		&webhook.ID,
+		&webhook.Owner,
+		&webhook.URL,
+		&webhook.Secret,
+		&webhook.Scope,
+		&webhook.CreatedAt,
+	)
+}
+
+// webhookDeliveryColumns lists the webhook_deliveries columns in the order scanWebhookDelivery reads them
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.crawl_id, d.event, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at, d.updated_at`
+
+// scanWebhookDelivery scans a row selected with webhookDeliveryColumns, followed by any extra columns
+func scanWebhookDelivery(row rowScanner, delivery *models.WebhookDelivery, extra ...interface{}) error {
+	var payload []byte
+	dest := []interface{}{
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.CrawlID,
+		&delivery.Event,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	delivery.Payload = payload
+	return nil
+}
+
+// SaveWebhook inserts a new webhook
+func (cs *CrawlStorage) SaveWebhook(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (id, owner, url, secret, scope, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := cs.db.ExecContext(ctx, query,
+		webhook.ID,
+		webhook.Owner,
+		webhook.URL,
+		webhook.Secret,
+		webhook.Scope,
+		webhook.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save webhook: %w", err)
+	}
+
+	return nil
+}
+
+// GetWebhook retrieves a webhook registered by the given API key
+func (cs *CrawlStorage) GetWebhook(ctx context.Context, id, owner string) (*models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE id = ? AND owner = ?
+	`
+
+	webhook := &models.Webhook{}
+	if err := scanWebhook(cs.db.QueryRowContext(ctx, query, id, owner), webhook); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+// GetWebhooks returns the webhooks registered by the given API key, newest first
+func (cs *CrawlStorage) GetWebhooks(ctx context.Context, owner string) ([]models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE owner = ?
+		ORDER BY created_at DESC
+	`
+	return cs.queryWebhooks(ctx, query, owner)
+}
+
+// GetCrawlWebhooks returns the webhooks to notify about a finished crawl: those
+// registered for every crawl of its owner, and the one registered with the crawl itself
+func (cs *CrawlStorage) GetCrawlWebhooks(ctx context.Context, owner, webhookID *string) ([]models.Webhook, error) {
+	query := `
+		SELECT ` + webhookColumns + `
+		FROM webhooks
+		WHERE (scope = 'key' AND owner = ?) OR id = ?
+	`
+	return cs.queryWebhooks(ctx, query, owner, webhookID)
+}
+
+// queryWebhooks runs a webhook query selecting webhookColumns
+func (cs *CrawlStorage) queryWebhooks(ctx context.Context, query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := cs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := scanWebhook(rows, &webhook); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook removes a webhook registered by the given API key, along with its deliveries
+func (cs *CrawlStorage) DeleteWebhook(ctx context.Context, id, owner string) error {
+	return cs.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ? AND owner = ?", id, owner)
+		if err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("webhook not found")
+		}
+
+		query := `
+			DELETE a FROM webhook_delivery_attempts a
+			JOIN webhook_deliveries d ON d.id = a.delivery_id
+			WHERE d.webhook_id = ?
+		`
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return fmt.Errorf("failed to delete webhook delivery attempts: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// CreateWebhookDeliveries queues deliveries for immediate sending
+func (cs *CrawlStorage) CreateWebhookDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (
+			id, webhook_id, crawl_id, event, payload, status, attempts, next_attempt_at, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, 'pending', 0, NOW(3), ?, ?)
+	`
+
+	return cs.withTx(ctx, func(tx *sql.Tx) error {
+		for _, delivery := range deliveries {
+			_, err := tx.ExecContext(ctx, query,
+				delivery.ID,
+				delivery.WebhookID,
+				delivery.CrawlID,
+				delivery.Event,
+				string(delivery.Payload),
+				delivery.CreatedAt,
+				delivery.UpdatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to save webhook delivery: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimWebhookDelivery takes the next due delivery and the webhook it is for,
+// counting the attempt and holding it for lease so no other sender picks it up
+// while the request is in flight. Both are nil when nothing is due.
+func (cs *CrawlStorage) ClaimWebhookDelivery(ctx context.Context, lease time.Duration) (*models.WebhookDelivery, *models.Webhook, error) {
+	var delivery *models.WebhookDelivery
+	var webhook *models.Webhook
+
+	err := cs.withTx(ctx, func(tx *sql.Tx) error {
+		query := `
+			SELECT ` + webhookDeliveryColumns + `, w.url, w.secret
+			FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW(3)
+			ORDER BY d.next_attempt_at ASC
+			LIMIT 1
+			FOR UPDATE OF d SKIP LOCKED
+		`
+
+		claimed := &models.WebhookDelivery{}
+		target := &models.Webhook{}
+		if err := scanWebhookDelivery(tx.QueryRowContext(ctx, query), claimed, &target.URL, &target.Secret); err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return fmt.Errorf("failed to select webhook delivery: %w", err)
+		}
+
+		update := `
+			UPDATE webhook_deliveries
+			SET attempts = attempts + 1, next_attempt_at = NOW(3) + INTERVAL ? MICROSECOND, updated_at = ?
+			WHERE id = ?
+		`
+		if _, err := tx.ExecContext(ctx, update, lease.Microseconds(), time.Now(), claimed.ID); err != nil {
+			return fmt.Errorf("failed to lease webhook delivery: %w", err)
+		}
+
+		claimed.Attempts++
+		target.ID = claimed.WebhookID
+		delivery, webhook = claimed, target
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return delivery, webhook, nil
+}
+
+// RecordWebhookAttempt logs a delivery request and stores the delivery's new
+// state. Pending deliveries become due again after retryIn.
+func (cs *CrawlStorage) RecordWebhookAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt, retryIn time.Duration) error {
+	return cs.withTx(ctx, func(tx *sql.Tx) error {
+		insert := `
+			INSERT INTO webhook_delivery_attempts (delivery_id, attempt, status_code, error, duration_ms, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`
+		_, err := tx.ExecContext(ctx, insert,
+			attempt.DeliveryID,
+			attempt.Attempt,
+			attempt.StatusCode,
+			attempt.Error,
+			attempt.DurationMs,
+			attempt.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to log webhook delivery attempt: %w", err)
+		}
+
+		update := `
+			UPDATE webhook_deliveries
+			SET status = ?, last_status_code = ?, last_error = ?, delivered_at = ?,
+				next_attempt_at = NOW(3) + INTERVAL ? MICROSECOND, updated_at = ?
+			WHERE id = ?
+		`
+		_, err = tx.ExecContext(ctx, update,
+			delivery.Status,
+			delivery.LastStatusCode,
+			delivery.LastError,
+			delivery.DeliveredAt,
+			retryIn.Microseconds(),
+			time.Now(),
+			delivery.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update webhook delivery: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetWebhookDeliveries returns up to limit deliveries to a webhook, most recent first
+func (cs *CrawlStorage) GetWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries d
+		WHERE d.webhook_id = ?
+		ORDER BY d.created_at DESC
+		LIMIT ?
+	`
+
+	rows, err := cs.db.QueryContext(ctx, query, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := scanWebhookDelivery(rows, &delivery); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// GetWebhookDelivery retrieves a delivery to a webhook of the given API key, with its attempt log
+func (cs *CrawlStorage) GetWebhookDelivery(ctx context.Context, id, owner string) (*models.WebhookDelivery, error) {
+	query := `
+		SELECT ` + webhookDeliveryColumns + `
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = ? AND w.owner = ?
+	`
+
+	delivery := &models.WebhookDelivery{}
+	if err := scanWebhookDelivery(cs.db.QueryRowContext(ctx, query, id, owner), delivery); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook delivery not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	attempts, err := cs.getWebhookDeliveryAttempts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	delivery.AttemptLog = attempts
+
+	return delivery, nil
+}
+
+// getWebhookDeliveryAttempts returns the logged requests of a delivery in the order they were made
+func (cs *CrawlStorage) getWebhookDeliveryAttempts(ctx context.Context, deliveryID string) ([]models.WebhookDeliveryAttempt, error) {
+	query := `
+		SELECT delivery_id, attempt, status_code, error, duration_ms, created_at
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = ?
+		ORDER BY id ASC
+	`
+
+	rows, err := cs.db.QueryContext(ctx, query, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook delivery attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []models.WebhookDeliveryAttempt{}
+	for rows.Next() {
+		var attempt models.WebhookDeliveryAttempt
+		if err := rows.Scan(
+			&attempt.DeliveryID,
+			&attempt.Attempt,
+			&attempt.StatusCode,
+			&attempt.Error,
+			&attempt.DurationMs,
+			&attempt.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery attempt: %w", err)
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook delivery attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// RedeliverWebhookDelivery queues a delivery to a webhook of the given API key
+// for sending again, with a fresh set of attempts
+func (cs *CrawlStorage) RedeliverWebhookDelivery(ctx context.Context, id, owner string) error {
+	query := `
+		UPDATE webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		SET d.status = 'pending', d.attempts = 0, d.next_attempt_at = NOW(3), d.updated_at = ?
+		WHERE d.id = ? AND w.owner = ?
+	`
+
+	res, err := cs.db.ExecContext(ctx, query, time.Now(), id, owner)
+	if err != nil {
+		return fmt.Errorf("failed to redeliver webhook delivery: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook delivery not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"url-crawler/internal/models"
+)
+
+// HeartbeatWorker registers a worker process or refreshes its heartbeat
+func (cs *CrawlStorage) HeartbeatWorker(ctx context.Context, worker *models.Worker) error {
+	query := `
+		INSERT INTO crawl_workers (
+			id, hostname, pid, concurrency, active_tasks, started_at, last_heartbeat_at
+		) VALUES (?, ?, ?, ?, ?, ?, NOW(3))
+		ON DUPLICATE KEY UPDATE
+			concurrency = VALUES(concurrency),
+			active_tasks = VALUES(active_tasks),
+			last_heartbeat_at = NOW(3)
+	`
+
+	_, err := cs.db.ExecContext(ctx, query,
+		worker.ID,
+		worker.Hostname,
+		worker.PID,
+		worker.Concurrency,
+		worker.ActiveTasks,
+		worker.StartedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record worker heartbeat: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveWorker deregisters a worker process that is shutting down
+func (cs *CrawlStorage) RemoveWorker(ctx context.Context, id string) error {
+	_, err := cs.db.ExecContext(ctx, "DELETE FROM crawl_workers WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to remove worker: %w", err)
+	}
+
+	return nil
+}
+
+// PruneWorkers deletes workers that have not sent a heartbeat within maxAge,
+// typically because they crashed without deregistering
+func (cs *CrawlStorage) PruneWorkers(ctx context.Context, maxAge time.Duration) (int, error) {
+	res, err := cs.db.ExecContext(ctx,
+		"DELETE FROM crawl_workers WHERE last_heartbeat_at < NOW(3) - INTERVAL ? MICROSECOND",
+		maxAge.Microseconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune workers: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
+
+// GetLiveWorkers returns the workers that sent a heartbeat within maxAge
+func (cs *CrawlStorage) GetLiveWorkers(ctx context.Context, maxAge time.Duration) ([]models.Worker, error) {
+	query := `
+		SELECT id, hostname, pid, concurrency, active_tasks, started_at, last_heartbeat_at
+		FROM crawl_workers
+		WHERE last_heartbeat_at >= NOW(3) - INTERVAL ? MICROSECOND
+		ORDER BY started_at ASC
+	`
+
+	rows, err := cs.db.QueryContext(ctx, query, maxAge.Microseconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workers: %w", err)
+	}
+	defer rows.Close()
+
+	workers := []models.Worker{}
+	for rows.Next() {
+		worker := models.Worker{}
+		err := rows.Scan(
+			&worker.ID,
+			&worker.Hostname,
+			&worker.PID,
+			&worker.Concurrency,
+			&worker.ActiveTasks,
+			&worker.StartedAt,
+			&worker.LastHeartbeatAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan worker: %w", err)
+		}
+		workers = append(workers, worker)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workers: %w", err)
+	}
+
+	return workers, nil
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/models"
+	"url-crawler/internal/services"
+)
+
+// maxBatchBodyBytes caps the size of a batch submission body or upload
+const maxBatchBodyBytes = 4 << 20
+
+// errBatchTooLarge is returned when a submission holds more URLs than a batch may contain
+var errBatchTooLarge = fmt.Errorf("a batch may contain at most %d URLs", models.MaxBatchSize)
+
+// CreateBatchCrawl handles POST /api/crawl/batch requests. The URLs are read from
+// a JSON array, a newline-delimited body or a CSV file uploaded as multipart form
+// data; the priority query parameter applies to every crawl of the batch.
+func (h *CrawlHandler) CreateBatchCrawl(c echo.Context) error {
+	priority := models.CrawlPriority(c.QueryParam("priority"))
+	if priority != "" && !priority.IsValid() {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid priority, must be one of: high, normal, low",
+		})
+	}
+
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxBatchBodyBytes)
+
+	urls, source, err := readBatchURLs(c)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) || errors.Is(err, errBatchTooLarge) {
+			return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		var contentTypeErr *unsupportedContentTypeError
+		if errors.As(err, &contentTypeErr) {
+			return c.JSON(http.StatusUnsupportedMediaType, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid batch: " + err.Error(),
+		})
+	}
+	if len(urls) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Batch contains no URLs",
+		})
+	}
+
+	// URLs are validated one by one, so validation runs on a budget
+	budget, cancel := context.WithTimeout(c.Request().Context(), h.enqueueBudget)
+	defer cancel()
+
+	submission, err := h.queue.EnqueueBatch(budget, urls, source, services.EnqueueOptions{
+		Priority: priority,
+		Owner:    apiKeyName(c),
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrQueueFull) {
+			return c.JSON(http.StatusTooManyRequests, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrQueueStopped) {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to enqueue batch",
+		})
+	}
+
+	if submission.BatchID == "" && len(submission.Unprocessed) > 0 {
+		return c.JSON(http.StatusGatewayTimeout, map[string]interface{}{
+			"error":       "Timed out validating the batch",
+			"errors":      submission.Errors,
+			"unprocessed": submission.Unprocessed,
+		})
+	}
+	if submission.BatchID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":  "Batch contains no valid URLs",
+			"errors": submission.Errors,
+		})
+	}
+
+	return c.JSON(http.StatusCreated, submission)
+}
+
+// GetBatch handles GET /api/batches/:id requests
+func (h *CrawlHandler) GetBatch(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing batch ID",
+		})
+	}
+
+	batch, err := h.storage.GetBatch(c.Request().Context(), id, apiKeyName(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Batch not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve batch",
+		})
+	}
+
+	progress, err := h.storage.GetBatchProgress(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve batch progress",
+		})
+	}
+
+	memberIDs, err := h.storage.GetBatchMemberIDs(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve batch members",
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.BatchStatus{
+		Batch:         *batch,
+		BatchProgress: *progress,
+		Done:          progress.Pending == 0,
+		MemberIDs:     memberIDs,
+	})
+}
+
+// unsupportedContentTypeError is returned for a batch body in an unknown format
+type unsupportedContentTypeError struct {
+	contentType string
+}
+
+func (e *unsupportedContentTypeError) Error() string {
+	return fmt.Sprintf("unsupported content type %q, expected application/json, text/plain, application/x-ndjson, text/csv or multipart/form-data", e.contentType)
+}
+
+// readBatchURLs reads the URLs of a batch submission according to its content type
+func readBatchURLs(c echo.Context) ([]string, models.BatchSource, error) {
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, "", &unsupportedContentTypeError{contentType: contentType}
+	}
+
+	switch mediaType {
+	case echo.MIMEApplicationJSON:
+		urls, err := parseBatchJSON(c.Request().Body)
+		return urls, models.BatchSourceJSON, err
+	case echo.MIMETextPlain, "application/x-ndjson":
+		urls, err := parseBatchLines(c.Request().Body)
+		return urls, models.BatchSourceLines, err
+	case "text/csv":
+		urls, err := parseBatchCSV(c.Request().Body)
+		return urls, models.BatchSourceCSV, err
+	case echo.MIMEMultipartForm:
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("expected a CSV file in the \"file\" form field: %w", err)
+		}
+		upload, err := file.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open upload: %w", err)
+		}
+		defer upload.Close()
+		urls, err := parseBatchCSV(upload)
+		return urls, models.BatchSourceCSV, err
+	default:
+		return nil, "", &unsupportedContentTypeError{contentType: mediaType}
+	}
+}
+
+// parseBatchEntry reads a URL given as a JSON string or as an object with a url field
+func parseBatchEntry(raw []byte) (string, error) {
+	var url string
+	if err := json.Unmarshal(raw, &url); err == nil {
+		return url, nil
+	}
+
+	var req models.CrawlRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return "", fmt.Errorf("entries must be URL strings or objects with a url field")
+	}
+	return req.URL, nil
+}
+
+// parseBatchJSON reads a JSON array of URL strings or {"url": ...} objects
+func parseBatchJSON(body io.Reader) ([]string, error) {
+	var entries []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("expected a JSON array: %w", err)
+	}
+	if len(entries) > models.MaxBatchSize {
+		return nil, errBatchTooLarge
+	}
+
+	urls := make([]string, 0, len(entries))
+	for i, entry := range entries {
+		url, err := parseBatchEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		urls = append(urls, url)
+	}
+	return urls, nil
+}
+
+// parseBatchLines reads one URL per line, skipping blank lines and # comments.
+// A line may also hold a JSON string or object, as in newline-delimited JSON.
+func parseBatchLines(body io.Reader) ([]string, error) {
+	var urls []string
+	scanner := bufio.NewScanner(body)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		url := text
+		if strings.HasPrefix(text, "{") || strings.HasPrefix(text, `"`) {
+			entry, err := parseBatchEntry([]byte(text))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			url = entry
+		}
+
+		if len(urls) == models.MaxBatchSize {
+			return nil, errBatchTooLarge
+		}
+		urls = append(urls, url)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// parseBatchCSV reads URLs from a CSV file. The column named "url" is used when
+// the first row is a header; otherwise the first column holds the URLs.
+func parseBatchCSV(body io.Reader) ([]string, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Spreadsheet exports often start with a byte order mark
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var urls []string
+	column := 0
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		if first {
+			first = false
+			if header := csvURLColumn(record); header >= 0 {
+				column = header
+				continue
+			}
+		}
+
+		if column >= len(record) || strings.TrimSpace(record[column]) == "" {
+			continue
+		}
+		if len(urls) == models.MaxBatchSize {
+			return nil, errBatchTooLarge
+		}
+		urls = append(urls, record[column])
+	}
+	return urls, nil
+}
+
+// csvURLColumn returns the index of the "url" column of a header row, or -1
+func csvURLColumn(header []string) int {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), "url") {
+			return i
+		}
+	}
+	return -1
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -19,16 +21,47 @@ import (
 type CrawlHandler struct {
 	queue     *services.QueueService
 	storage   *database.CrawlStorage
+	sitemaps  *services.SitemapFetcher
+	scheduler *services.Scheduler
+	webhooks  *services.WebhookService
+	events    *services.CrawlEvents
 	validator *validator.Validate
+
+	// enqueueBudget bounds the time a batch or sitemap request spends validating URLs
+	enqueueBudget time.Duration
 }
 
 // NewCrawlHandler creates a new crawl handler
-func NewCrawlHandler(queue *services.QueueService, storage *database.CrawlStorage) *CrawlHandler {
+func NewCrawlHandler(queue *services.QueueService, storage *database.CrawlStorage, sitemaps *services.SitemapFetcher, scheduler *services.Scheduler, webhooks *services.WebhookService, events *services.CrawlEvents, writeTimeout time.Duration) *CrawlHandler {
 	return &CrawlHandler{
-		queue:     queue,
-		storage:   storage,
-		validator: validator.New(),
+		queue:         queue,
+		storage:       storage,
+		sitemaps:      sitemaps,
+		scheduler:     scheduler,
+		webhooks:      webhooks,
+		events:        events,
+		validator:     validator.New(),
+		enqueueBudget: enqueueRequestBudget(writeTimeout),
+	}
+}
+
+// defaultEnqueueBudget bounds batch and sitemap requests when the server has no write timeout
+const defaultEnqueueBudget = 15 * time.Second
+
+// enqueueRequestBudget returns how long a request that enqueues many URLs may
+// spend validating them. Half the write timeout leaves headroom for the
+// validation underway when the budget runs out and for saving the crawls.
+func enqueueRequestBudget(writeTimeout time.Duration) time.Duration {
+	if writeTimeout <= 0 {
+		return defaultEnqueueBudget
 	}
+	return writeTimeout / 2
+}
+
+// apiKeyName returns the name of the API key that authenticated the request
+func apiKeyName(c echo.Context) string {
+	name, _ := c.Get("api_key_name").(string)
+	return name
 }
 
 // CreateCrawlRequest handles POST /api/crawl requests
@@ -49,14 +82,43 @@ func (h *CrawlHandler) CreateCrawlRequest(c echo.Context) error {
 		})
 	}
 
+	opts := services.EnqueueOptions{
+		Priority: req.Priority,
+		Owner:    apiKeyName(c),
+	}
+
+	// Register the crawl's own webhook first, so it cannot finish unnoticed
+	var webhook *models.WebhookRegistration
+	if req.Webhook != nil {
+		registered, err := h.webhooks.Register(c.Request().Context(), opts.Owner, models.WebhookScopeCrawl, *req.Webhook)
+		if err != nil {
+			return webhookRegistrationError(c, err)
+		}
+		webhook = registered
+		opts.WebhookID = webhook.ID
+	}
+
 	// Enqueue the URL for crawling
-	result, err := h.queue.EnqueueURL(req.URL)
+	result, err := h.queue.EnqueueURL(c.Request().Context(), req.URL, opts)
 	if err != nil {
-		if strings.Contains(err.Error(), "queue is full") {
+		if webhook != nil {
+			h.storage.DeleteWebhook(c.Request().Context(), webhook.ID, opts.Owner)
+		}
+		if errors.Is(err, services.ErrDomainNotAllowed) || errors.Is(err, services.ErrForbiddenTarget) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrQueueFull) {
 			return c.JSON(http.StatusTooManyRequests, map[string]string{
 				"error": err.Error(),
 			})
 		}
+		if errors.Is(err, services.ErrQueueStopped) {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{
+				"error": err.Error(),
+			})
+		}
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": err.Error(),
 		})
@@ -68,6 +130,7 @@ func (h *CrawlHandler) CreateCrawlRequest(c echo.Context) error {
 		URL:     result.URL,
 		Status:  result.Status,
 		Message: "URL added to crawl queue successfully",
+		Webhook: webhook,
 	}
 
 	return c.JSON(http.StatusCreated, response)
@@ -123,7 +186,7 @@ func (h *CrawlHandler) GetCrawlResults(c echo.Context) error {
 	}
 
 	// Get results from storage
-	results, err := h.storage.GetCrawlResults(filters)
+	results, err := h.storage.GetCrawlResults(c.Request().Context(), filters)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to retrieve crawl results",
@@ -142,7 +205,7 @@ func (h *CrawlHandler) GetCrawlResult(c echo.Context) error {
 		})
 	}
 
-	result, err := h.storage.GetCrawlResult(id)
+	result, err := h.storage.GetCrawlResult(c.Request().Context(), id)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			return c.JSON(http.StatusNotFound, map[string]string{
@@ -178,7 +241,7 @@ func (h *CrawlHandler) DeleteCrawlResults(c echo.Context) error {
 	}
 
 	// Delete from storage
-	err := h.storage.DeleteCrawlResults(req.IDs)
+	err := h.storage.DeleteCrawlResults(c.Request().Context(), req.IDs)
 	if err != nil {
 		if strings.Contains(err.Error(), "no crawl results were deleted") {
 			return c.JSON(http.StatusNotFound, map[string]string{
@@ -216,21 +279,15 @@ func (h *CrawlHandler) RerunCrawlResults(c echo.Context) error {
 		})
 	}
 
-	// Update status to queued first
-	err := h.storage.UpdateCrawlResultsBulkStatus(req.IDs, models.CrawlStatusQueued)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to update crawl results status",
-		})
-	}
-
-	// Requeue each task
+	// Requeue each task; crawls that cannot be queued keep their stored results
 	var successCount int
 	var errors []string
+	failureStatus := http.StatusInternalServerError
 
 	for _, id := range req.IDs {
-		if err := h.queue.RequeueTask(id); err != nil {
+		if err := h.queue.RequeueTask(c.Request().Context(), id); err != nil {
 			errors = append(errors, "Failed to requeue "+id+": "+err.Error())
+			failureStatus = requeueFailureStatus(err)
 		} else {
 			successCount++
 		}
@@ -244,6 +301,120 @@ func (h *CrawlHandler) RerunCrawlResults(c echo.Context) error {
 
 	if len(errors) > 0 {
 		response["errors"] = errors
+		if successCount == 0 {
+			return c.JSON(failureStatus, response)
+		}
+		return c.JSON(http.StatusPartialContent, response)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// requeueFailureStatus maps a rerun error to the status returned when no crawl was requeued
+func requeueFailureStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrQueueFull):
+		return http.StatusTooManyRequests
+	case errors.Is(err, services.ErrQueueStopped):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, services.ErrTaskActive):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CancelCrawl handles POST /api/crawl/:id/cancel requests
+func (h *CrawlHandler) CancelCrawl(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing crawl result ID",
+		})
+	}
+
+	status, err := h.queue.CancelTask(c.Request().Context(), id)
+	if err != nil {
+		if !errors.Is(err, services.ErrTaskNotActive) {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to cancel crawl",
+			})
+		}
+
+		result, getErr := h.storage.GetCrawlResult(c.Request().Context(), id)
+		if getErr != nil {
+			if strings.Contains(getErr.Error(), "not found") {
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "Crawl result not found",
+				})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to retrieve crawl status",
+			})
+		}
+
+		return c.JSON(http.StatusConflict, map[string]interface{}{
+			"error":  "Crawl is not queued or running",
+			"status": result.Status,
+		})
+	}
+
+	message := "Crawl cancelled"
+	if status == models.CrawlStatusRunning {
+		message = "Cancellation requested, the crawl will stop shortly"
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"status":  status,
+		"message": message,
+	})
+}
+
+// CancelCrawlResults handles POST /api/crawl/cancel requests
+func (h *CrawlHandler) CancelCrawlResults(c echo.Context) error {
+	var req struct {
+		IDs []string `json:"ids" validate:"required,min=1"`
+	}
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	// Validate request
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request data: " + err.Error(),
+		})
+	}
+
+	// Cancel each task, reporting the resulting status per ID
+	var successCount int
+	var failures []string
+	statuses := make(map[string]models.CrawlStatus)
+
+	for _, id := range req.IDs {
+		status, err := h.queue.CancelTask(c.Request().Context(), id)
+		if err != nil {
+			failures = append(failures, "Failed to cancel "+id+": "+err.Error())
+			continue
+		}
+		statuses[id] = status
+		successCount++
+	}
+
+	response := map[string]interface{}{
+		"message":         "Cancel operation completed",
+		"success_count":   successCount,
+		"total_requested": len(req.IDs),
+		"statuses":        statuses,
+	}
+
+	if len(failures) > 0 {
+		response["errors"] = failures
 		return c.JSON(http.StatusPartialContent, response)
 	}
 
@@ -253,7 +424,7 @@ func (h *CrawlHandler) RerunCrawlResults(c echo.Context) error {
 // GetCrawlStats handles GET /api/crawl/stats requests
 func (h *CrawlHandler) GetCrawlStats(c echo.Context) error {
 	// Get database stats
-	dbStats, err := h.storage.GetCrawlStats()
+	dbStats, err := h.storage.GetCrawlStats(c.Request().Context())
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to retrieve crawl statistics",
@@ -261,7 +432,7 @@ func (h *CrawlHandler) GetCrawlStats(c echo.Context) error {
 	}
 
 	// Get queue stats
-	queueStats := h.queue.GetQueueStats()
+	queueStats := h.queue.GetQueueStats(c.Request().Context())
 
 	// Combine stats
 	response := map[string]interface{}{
@@ -284,16 +455,22 @@ func (h *CrawlHandler) GetCrawlStatus(c echo.Context) error {
 
 	// First check if it's in the active queue
 	if task, exists := h.queue.GetActiveTask(id); exists {
-		return c.JSON(http.StatusOK, map[string]interface{}{
+		response := map[string]interface{}{
 			"id":        task.ID,
 			"status":    task.Status,
 			"url":       task.URL,
 			"queued_at": task.CreatedAt,
-		})
+			"attempts":  task.Attempt,
+			"priority":  task.Priority,
+		}
+		if task.LastError != "" {
+			response["last_error"] = task.LastError
+		}
+		return c.JSON(http.StatusOK, response)
 	}
 
 	// If not in queue, get from database
-	result, err := h.storage.GetCrawlResult(id)
+	result, err := h.storage.GetCrawlResult(c.Request().Context(), id)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			return c.JSON(http.StatusNotFound, map[string]string{
@@ -311,18 +488,30 @@ func (h *CrawlHandler) GetCrawlStatus(c echo.Context) error {
 		"url":        result.URL,
 		"created_at": result.CreatedAt,
 		"updated_at": result.UpdatedAt,
+		"attempts":   result.Attempts,
 	}
 
 	if result.ErrorMessage != nil {
 		response["error_message"] = *result.ErrorMessage
 	}
+	if result.LastError != nil {
+		response["last_error"] = *result.LastError
+	}
+
+	if job, err := h.queue.GetJob(c.Request().Context(), id); err == nil {
+		response["priority"] = job.Priority
+		// A queued crawl waiting out a retry backoff reports when it becomes due
+		if result.Status == models.CrawlStatusQueued && job.AvailableAt.After(time.Now()) {
+			response["next_attempt_at"] = job.AvailableAt
+		}
+	}
 
 	return c.JSON(http.StatusOK, response)
 }
 
 // HealthCheck handles GET /api/health requests
 func (h *CrawlHandler) HealthCheck(c echo.Context) error {
-	queueStats := h.queue.GetQueueStats()
+	queueStats := h.queue.GetQueueStats(c.Request().Context())
 
 	response := map[string]interface{}{
 		"status":    "healthy",
@@ -339,9 +528,9 @@ func (h *CrawlHandler) getCurrentTimestamp() string {
 }
 
 // validateCrawlIDs validates that all provided IDs exist in the database
-func (h *CrawlHandler) validateCrawlIDs(ids []string) error {
+func (h *CrawlHandler) validateCrawlIDs(ctx context.Context, ids []string) error {
 	for _, id := range ids {
-		_, err := h.storage.GetCrawlResult(id)
+		_, err := h.storage.GetCrawlResult(ctx, id)
 		if err != nil {
 			if strings.Contains(err.Error(), "not found") {
 				return fmt.Errorf("crawl result with ID %s not found", id)
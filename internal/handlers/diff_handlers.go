@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/models"
+	"url-crawler/internal/services"
+)
+
+// GetCrawlDiff handles GET /api/crawl/diff?a=..&b=.. requests. Each side is a
+// crawl ID, comparing its latest result, or "<crawl ID>:<run number>" to
+// compare a past run, e.g. a=<id>:1&b=<id>:2 for two runs of the same URL.
+func (h *CrawlHandler) GetCrawlDiff(c echo.Context) error {
+	refA, refB := c.QueryParam("a"), c.QueryParam("b")
+	if refA == "" || refB == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Both a and b query parameters are required",
+		})
+	}
+
+	a, runA, err := h.loadDiffSide(c.Request().Context(), refA)
+	if err != nil {
+		return diffSideError(c, "a", err)
+	}
+	b, runB, err := h.loadDiffSide(c.Request().Context(), refB)
+	if err != nil {
+		return diffSideError(c, "b", err)
+	}
+
+	diff, err := services.DiffCrawlResults(a, b)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Only completed crawls can be compared: " + diffNotCompleted(a, b),
+		})
+	}
+	diff.A.RunNumber = runA
+	diff.B.RunNumber = runB
+
+	return c.JSON(http.StatusOK, diff)
+}
+
+// diffNotCompleted names the sides of a diff that have not completed, with their status
+func diffNotCompleted(a, b *models.CrawlResult) string {
+	var sides []string
+	if a.Status != models.CrawlStatusCompleted {
+		sides = append(sides, "a is "+string(a.Status))
+	}
+	if b.Status != models.CrawlStatusCompleted {
+		sides = append(sides, "b is "+string(b.Status))
+	}
+	return strings.Join(sides, ", ")
+}
+
+// errInvalidDiffRef is returned for diff references that cannot be parsed
+var errInvalidDiffRef = errors.New("must be a crawl ID or <crawl ID>:<run number>")
+
+// loadDiffSide loads the crawl result or past run a diff reference points to
+func (h *CrawlHandler) loadDiffSide(ctx context.Context, ref string) (*models.CrawlResult, *int, error) {
+	id, runStr, isRun := strings.Cut(ref, ":")
+	if !isRun {
+		result, err := h.storage.GetCrawlResult(ctx, id)
+		return result, nil, err
+	}
+
+	runNumber, err := strconv.Atoi(runStr)
+	if id == "" || err != nil || runNumber < 1 {
+		return nil, nil, errInvalidDiffRef
+	}
+
+	run, err := h.storage.GetCrawlRun(ctx, id, runNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	return run.Result(), &runNumber, nil
+}
+
+// diffSideError reports why one side of a diff could not be loaded
+func diffSideError(c echo.Context, side string, err error) error {
+	switch {
+	case errors.Is(err, errInvalidDiffRef):
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": side + " " + err.Error(),
+		})
+	case strings.Contains(err.Error(), "not found"):
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Crawl " + side + " not found",
+		})
+	default:
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve crawl " + side,
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/models"
+)
+
+// eventRetryMillis is how long EventSource clients wait before reconnecting a dropped stream
+const eventRetryMillis = 3000
+
+// StreamCrawlEvents handles GET /api/crawl/events requests, streaming the state
+// changes of every crawl requested with the caller's API key as Server-Sent
+// Events. Without a Last-Event-ID only new events are sent.
+func (h *CrawlHandler) StreamCrawlEvents(c echo.Context) error {
+	lastID, resumed, err := lastEventID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if !resumed {
+		lastID, err = h.events.LatestID(c.Request().Context())
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to start event stream",
+			})
+		}
+	}
+
+	return h.streamEvents(c, models.CrawlEventFilter{Owner: apiKeyName(c)}, lastID)
+}
+
+// StreamCrawlEventsForCrawl handles GET /api/crawl/:id/events requests, streaming
+// the state changes of one crawl requested with the caller's API key as
+// Server-Sent Events. Without a Last-Event-ID the crawl's recent events are
+// replayed first.
+func (h *CrawlHandler) StreamCrawlEventsForCrawl(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing crawl result ID",
+		})
+	}
+
+	lastID, _, err := lastEventID(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	result, err := h.storage.GetCrawlResult(c.Request().Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Crawl result not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve crawl result",
+		})
+	}
+
+	// Other keys' crawls are reported as missing rather than forbidden, so their IDs are not confirmed
+	owner := ""
+	if result.Owner != nil {
+		owner = *result.Owner
+	}
+	if owner != apiKeyName(c) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Crawl result not found",
+		})
+	}
+
+	return h.streamEvents(c, models.CrawlEventFilter{CrawlID: id}, lastID)
+}
+
+// lastEventID reads the event ID a client resumes after, from the Last-Event-ID
+// header sent by reconnecting EventSource clients or the lastEventId query parameter
+func lastEventID(c echo.Context) (int64, bool, error) {
+	value := c.Request().Header.Get("Last-Event-ID")
+	if value == "" {
+		value = c.QueryParam("lastEventId")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false, fmt.Errorf("Last-Event-ID must be a non-negative integer")
+	}
+	return id, true, nil
+}
+
+// streamEvents writes the matching events after lastID as Server-Sent Events
+// until the client disconnects
+func (h *CrawlHandler) streamEvents(c echo.Context, filter models.CrawlEventFilter, lastID int64) error {
+	res := c.Response()
+
+	// A stream stays open far longer than the server's write timeout
+	http.NewResponseController(res).SetWriteDeadline(time.Time{})
+
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering
+	res.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(res, "retry: %d\n\n", eventRetryMillis)
+	res.Flush()
+
+	emit := func(event models.CrawlEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	}
+	keepAlive := func() error {
+		if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+			return err
+		}
+		res.Flush()
+		return nil
+	}
+
+	// The stream ends when the client goes away; there is no error to report to it
+	h.events.Stream(c.Request().Context(), filter, lastID, emit, keepAlive)
+	return nil
+}
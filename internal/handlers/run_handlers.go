@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Run history page size limits
+const (
+	defaultRunsLimit = 50
+	maxRunsLimit     = 500
+)
+
+// GetCrawlRuns handles GET /api/crawl/:id/runs requests
+func (h *CrawlHandler) GetCrawlRuns(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing crawl result ID",
+		})
+	}
+
+	limit := defaultRunsLimit
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxRunsLimit {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "limit must be between 1 and " + strconv.Itoa(maxRunsLimit),
+			})
+		}
+		limit = parsed
+	}
+
+	if _, err := h.storage.GetCrawlResult(c.Request().Context(), id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Crawl result not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve crawl result",
+		})
+	}
+
+	runs, err := h.storage.GetCrawlRuns(c.Request().Context(), id, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve crawl runs",
+		})
+	}
+
+	total, err := h.storage.CountCrawlRuns(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to count crawl runs",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"crawlId": id,
+		"runs":    runs,
+		"total":   total,
+	})
+}
+
+// GetCrawlRun handles GET /api/crawl/:id/runs/:n requests
+func (h *CrawlHandler) GetCrawlRun(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing crawl result ID",
+		})
+	}
+
+	runNumber, err := strconv.Atoi(c.Param("n"))
+	if err != nil || runNumber < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Run number must be a positive integer",
+		})
+	}
+
+	run, err := h.storage.GetCrawlRun(c.Request().Context(), id, runNumber)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Crawl run not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve crawl run",
+		})
+	}
+
+	return c.JSON(http.StatusOK, run)
+}
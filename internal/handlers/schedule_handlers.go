@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/models"
+	"url-crawler/internal/services"
+)
+
+// CreateSchedule handles POST /api/schedules requests
+func (h *CrawlHandler) CreateSchedule(c echo.Context) error {
+	var req models.ScheduleRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	// Validate request
+	req.ApplyDefaults()
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request data: " + err.Error(),
+		})
+	}
+
+	schedule, err := h.scheduler.CreateSchedule(c.Request().Context(), req, apiKeyName(c))
+	if err != nil {
+		if errors.Is(err, services.ErrDomainNotAllowed) || errors.Is(err, services.ErrForbiddenTarget) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		// Invalid URLs, cron expressions and timezones
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, schedule)
+}
+
+// GetSchedules handles GET /api/schedules requests
+func (h *CrawlHandler) GetSchedules(c echo.Context) error {
+	schedules, err := h.storage.GetSchedules(c.Request().Context(), apiKeyName(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve schedules",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"schedules": schedules,
+		"total":     len(schedules),
+	})
+}
+
+// GetSchedule handles GET /api/schedules/:id requests
+func (h *CrawlHandler) GetSchedule(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing schedule ID",
+		})
+	}
+
+	schedule, err := h.storage.GetSchedule(c.Request().Context(), id, apiKeyName(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Schedule not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve schedule",
+		})
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteSchedule handles DELETE /api/schedules/:id requests
+func (h *CrawlHandler) DeleteSchedule(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing schedule ID",
+		})
+	}
+
+	if err := h.storage.DeleteSchedule(c.Request().Context(), id, apiKeyName(c)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Schedule not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete schedule",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Schedule deleted",
+		"id":      id,
+	})
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/models"
+	"url-crawler/internal/services"
+)
+
+// CreateSiteCrawl handles POST /api/crawl/site requests
+func (h *CrawlHandler) CreateSiteCrawl(c echo.Context) error {
+	var req models.SiteCrawlRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	// Validate request
+	req.ApplyDefaults()
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request data: " + err.Error(),
+		})
+	}
+
+	// Start the site crawl from the seed URL
+	siteCrawl, err := h.queue.EnqueueSiteCrawl(c.Request().Context(), req, apiKeyName(c))
+	if err != nil {
+		if errors.Is(err, services.ErrDomainNotAllowed) || errors.Is(err, services.ErrForbiddenTarget) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrQueueFull) {
+			return c.JSON(http.StatusTooManyRequests, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, services.ErrQueueStopped) {
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, siteCrawl)
+}
+
+// GetSiteCrawl handles GET /api/crawl/site/:id requests
+func (h *CrawlHandler) GetSiteCrawl(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing site crawl ID",
+		})
+	}
+
+	siteCrawl, err := h.storage.GetSiteCrawl(c.Request().Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Site crawl not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve site crawl",
+		})
+	}
+
+	stats, err := h.storage.GetSiteCrawlStats(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve site crawl statistics",
+		})
+	}
+
+	pages, err := h.storage.GetSiteCrawlPages(c.Request().Context(), id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve site crawl pages",
+		})
+	}
+
+	return c.JSON(http.StatusOK, models.SiteCrawlResults{
+		SiteCrawl: *siteCrawl,
+		Stats:     *stats,
+		Pages:     pages,
+	})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/models"
+	"url-crawler/internal/services"
+)
+
+// CreateSitemapCrawl handles POST /api/crawl/sitemap requests. URLs that cannot
+// be validated and enqueued within the request budget are reported as unprocessed.
+func (h *CrawlHandler) CreateSitemapCrawl(c echo.Context) error {
+	var req models.SitemapCrawlRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	// Validate request
+	req.ApplyDefaults()
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request data: " + err.Error(),
+		})
+	}
+
+	filter := services.SitemapFilter{PathPrefix: req.PathPrefix}
+	if req.Pattern != "" {
+		pattern, err := regexp.Compile(req.Pattern)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid pattern: " + err.Error(),
+			})
+		}
+		filter.Pattern = pattern
+	}
+
+	// Every URL is validated before it is enqueued, so the whole request runs on a budget
+	budget, cancel := context.WithTimeout(c.Request().Context(), h.enqueueBudget)
+	defer cancel()
+
+	// Fetch and parse the sitemap
+	entries, err := h.sitemaps.Fetch(budget, req.URL)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return c.JSON(http.StatusGatewayTimeout, map[string]string{
+				"error": "Timed out fetching the sitemap",
+			})
+		}
+		if errors.Is(err, services.ErrDomainNotAllowed) || errors.Is(err, services.ErrForbiddenTarget) {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": err.Error(),
+			})
+		}
+		return c.JSON(http.StatusBadGateway, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	// The sitemap's crawls are tracked as a batch
+	batch := &models.Batch{
+		ID:        uuid.New().String(),
+		Owner:     apiKeyName(c),
+		Source:    models.BatchSourceSitemap,
+		CreatedAt: time.Now(),
+	}
+	if err := h.storage.SaveBatch(c.Request().Context(), batch); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create batch",
+		})
+	}
+
+	response := models.SitemapCrawlResponse{
+		BatchID: batch.ID,
+		Sitemap: req.URL,
+		Found:   len(entries),
+		Crawls:  []models.SitemapCrawl{},
+	}
+
+	// Enqueue each matching URL as a regular crawl
+	for _, entry := range entries {
+		if !filter.Match(entry.URL) || response.Accepted+response.Rejected >= req.Limit {
+			response.Skipped++
+			continue
+		}
+		if budget.Err() != nil {
+			response.Unprocessed++
+			continue
+		}
+
+		result, err := h.queue.EnqueueURL(c.Request().Context(), entry.URL, services.EnqueueOptions{
+			Priority: req.Priority,
+			Owner:    batch.Owner,
+			BatchID:  batch.ID,
+		})
+		if err != nil {
+			response.Rejected++
+			response.Errors = append(response.Errors, entry.URL+": "+err.Error())
+			continue
+		}
+
+		response.Accepted++
+		response.Crawls = append(response.Crawls, models.SitemapCrawl{
+			ID:           result.ID,
+			SitemapEntry: entry,
+		})
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/models"
+	"url-crawler/internal/services"
+)
+
+// Delivery log page size limits
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 500
+)
+
+// CreateWebhook handles POST /api/webhooks requests. The webhook is notified
+// about every crawl requested with the caller's API key.
+func (h *CrawlHandler) CreateWebhook(c echo.Context) error {
+	var req models.WebhookRequest
+
+	// Bind request body
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request format",
+		})
+	}
+
+	// Validate request
+	if err := h.validator.Struct(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request data: " + err.Error(),
+		})
+	}
+
+	webhook, err := h.webhooks.Register(c.Request().Context(), apiKeyName(c), models.WebhookScopeKey, req)
+	if err != nil {
+		return webhookRegistrationError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, webhook)
+}
+
+// webhookRegistrationError reports why a webhook could not be registered
+func webhookRegistrationError(c echo.Context, err error) error {
+	if errors.Is(err, services.ErrForbiddenTarget) {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	if strings.Contains(err.Error(), "invalid webhook URL") {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{
+		"error": "Failed to register webhook",
+	})
+}
+
+// GetWebhooks handles GET /api/webhooks requests
+func (h *CrawlHandler) GetWebhooks(c echo.Context) error {
+	webhooks, err := h.storage.GetWebhooks(c.Request().Context(), apiKeyName(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve webhooks",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"webhooks": webhooks,
+		"total":    len(webhooks),
+	})
+}
+
+// GetWebhook handles GET /api/webhooks/:id requests
+func (h *CrawlHandler) GetWebhook(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing webhook ID",
+		})
+	}
+
+	webhook, err := h.storage.GetWebhook(c.Request().Context(), id, apiKeyName(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Webhook not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve webhook",
+		})
+	}
+
+	return c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook handles DELETE /api/webhooks/:id requests
+func (h *CrawlHandler) DeleteWebhook(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing webhook ID",
+		})
+	}
+
+	if err := h.storage.DeleteWebhook(c.Request().Context(), id, apiKeyName(c)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Webhook not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete webhook",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Webhook deleted",
+		"id":      id,
+	})
+}
+
+// GetWebhookDeliveries handles GET /api/webhooks/:id/deliveries requests
+func (h *CrawlHandler) GetWebhookDeliveries(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing webhook ID",
+		})
+	}
+
+	limit := defaultDeliveriesLimit
+	if limitStr := c.QueryParam("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > maxDeliveriesLimit {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "limit must be between 1 and " + strconv.Itoa(maxDeliveriesLimit),
+			})
+		}
+		limit = parsed
+	}
+
+	if _, err := h.storage.GetWebhook(c.Request().Context(), id, apiKeyName(c)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Webhook not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve webhook",
+		})
+	}
+
+	deliveries, err := h.storage.GetWebhookDeliveries(c.Request().Context(), id, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve webhook deliveries",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"webhookId":  id,
+		"deliveries": deliveries,
+		"total":      len(deliveries),
+	})
+}
+
+// GetWebhookDelivery handles GET /api/webhooks/deliveries/:id requests,
+// returning the delivery with the response code of every attempt
+func (h *CrawlHandler) GetWebhookDelivery(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing delivery ID",
+		})
+	}
+
+	delivery, err := h.storage.GetWebhookDelivery(c.Request().Context(), id, apiKeyName(c))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Webhook delivery not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to retrieve webhook delivery",
+		})
+	}
+
+	return c.JSON(http.StatusOK, delivery)
+}
+
+// RedeliverWebhook handles POST /api/webhooks/deliveries/:id/redeliver requests
+func (h *CrawlHandler) RedeliverWebhook(c echo.Context) error {
+	id := c.Param("id")
+	if id == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Missing delivery ID",
+		})
+	}
+
+	if err := h.webhooks.Redeliver(c.Request().Context(), id, apiKeyName(c)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Webhook delivery not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to redeliver webhook delivery",
+		})
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]string{
+		"message": "Webhook delivery queued for redelivery",
+		"id":      id,
+	})
+}
@@ -6,9 +6,10 @@ import (
 	"net/http"
 	"strings"
 	"time"
-	"url-crawler/internal/config"
 
 	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/config"
 )
 
 // AuthConfig holds authentication configuration
@@ -102,99 +103,6 @@ func AuthMiddleware(config *AuthConfig) echo.MiddlewareFunc {
 	}
 }
 
-// RateLimitConfig holds rate limiting configuration
-type RateLimitConfig struct {
-	RequestsPerMinute int                         // Max requests per minute
-	WindowSize        time.Duration               // Time window for rate limiting
-	KeyGenerator      func(c echo.Context) string // Function to generate rate limit key
-}
-
-// DefaultRateLimitConfig creates a default rate limit configuration
-func DefaultRateLimitConfig() *RateLimitConfig {
-	return &RateLimitConfig{
-		RequestsPerMinute: 60, // 60 requests per minute
-		WindowSize:        time.Minute,
-		KeyGenerator: func(c echo.Context) string {
-			// Use API key if available, otherwise use IP
-			if keyName := c.Get("api_key_name"); keyName != nil {
-				return fmt.Sprintf("api:%s", keyName)
-			}
-			return fmt.Sprintf("ip:%s", c.RealIP())
-		},
-	}
-}
-
-// NewRateLimitConfigFromConfig creates a rate limit configuration from the main config
-func NewRateLimitConfig(cfg config.AuthConfig) *RateLimitConfig {
-	if !cfg.RateLimitEnabled {
-		// Return a config that allows unlimited requests
-		return &RateLimitConfig{
-			RequestsPerMinute: 999999, // Effectively unlimited
-			WindowSize:        time.Minute,
-			KeyGenerator: func(c echo.Context) string {
-				return "unlimited"
-			},
-		}
-	}
-
-	return &RateLimitConfig{
-		RequestsPerMinute: cfg.RequestsPerMinute,
-		WindowSize:        cfg.RateLimitWindow,
-		KeyGenerator: func(c echo.Context) string {
-			// Use API key if available, otherwise use IP
-			if keyName := c.Get("api_key_name"); keyName != nil {
-				return fmt.Sprintf("api:%s", keyName)
-			}
-			return fmt.Sprintf("ip:%s", c.RealIP())
-		},
-	}
-}
-
-// Simple in-memory rate limiter
-type rateLimiter struct {
-	requests map[string][]time.Time
-}
-
-var globalRateLimiter = &rateLimiter{
-	requests: make(map[string][]time.Time),
-}
-
-// RateLimitMiddleware creates a rate limiting middleware
-func RateLimitMiddleware(config *RateLimitConfig) echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			key := config.KeyGenerator(c)
-			now := time.Now()
-
-			// Clean up old requests
-			if requests, exists := globalRateLimiter.requests[key]; exists {
-				var validRequests []time.Time
-				cutoff := now.Add(-config.WindowSize)
-
-				for _, reqTime := range requests {
-					if reqTime.After(cutoff) {
-						validRequests = append(validRequests, reqTime)
-					}
-				}
-
-				globalRateLimiter.requests[key] = validRequests
-			}
-
-			// Check if limit exceeded
-			if len(globalRateLimiter.requests[key]) >= config.RequestsPerMinute {
-				return c.JSON(http.StatusTooManyRequests, map[string]string{
-					"error": "Rate limit exceeded",
-				})
-			}
-
-			// Add current request
-			globalRateLimiter.requests[key] = append(globalRateLimiter.requests[key], now)
-
-			return next(c)
-		}
-	}
-}
-
 // RequestIDMiddleware adds a unique request ID to each request
 func RequestIDMiddleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client-chosen key that identifies retries of a request
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader marks a response replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength matches the width of the stored key
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotentBodyBytes caps the request body buffered to hash it
+	maxIdempotentBodyBytes = 1 << 20
+
+	// defaultIdempotencyKeyTTL is used when the configuration leaves the TTL unset
+	defaultIdempotencyKeyTTL = 24 * time.Hour
+
+	// idempotencyPendingTimeout frees a key whose request never finished, e.g. after a crash
+	idempotencyPendingTimeout = 5 * time.Minute
+)
+
+// IdempotencyStore persists idempotency keys and the responses recorded for them
+type IdempotencyStore interface {
+	ClaimIdempotencyKey(ctx context.Context, owner, key, requestHash string, ttl, pendingTimeout time.Duration) (*models.IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, owner, key string, status int, contentType string, body []byte) error
+	ReleaseIdempotencyKey(ctx context.Context, owner, key string) error
+	PruneIdempotencyKeys(ctx context.Context) (int, error)
+}
+
+// IdempotencyConfig holds idempotency key configuration
+type IdempotencyConfig struct {
+	Store IdempotencyStore
+	TTL   time.Duration // how long a response is replayed
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewIdempotencyConfig creates an idempotency configuration from the main config
+func NewIdempotencyConfig(cfg config.AuthConfig, store IdempotencyStore) *IdempotencyConfig {
+	ttl := cfg.IdempotencyKeyTTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyKeyTTL
+	}
+	return &IdempotencyConfig{
+		Store:     store,
+		TTL:       ttl,
+		lastPrune: time.Now(),
+	}
+}
+
+// shouldPrune reports whether expired keys are due to be deleted
+func (ic *IdempotencyConfig) shouldPrune() bool {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+
+	if time.Since(ic.lastPrune) < ic.TTL/10 {
+		return false
+	}
+	ic.lastPrune = time.Now()
+	return true
+}
+
+// responseRecorder copies the response body while it is written to the client
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// IdempotencyMiddleware creates a middleware that makes requests carrying an
+// Idempotency-Key safe to retry. The first request with a key is handled and
+// its response recorded per API key; retries get the recorded response, a
+// different request reusing the key gets 422, and a retry arriving while the
+// first request is still being handled gets 409. Server errors and responses
+// that tell the client to try again later, such as 429 when the queue is full
+// or 409 when a crawl is still running, are not recorded, so such requests can
+// be retried with the same key.
+func IdempotencyMiddleware(config *IdempotencyConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength),
+				})
+			}
+
+			// The body is read here, so it is put back for the handler
+			body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, maxIdempotentBodyBytes))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{
+						"error": fmt.Sprintf("Request body must be at most %d bytes", maxIdempotentBodyBytes),
+					})
+				}
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Failed to read request body",
+				})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			owner, _ := c.Get("api_key_name").(string)
+			hash := requestHash(c.Request(), body)
+			ctx := c.Request().Context()
+
+			existing, err := config.Store.ClaimIdempotencyKey(ctx, owner, key, hash, config.TTL, idempotencyPendingTimeout)
+			if err != nil {
+				log.Printf("Failed to claim idempotency key: %v", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to check idempotency key",
+				})
+			}
+
+			if existing != nil {
+				if existing.RequestHash != hash {
+					return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+						"error": fmt.Sprintf("%s was already used for a different request", IdempotencyKeyHeader),
+					})
+				}
+				if existing.Pending() {
+					return c.JSON(http.StatusConflict, map[string]string{
+						"error": fmt.Sprintf("A request with this %s is still being processed", IdempotencyKeyHeader),
+					})
+				}
+				c.Response().Header().Set(IdempotentReplayedHeader, "true")
+				return c.Blob(*existing.ResponseStatus, existing.ContentType, existing.ResponseBody)
+			}
+
+			res := c.Response()
+			recorder := &responseRecorder{ResponseWriter: res.Writer}
+			res.Writer = recorder
+			defer func() { res.Writer = recorder.ResponseWriter }()
+
+			// Errors returned to Echo are written after the middleware chain, so they cannot be recorded
+			err = next(c)
+
+			// The outcome is stored even if the client has gone away in the meantime
+			ctx = context.WithoutCancel(ctx)
+			if err != nil || !res.Committed || retryableStatus(res.Status) {
+				if releaseErr := config.Store.ReleaseIdempotencyKey(ctx, owner, key); releaseErr != nil {
+					log.Printf("Failed to release idempotency key: %v", releaseErr)
+				}
+				return err
+			}
+
+			if err := config.Store.CompleteIdempotencyKey(ctx, owner, key, res.Status, res.Header().Get(echo.HeaderContentType), recorder.body.Bytes()); err != nil {
+				log.Printf("Failed to record idempotent response: %v", err)
+			}
+
+			if config.shouldPrune() {
+				if _, err := config.Store.PruneIdempotencyKeys(ctx); err != nil {
+					log.Printf("Failed to prune idempotency keys: %v", err)
+				}
+			}
+
+			return nil
+		}
+	}
+}
+
+// retryableStatus reports whether a response status may change when the same
+// request is retried later, so it must not be replayed
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	default:
+		return status >= http.StatusInternalServerError
+	}
+}
+
+// requestHash fingerprints a request by its method, URI and body, so a reused
+// key can be told apart from a retry
+func requestHash(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", req.Method, req.URL.RequestURI())
+	hash.Write(body)
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+// memoryIdempotencyStore is an in-memory IdempotencyStore for tests
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*models.IdempotencyRecord
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+}
+
+func (m *memoryIdempotencyStore) ClaimIdempotencyKey(ctx context.Context, owner, key, requestHash string, ttl, pendingTimeout time.Duration) (*models.IdempotencyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if record, ok := m.records[owner+"/"+key]; ok && time.Now().Before(record.ExpiresAt) {
+		copied := *record
+		return &copied, nil
+	}
+	m.records[owner+"/"+key] = &models.IdempotencyRecord{
+		Owner:       owner,
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+	return nil, nil
+}
+
+func (m *memoryIdempotencyStore) CompleteIdempotencyKey(ctx context.Context, owner, key string, status int, contentType string, body []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record := m.records[owner+"/"+key]
+	record.ResponseStatus = &status
+	record.ContentType = contentType
+	record.ResponseBody = append([]byte(nil), body...)
+	return nil
+}
+
+func (m *memoryIdempotencyStore) ReleaseIdempotencyKey(ctx context.Context, owner, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if record, ok := m.records[owner+"/"+key]; ok && record.Pending() {
+		delete(m.records, owner+"/"+key)
+	}
+	return nil
+}
+
+func (m *memoryIdempotencyStore) PruneIdempotencyKeys(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// newIdempotentServer serves POST /crawl as the given API key, counting handler calls
+func newIdempotentServer(store IdempotencyStore, keyName string, status *int) (*echo.Echo, *int) {
+	calls := 0
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("api_key_name", keyName)
+			return next(c)
+		}
+	})
+	e.POST("/crawl", func(c echo.Context) error {
+		calls++
+		return c.JSON(*status, map[string]int{"call": calls})
+	}, IdempotencyMiddleware(NewIdempotencyConfig(config.AuthConfig{}, store)))
+	return e, &calls
+}
+
+// send posts body to the server with an Idempotency-Key
+func send(e *echo.Echo, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/crawl", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyMiddlewareReplaysResponse(t *testing.T) {
+	status := http.StatusCreated
+	e, calls := newIdempotentServer(newMemoryIdempotencyStore(), "team-a", &status)
+
+	first := send(e, "abc", `{"url":"https://example.com"}`)
+	retry := send(e, "abc", `{"url":"https://example.com"}`)
+
+	if *calls != 1 {
+		t.Errorf("handler called %d times, expected once", *calls)
+	}
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry got %d %q, expected the original %d %q", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry is missing the %s header", IdempotentReplayedHeader)
+	}
+
+	// Requests without a key are always handled
+	send(e, "", `{"url":"https://example.com"}`)
+	if *calls != 2 {
+		t.Errorf("handler called %d times, expected a request without a key to be handled", *calls)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsReusedKey(t *testing.T) {
+	status := http.StatusCreated
+	e, calls := newIdempotentServer(newMemoryIdempotencyStore(), "team-a", &status)
+
+	send(e, "abc", `{"url":"https://example.com"}`)
+	rec := send(e, "abc", `{"url":"https://example.org"}`)
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key got %d, expected 422", rec.Code)
+	}
+	if *calls != 1 {
+		t.Errorf("handler called %d times, expected once", *calls)
+	}
+}
+
+func TestIdempotencyMiddlewareScopesKeysPerAPIKey(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	status := http.StatusCreated
+	teamA, callsA := newIdempotentServer(store, "team-a", &status)
+	teamB, callsB := newIdempotentServer(store, "team-b", &status)
+
+	send(teamA, "abc", `{"url":"https://example.com"}`)
+	rec := send(teamB, "abc", `{"url":"https://example.org"}`)
+
+	if rec.Code != http.StatusCreated || *callsA != 1 || *callsB != 1 {
+		t.Errorf("team-b got %d, expected its own key to be handled", rec.Code)
+	}
+}
+
+func TestIdempotencyMiddlewareDoesNotRecordServerErrors(t *testing.T) {
+	status := http.StatusInternalServerError
+	e, calls := newIdempotentServer(newMemoryIdempotencyStore(), "team-a", &status)
+
+	send(e, "abc", `{"url":"https://example.com"}`)
+	status = http.StatusCreated
+	rec := send(e, "abc", `{"url":"https://example.com"}`)
+
+	if rec.Code != http.StatusCreated || *calls != 2 {
+		t.Errorf("retry after a server error got %d after %d calls, expected it to be handled again", rec.Code, *calls)
+	}
+}
+
+func TestIdempotencyMiddlewareRejectsOversizedBody(t *testing.T) {
+	status := http.StatusCreated
+	e, calls := newIdempotentServer(newMemoryIdempotencyStore(), "team-a", &status)
+
+	rec := send(e, "abc", `{"url":"https://example.com","padding":"`+strings.Repeat("a", maxIdempotentBodyBytes)+`"}`)
+
+	if rec.Code != http.StatusRequestEntityTooLarge || *calls != 0 {
+		t.Errorf("oversized body got %d after %d calls, expected 413 without calling the handler", rec.Code, *calls)
+	}
+}
+
+func TestIdempotencyMiddlewareDoesNotRecordRetryableErrors(t *testing.T) {
+	for _, retryable := range []int{http.StatusConflict, http.StatusTooManyRequests} {
+		status := retryable
+		e, calls := newIdempotentServer(newMemoryIdempotencyStore(), "team-a", &status)
+
+		send(e, "abc", `{"ids":["1"]}`)
+		status = http.StatusOK
+		rec := send(e, "abc", `{"ids":["1"]}`)
+
+		if rec.Code != http.StatusOK || *calls != 2 {
+			t.Errorf("retry after %d got %d after %d calls, expected it to be handled again", retryable, rec.Code, *calls)
+		}
+	}
+
+	// Other client errors are final and replayed
+	status := http.StatusBadRequest
+	e, calls := newIdempotentServer(newMemoryIdempotencyStore(), "team-a", &status)
+	send(e, "abc", `{}`)
+	status = http.StatusOK
+	if rec := send(e, "abc", `{}`); rec.Code != http.StatusBadRequest || *calls != 1 {
+		t.Errorf("retry after 400 got %d after %d calls, expected the 400 to be replayed", rec.Code, *calls)
+	}
+}
@@ -0,0 +1,243 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"url-crawler/internal/config"
+)
+
+// defaultSweepInterval is how often idle buckets are looked for
+const defaultSweepInterval = time.Minute
+
+// RouteClass groups routes that share a rate limit
+type RouteClass string
+
+const (
+	RouteClassRead   RouteClass = "read"   // requests that only read state
+	RouteClassCreate RouteClass = "create" // requests that create or change state, such as enqueuing crawls
+)
+
+// RateLimit allows Requests requests per Window, in bursts of up to Requests
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitResult describes the outcome of taking a request from a rate limit
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the limit is fully replenished
+	RetryAfter time.Duration // until the next request is allowed, when denied
+}
+
+// RateLimiter takes requests from per-key rate limits. Implementations must be
+// safe for concurrent use.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// tokenBucket holds the tokens left for one key
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	window  time.Duration // time a bucket takes to refill from empty
+}
+
+// TokenBucketLimiter is a concurrency-safe in-memory rate limiter, which only
+// sees the requests served by its own instance. Every key has
+// a bucket that refills continuously at Requests per Window; buckets that have
+// been idle long enough to refill completely are evicted, since they behave
+// exactly like new ones.
+type TokenBucketLimiter struct {
+	mu            sync.Mutex
+	buckets       map[string]*tokenBucket
+	sweepInterval time.Duration
+	lastSweep     time.Time
+	now           func() time.Time
+}
+
+// NewTokenBucketLimiter creates an empty token bucket limiter
+func NewTokenBucketLimiter() *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		buckets:       make(map[string]*tokenBucket),
+		sweepInterval: defaultSweepInterval,
+		lastSweep:     time.Now(),
+		now:           time.Now,
+	}
+}
+
+// Allow takes a request from the bucket of key, if one is left. It never fails.
+func (l *TokenBucketLimiter) Allow(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Window.Seconds() // tokens per second
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.window = limit.Window
+	if elapsed := now.Sub(bucket.updated).Seconds(); elapsed > 0 {
+		bucket.tokens = math.Min(capacity, bucket.tokens+elapsed*rate)
+	}
+	bucket.updated = now
+
+	result := RateLimitResult{Limit: limit.Requests}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - bucket.tokens) / rate)
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = secondsToDuration((capacity - bucket.tokens) / rate)
+
+	return result, nil
+}
+
+// sweep evicts buckets that have refilled completely, at most once per sweep interval
+func (l *TokenBucketLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) >= bucket.window {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// size returns the number of tracked keys
+func (l *TokenBucketLimiter) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets)
+}
+
+// secondsToDuration converts fractional seconds to a duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// RateLimitConfig holds rate limiting configuration
+type RateLimitConfig struct {
+	Limits       map[RouteClass]RateLimit    // classes without a limit are not rate limited
+	KeyGenerator func(c echo.Context) string // Function to generate rate limit key
+	Limiter      RateLimiter
+}
+
+// rateLimitKey limits requests per API key, or per client IP when unauthenticated
+func rateLimitKey(c echo.Context) string {
+	if keyName := c.Get("api_key_name"); keyName != nil {
+		return fmt.Sprintf("api:%s", keyName)
+	}
+	return fmt.Sprintf("ip:%s", c.RealIP())
+}
+
+// DefaultRateLimitConfig creates a default rate limit configuration
+func DefaultRateLimitConfig() *RateLimitConfig {
+	return &RateLimitConfig{
+		Limits: map[RouteClass]RateLimit{
+			RouteClassRead:   {Requests: 60, Window: time.Minute},
+			RouteClassCreate: {Requests: 20, Window: time.Minute},
+		},
+		KeyGenerator: rateLimitKey,
+		Limiter:      NewTokenBucketLimiter(),
+	}
+}
+
+// NewRateLimitConfig creates a rate limit configuration from the main config.
+// The mysql backend keeps request counts in store, shared by all API instances.
+func NewRateLimitConfig(cfg config.AuthConfig, store RateLimitStore) *RateLimitConfig {
+	rateLimitConfig := &RateLimitConfig{
+		Limits:       map[RouteClass]RateLimit{},
+		KeyGenerator: rateLimitKey,
+		Limiter:      NewTokenBucketLimiter(),
+	}
+	if cfg.RateLimitBackend == config.RateLimitBackendMySQL {
+		rateLimitConfig.Limiter = NewSlidingWindowLimiter(store)
+	}
+	if !cfg.RateLimitEnabled || cfg.RateLimitWindow <= 0 {
+		return rateLimitConfig
+	}
+
+	if cfg.RequestsPerMinute > 0 {
+		rateLimitConfig.Limits[RouteClassRead] = RateLimit{Requests: cfg.RequestsPerMinute, Window: cfg.RateLimitWindow}
+	}
+	if cfg.CreateRequestsPerMinute > 0 {
+		rateLimitConfig.Limits[RouteClassCreate] = RateLimit{Requests: cfg.CreateRequestsPerMinute, Window: cfg.RateLimitWindow}
+	}
+	return rateLimitConfig
+}
+
+// routeClass classifies a request by whether it can change state
+func routeClass(c echo.Context) RouteClass {
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RouteClassRead
+	default:
+		return RouteClassCreate
+	}
+}
+
+// RateLimitMiddleware creates a rate limiting middleware. Every response carries
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (seconds until
+// the limit is fully replenished); rejected requests also get Retry-After.
+// Requests are let through when the limiter fails, e.g. while its database is
+// unreachable, rather than failing the whole API.
+func RateLimitMiddleware(config *RateLimitConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			class := routeClass(c)
+			limit, limited := config.Limits[class]
+			if !limited {
+				return next(c)
+			}
+
+			result, err := config.Limiter.Allow(c.Request().Context(), string(class)+":"+config.KeyGenerator(c), limit)
+			if err != nil {
+				log.Printf("Failed to check rate limit: %v", err)
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				header.Set("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+				return c.JSON(http.StatusTooManyRequests, map[string]string{
+					"error": "Rate limit exceeded",
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// fakeClock is a manually advanced clock for limiter tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+}
+
+func newTestLimiter() (*TokenBucketLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	limiter := NewTokenBucketLimiter()
+	limiter.now = clock.Now
+	limiter.lastSweep = clock.Now()
+	return limiter, clock
+}
+
+// allow takes a request from limiter, failing the test if the limiter fails
+func allow(t *testing.T, limiter RateLimiter, key string, limit RateLimit) RateLimitResult {
+	result, err := limiter.Allow(context.Background(), key, limit)
+	if err != nil {
+		t.Errorf("Allow(%q) failed: %v", key, err)
+	}
+	return result
+}
+
+func TestTokenBucketLimiterBurstAndRefill(t *testing.T) {
+	limiter, clock := newTestLimiter()
+	limit := RateLimit{Requests: 3, Window: 3 * time.Second}
+
+	for i := 0; i < 3; i++ {
+		if result := allow(t, limiter, "a", limit); !result.Allowed || result.Remaining != 2-i {
+			t.Fatalf("request %d = %+v, expected allowed with %d remaining", i+1, result, 2-i)
+		}
+	}
+
+	result := allow(t, limiter, "a", limit)
+	if result.Allowed {
+		t.Fatal("request over the burst was allowed")
+	}
+	if result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Errorf("denied request = %+v, expected retry after 1s and reset after 3s", result)
+	}
+
+	// Another key has its own bucket
+	if !allow(t, limiter, "b", limit).Allowed {
+		t.Error("request for another key was denied")
+	}
+
+	clock.Advance(time.Second)
+	if !allow(t, limiter, "a", limit).Allowed {
+		t.Error("request after one token refilled was denied")
+	}
+	if allow(t, limiter, "a", limit).Allowed {
+		t.Error("second request after one token refilled was allowed")
+	}
+}
+
+func TestTokenBucketLimiterEvictsIdleKeys(t *testing.T) {
+	limiter, clock := newTestLimiter()
+	limit := RateLimit{Requests: 5, Window: time.Minute}
+
+	for i := 0; i < 100; i++ {
+		allow(t, limiter, fmt.Sprintf("key-%d", i), limit)
+	}
+	clock.Advance(30 * time.Second)
+	allow(t, limiter, "recent", limit)
+
+	clock.Advance(40 * time.Second)
+	allow(t, limiter, "recent", limit)
+
+	// Only the key used within the last window is still tracked
+	if size := limiter.size(); size != 1 {
+		t.Errorf("tracked %d keys after the sweep, expected 1", size)
+	}
+}
+
+func TestTokenBucketLimiterConcurrentRequests(t *testing.T) {
+	limiter, _ := newTestLimiter()
+	limit := RateLimit{Requests: 50, Window: time.Minute}
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if allow(t, limiter, "shared", limit).Allowed {
+					allowed.Add(1)
+				}
+				allow(t, limiter, fmt.Sprintf("own-%d-%d", i, j), limit)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if allowed.Load() != 50 {
+		t.Errorf("allowed %d concurrent requests, expected exactly 50", allowed.Load())
+	}
+}
+
+// newRateLimitedServer serves GET and POST /crawl behind the rate limit middleware
+func newRateLimitedServer(config *RateLimitConfig) *echo.Echo {
+	e := echo.New()
+	e.Use(RateLimitMiddleware(config))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/crawl", ok)
+	e.POST("/crawl", ok)
+	return e
+}
+
+func serve(e *echo.Echo, method string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/crawl", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitMiddlewareHeadersAndClasses(t *testing.T) {
+	limiter, _ := newTestLimiter()
+	e := newRateLimitedServer(&RateLimitConfig{
+		Limits: map[RouteClass]RateLimit{
+			RouteClassRead:   {Requests: 5, Window: time.Minute},
+			RouteClassCreate: {Requests: 1, Window: time.Minute},
+		},
+		KeyGenerator: func(c echo.Context) string { return "client" },
+		Limiter:      limiter,
+	})
+
+	rec := serve(e, http.MethodPost)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first create = %d, expected 200", rec.Code)
+	}
+	if rec.Header().Get("X-RateLimit-Limit") != "1" || rec.Header().Get("X-RateLimit-Remaining") != "0" || rec.Header().Get("X-RateLimit-Reset") != "60" {
+		t.Errorf("create headers = %v, expected limit 1, 0 remaining, reset in 60s", rec.Header())
+	}
+
+	rec = serve(e, http.MethodPost)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("second create = %d with Retry-After %q, expected 429 with Retry-After 60", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Reads have their own limit
+	rec = serve(e, http.MethodGet)
+	if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "5" || rec.Header().Get("X-RateLimit-Remaining") != "4" {
+		t.Errorf("read = %d with headers %v, expected 200 with limit 5 and 4 remaining", rec.Code, rec.Header())
+	}
+}
+
+func TestRateLimitMiddlewareConcurrentRequests(t *testing.T) {
+	e := newRateLimitedServer(&RateLimitConfig{
+		Limits:       map[RouteClass]RateLimit{RouteClassRead: {Requests: 25, Window: time.Hour}},
+		KeyGenerator: rateLimitKey,
+		Limiter:      NewTokenBucketLimiter(),
+	})
+
+	var allowed, limited atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				switch serve(e, http.MethodGet).Code {
+				case http.StatusOK:
+					allowed.Add(1)
+				case http.StatusTooManyRequests:
+					limited.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() != 25 || limited.Load() != 25 {
+		t.Errorf("%d allowed and %d limited, expected 25 of each", allowed.Load(), limited.Load())
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimitStore keeps request counts per rate limit key and fixed window, so
+// that every instance sharing the store enforces the same limits. Windows are
+// identified by their start in Unix milliseconds.
+type RateLimitStore interface {
+	IncrementRateLimitHits(ctx context.Context, key string, window, previousWindow int64, ttl time.Duration) (int, int, error)
+	DecrementRateLimitHits(ctx context.Context, key string, window int64) error
+	PruneRateLimitWindows(ctx context.Context) (int, error)
+}
+
+// SlidingWindowLimiter is a rate limiter backed by a RateLimitStore. It counts
+// requests in fixed windows and estimates the requests of the sliding window
+// ending now by weighing the previous window by how much of it still overlaps,
+// which smooths out the bursts fixed windows allow at their edges. Instances
+// sharing a store are assumed to have synchronized clocks.
+type SlidingWindowLimiter struct {
+	store         RateLimitStore
+	pruneInterval time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewSlidingWindowLimiter creates a sliding window limiter on store
+func NewSlidingWindowLimiter(store RateLimitStore) *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{
+		store:         store,
+		pruneInterval: defaultSweepInterval,
+		now:           time.Now,
+		lastPrune:     time.Now(),
+	}
+}
+
+// Allow counts a request against key if the limit allows it. Denied requests
+// are taken back, so they do not count against later ones.
+func (l *SlidingWindowLimiter) Allow(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	now := l.now()
+	window := limit.Window
+	start := now.Truncate(window)
+	elapsed := now.Sub(start)
+
+	current, previous, err := l.store.IncrementRateLimitHits(ctx, key, start.UnixMilli(), start.Add(-window).UnixMilli(), 2*window)
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	// The hit is recorded even if the client has gone away in the meantime
+	ctx = context.WithoutCancel(ctx)
+	l.prune(ctx, now)
+
+	requests := float64(limit.Requests)
+	overlap := 1 - elapsed.Seconds()/window.Seconds()
+	estimate := float64(previous)*overlap + float64(current)
+
+	result := RateLimitResult{Limit: limit.Requests}
+	if estimate <= requests {
+		result.Allowed = true
+		result.Remaining = int(requests - estimate)
+	} else {
+		if err := l.store.DecrementRateLimitHits(ctx, key, start.UnixMilli()); err != nil {
+			log.Printf("Failed to take back rate limit hit: %v", err)
+		}
+		current--
+		result.RetryAfter = retryAfter(current, previous, limit.Requests, window, elapsed)
+	}
+
+	// The estimate drops to zero once every counted request has left the sliding window
+	switch {
+	case current > 0:
+		result.Reset = 2*window - elapsed
+	case previous > 0:
+		result.Reset = window - elapsed
+	}
+
+	return result, nil
+}
+
+// retryAfter returns how long after elapsed into the current window one more
+// request fits in the limit, given the hits of the current and previous windows
+func retryAfter(current, previous, requests int, window, elapsed time.Duration) time.Duration {
+	if current+1 <= requests && previous > 0 {
+		// Wait for enough of the previous window to slide out
+		overlap := float64(requests-current-1) / float64(previous)
+		return max(ceilMilliseconds((1-overlap)*float64(window))-elapsed, 0)
+	}
+
+	// Wait for the next window, and then for enough of this one to slide out
+	overlap := float64(requests-1) / float64(current)
+	return window - elapsed + ceilMilliseconds(math.Max(1-overlap, 0)*float64(window))
+}
+
+// ceilMilliseconds rounds a fractional duration up to whole milliseconds, so
+// that clients are never told to retry too early
+func ceilMilliseconds(d float64) time.Duration {
+	return time.Duration(math.Ceil(d/float64(time.Millisecond))) * time.Millisecond
+}
+
+// prune deletes expired windows from the store, at most once per prune interval
+func (l *SlidingWindowLimiter) prune(ctx context.Context, now time.Time) {
+	l.mu.Lock()
+	if now.Sub(l.lastPrune) < l.pruneInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.lastPrune = now
+	l.mu.Unlock()
+
+	if _, err := l.store.PruneRateLimitWindows(ctx); err != nil {
+		log.Printf("Failed to prune rate limit windows: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+)
+
+// memoryRateLimitStore is an in-memory RateLimitStore for tests
+type memoryRateLimitStore struct {
+	mu   sync.Mutex
+	hits map[string]map[int64]int
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{hits: make(map[string]map[int64]int)}
+}
+
+func (m *memoryRateLimitStore) IncrementRateLimitHits(ctx context.Context, key string, window, previousWindow int64, ttl time.Duration) (int, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.hits[key] == nil {
+		m.hits[key] = make(map[int64]int)
+	}
+	m.hits[key][window]++
+	return m.hits[key][window], m.hits[key][previousWindow], nil
+}
+
+func (m *memoryRateLimitStore) DecrementRateLimitHits(ctx context.Context, key string, window int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hits[key][window]--
+	return nil
+}
+
+func (m *memoryRateLimitStore) PruneRateLimitWindows(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// failingRateLimitStore is a RateLimitStore whose database is unreachable
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) IncrementRateLimitHits(ctx context.Context, key string, window, previousWindow int64, ttl time.Duration) (int, int, error) {
+	return 0, 0, errors.New("connection refused")
+}
+
+func (failingRateLimitStore) DecrementRateLimitHits(ctx context.Context, key string, window int64) error {
+	return errors.New("connection refused")
+}
+
+func (failingRateLimitStore) PruneRateLimitWindows(ctx context.Context) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+// newTestSlidingWindowLimiter creates a limiter on store whose clock starts at the beginning of a minute
+func newTestSlidingWindowLimiter(store RateLimitStore) (*SlidingWindowLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1_699_999_980, 0)}
+	limiter := NewSlidingWindowLimiter(store)
+	limiter.now = clock.Now
+	limiter.lastPrune = clock.Now()
+	return limiter, clock
+}
+
+func TestSlidingWindowLimiterWeighsPreviousWindow(t *testing.T) {
+	limiter, clock := newTestSlidingWindowLimiter(newMemoryRateLimitStore())
+	limit := RateLimit{Requests: 10, Window: time.Minute}
+
+	for i := 0; i < 10; i++ {
+		if result := allow(t, limiter, "a", limit); !result.Allowed || result.Remaining != 9-i {
+			t.Fatalf("request %d = %+v, expected allowed with %d remaining", i+1, result, 9-i)
+		}
+	}
+
+	// The next request fits once 6s of the next window have pushed a tenth of this one out
+	result := allow(t, limiter, "a", limit)
+	if result.Allowed {
+		t.Fatal("request over the limit was allowed")
+	}
+	if result.RetryAfter != 66*time.Second || result.Reset != 2*time.Minute {
+		t.Errorf("denied request = %+v, expected retry after 66s and reset after 2m", result)
+	}
+
+	// Halfway through the next window, half of the previous window's requests still count
+	clock.Advance(90 * time.Second)
+	for i := 0; i < 5; i++ {
+		if !allow(t, limiter, "a", limit).Allowed {
+			t.Fatalf("request %d halfway through the next window was denied", i+1)
+		}
+	}
+	result = allow(t, limiter, "a", limit)
+	if result.Allowed {
+		t.Fatal("request over the weighted limit was allowed")
+	}
+	if result.RetryAfter != 6*time.Second {
+		t.Errorf("denied request = %+v, expected retry after 6s", result)
+	}
+
+	// Another key has its own windows
+	if !allow(t, limiter, "b", limit).Allowed {
+		t.Error("request for another key was denied")
+	}
+}
+
+func TestSlidingWindowLimiterSharesLimitsAcrossInstances(t *testing.T) {
+	store := newMemoryRateLimitStore()
+	first, _ := newTestSlidingWindowLimiter(store)
+	second, _ := newTestSlidingWindowLimiter(store)
+	limit := RateLimit{Requests: 50, Window: time.Minute}
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		limiter := first
+		if i%2 == 1 {
+			limiter = second
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if allow(t, limiter, "shared", limit).Allowed {
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() != 50 {
+		t.Errorf("allowed %d requests across both instances, expected exactly 50", allowed.Load())
+	}
+}
+
+func TestRateLimitMiddlewareAllowsRequestsWhenTheStoreFails(t *testing.T) {
+	e := newRateLimitedServer(&RateLimitConfig{
+		Limits:       map[RouteClass]RateLimit{RouteClassCreate: {Requests: 1, Window: time.Minute}},
+		KeyGenerator: rateLimitKey,
+		Limiter:      NewSlidingWindowLimiter(failingRateLimitStore{}),
+	})
+
+	for i := 0; i < 3; i++ {
+		if rec := serve(e, http.MethodPost); rec.Code != http.StatusOK {
+			t.Errorf("request %d = %d, expected 200 while the store is unreachable", i+1, rec.Code)
+		}
+	}
+}
+
+func TestNewRateLimitConfigSelectsBackend(t *testing.T) {
+	cfg := config.AuthConfig{
+		RateLimitEnabled:  true,
+		RequestsPerMinute: 5,
+		RateLimitWindow:   time.Minute,
+		RateLimitBackend:  config.RateLimitBackendMySQL,
+	}
+	if _, ok := NewRateLimitConfig(cfg, newMemoryRateLimitStore()).Limiter.(*SlidingWindowLimiter); !ok {
+		t.Error("mysql backend did not use the sliding window limiter")
+	}
+
+	cfg.RateLimitBackend = config.RateLimitBackendMemory
+	if _, ok := NewRateLimitConfig(cfg, newMemoryRateLimitStore()).Limiter.(*TokenBucketLimiter); !ok {
+		t.Error("memory backend did not use the token bucket limiter")
+	}
+}
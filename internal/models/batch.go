@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// MaxBatchSize caps how many URLs a single batch submission may contain
+const MaxBatchSize = 1000
+
+// BatchSource names how the URLs of a batch were submitted
+type BatchSource string
+
+const (
+	BatchSourceJSON    BatchSource = "json"
+	BatchSourceLines   BatchSource = "lines"
+	BatchSourceCSV     BatchSource = "csv"
+	BatchSourceSitemap BatchSource = "sitemap"
+)
+
+// Batch represents a group of crawls submitted together
+type Batch struct {
+	ID        string      `json:"id" db:"id"`
+	Owner     string      `json:"-" db:"owner"` // name of the API key that submitted the batch
+	Source    BatchSource `json:"source" db:"source"`
+	CreatedAt time.Time   `json:"createdAt" db:"created_at"`
+}
+
+// BatchProgress aggregates the statuses of the crawls in a batch
+type BatchProgress struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Error     int `json:"error"`
+	Cancelled int `json:"cancelled"`
+	Pending   int `json:"pending"` // queued or running
+}
+
+// BatchStatus represents a batch with its aggregate progress and member crawls
+type BatchStatus struct {
+	Batch
+	BatchProgress
+	Done      bool     `json:"done"` // no crawl of the batch is pending
+	MemberIDs []string `json:"memberIds"`
+}
+
+// BatchCrawl represents a crawl enqueued as part of a batch
+type BatchCrawl struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// BatchSubmission represents the outcome of submitting a batch of URLs
+type BatchSubmission struct {
+	BatchID     string       `json:"batchId"`
+	Submitted   int          `json:"submitted"`
+	Accepted    int          `json:"accepted"`
+	Duplicates  int          `json:"duplicates"`
+	Rejected    int          `json:"rejected"`
+	Unprocessed []string     `json:"unprocessed,omitempty"` // URLs left out when the request ran out of time
+	Crawls      []BatchCrawl `json:"crawls"`
+	Errors      []string     `json:"errors,omitempty"`
+}
@@ -15,8 +15,44 @@ const (
 	CrawlStatusRunning   CrawlStatus = "running"
 	CrawlStatusCompleted CrawlStatus = "completed"
 	CrawlStatusError     CrawlStatus = "error"
+	CrawlStatusCancelled CrawlStatus = "cancelled"
 )
 
+// IsActive reports whether a crawl with this status is waiting in or being processed by the queue
+func (s CrawlStatus) IsActive() bool {
+	return s == CrawlStatusQueued || s == CrawlStatusRunning
+}
+
+// CrawlPriority selects the queue lane a crawl waits in
+type CrawlPriority string
+
+const (
+	CrawlPriorityHigh   CrawlPriority = "high"
+	CrawlPriorityNormal CrawlPriority = "normal"
+	CrawlPriorityLow    CrawlPriority = "low"
+)
+
+// CrawlPriorities lists the priority lanes from most to least urgent
+var CrawlPriorities = []CrawlPriority{CrawlPriorityHigh, CrawlPriorityNormal, CrawlPriorityLow}
+
+// IsValid checks if the priority is one of the known lanes
+func (p CrawlPriority) IsValid() bool {
+	switch p {
+	case CrawlPriorityHigh, CrawlPriorityNormal, CrawlPriorityLow:
+		return true
+	default:
+		return false
+	}
+}
+
+// OrDefault returns the priority, or normal when none was given
+func (p CrawlPriority) OrDefault() CrawlPriority {
+	if p == "" {
+		return CrawlPriorityNormal
+	}
+	return p
+}
+
 // HeadingCounts represents the count of each heading level
 type HeadingCounts struct {
 	H1 int `json:"h1" db:"h1"`
@@ -105,6 +141,32 @@ func (el *ExternalLinks) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, el)
 }
 
+// CheckedLinks is a slice of strings representing the link URLs a link check probed
+type CheckedLinks []string
+
+// Value implements the driver.Valuer interface for database storage
+func (cl CheckedLinks) Value() (driver.Value, error) {
+	if cl == nil {
+		return "[]", nil
+	}
+	return json.Marshal(cl)
+}
+
+// Scan implements the sql.Scanner interface for database retrieval
+func (cl *CheckedLinks) Scan(value interface{}) error {
+	if value == nil {
+		*cl = CheckedLinks{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+
+	return json.Unmarshal(bytes, cl)
+}
+
 // CrawlResult represents the complete analysis result of a crawled URL
 type CrawlResult struct {
 	ID                     string        `json:"id" db:"id"`
@@ -113,28 +175,45 @@ type CrawlResult struct {
 	HTMLVersion            string        `json:"htmlVersion" db:"html_version"`
 	InternalLinksCount     int           `json:"internalLinksCount" db:"internal_links_count"`
 	ExternalLinksCount     int           `json:"externalLinksCount" db:"external_links_count"`
+	OtherLinksCount        int           `json:"otherLinksCount" db:"other_links_count"` // mailto:, tel:, javascript: etc.
 	InaccessibleLinksCount int           `json:"inaccessibleLinksCount" db:"inaccessible_links_count"`
 	HasLoginForm           bool          `json:"hasLoginForm" db:"has_login_form"`
 	HeadingCounts          HeadingCounts `json:"headingCounts" db:"heading_counts"`
 	BrokenLinks            BrokenLinks   `json:"brokenLinks" db:"broken_links"`
 	ExternalLinks          ExternalLinks `json:"externalLinks" db:"external_links"`
+	CheckedLinks           CheckedLinks  `json:"checkedLinks" db:"checked_links"` // links the link check probed, at most the configured maximum
 	Status                 CrawlStatus   `json:"status" db:"status"`
 	ErrorMessage           *string       `json:"errorMessage,omitempty" db:"error_message"`
+	Attempts               int           `json:"attempts" db:"attempts"`                   // crawl attempts made, including retries
+	LastError              *string       `json:"lastError,omitempty" db:"last_error"`      // most recent attempt failure
+	ParentID               *string       `json:"parentId,omitempty" db:"parent_id"`        // page that linked to this one in a site crawl
+	SiteCrawlID            *string       `json:"siteCrawlId,omitempty" db:"site_crawl_id"` // site crawl this page belongs to
+	Depth                  int           `json:"depth" db:"depth"`                         // link distance from the site crawl seed
+	Owner                  *string       `json:"-" db:"owner"`                             // name of the API key that requested the crawl
+	WebhookID              *string       `json:"webhookId,omitempty" db:"webhook_id"`      // webhook registered with the crawl request
+	BatchID                *string       `json:"batchId,omitempty" db:"batch_id"`          // batch the URL was submitted in
 	CreatedAt              time.Time     `json:"createdAt" db:"created_at"`
 	UpdatedAt              time.Time     `json:"updatedAt" db:"updated_at"`
+
+	// DiscoveredLinks holds the absolute internal links found on the page. It is used
+	// to expand site crawls and is not persisted.
+	DiscoveredLinks []string `json:"-" db:"-"`
 }
 
 // CrawlRequest represents a request to crawl a URL
 type CrawlRequest struct {
-	URL string `json:"url" validate:"required,url"`
+	URL      string          `json:"url" validate:"required,url"`
+	Priority CrawlPriority   `json:"priority,omitempty" validate:"omitempty,oneof=high normal low"` // defaults to normal
+	Webhook  *WebhookRequest `json:"webhook,omitempty"`                                             // notified when this crawl finishes
 }
 
 // CrawlRequestResponse represents the response when a crawl is requested
 type CrawlRequestResponse struct {
-	ID      string      `json:"id"`
-	URL     string      `json:"url"`
-	Status  CrawlStatus `json:"status"`
-	Message string      `json:"message"`
+	ID      string               `json:"id"`
+	URL     string               `json:"url"`
+	Status  CrawlStatus          `json:"status"`
+	Message string               `json:"message"`
+	Webhook *WebhookRegistration `json:"webhook,omitempty"`
 }
 
 // PaginatedCrawlResults represents paginated crawl results
@@ -163,12 +242,13 @@ type CrawlStats struct {
 	Running   int `json:"running"`
 	Completed int `json:"completed"`
 	Error     int `json:"error"`
+	Cancelled int `json:"cancelled"`
 }
 
 // ValidateStatus checks if the provided status is valid
 func (status CrawlStatus) IsValid() bool {
 	switch status {
-	case CrawlStatusQueued, CrawlStatusRunning, CrawlStatusCompleted, CrawlStatusError:
+	case CrawlStatusQueued, CrawlStatusRunning, CrawlStatusCompleted, CrawlStatusError, CrawlStatusCancelled:
 		return true
 	default:
 		return false
@@ -214,3 +294,49 @@ func (f *CrawlFilters) Validate() error {
 
 	return nil
 }
+
+// CrawlRun is a snapshot of one finished run of a crawl. Re-running a crawl
+// overwrites its result but adds a new run, so the runs form its history.
+type CrawlRun struct {
+	CrawlID                string        `json:"crawlId" db:"crawl_id"`
+	RunNumber              int           `json:"runNumber" db:"run_number"` // 1 for the first run, counting up
+	URL                    string        `json:"url" db:"url"`
+	Title                  string        `json:"title" db:"title"`
+	HTMLVersion            string        `json:"htmlVersion" db:"html_version"`
+	InternalLinksCount     int           `json:"internalLinksCount" db:"internal_links_count"`
+	ExternalLinksCount     int           `json:"externalLinksCount" db:"external_links_count"`
+	OtherLinksCount        int           `json:"otherLinksCount" db:"other_links_count"`
+	InaccessibleLinksCount int           `json:"inaccessibleLinksCount" db:"inaccessible_links_count"`
+	HasLoginForm           bool          `json:"hasLoginForm" db:"has_login_form"`
+	HeadingCounts          HeadingCounts `json:"headingCounts" db:"heading_counts"`
+	BrokenLinks            BrokenLinks   `json:"brokenLinks" db:"broken_links"`
+	ExternalLinks          ExternalLinks `json:"externalLinks" db:"external_links"`
+	CheckedLinks           CheckedLinks  `json:"checkedLinks" db:"checked_links"`
+	Status                 CrawlStatus   `json:"status" db:"status"`
+	ErrorMessage           *string       `json:"errorMessage,omitempty" db:"error_message"`
+	Attempts               int           `json:"attempts" db:"attempts"`
+	FinishedAt             time.Time     `json:"finishedAt" db:"finished_at"`
+}
+
+// Result returns the run as a crawl result, so runs and results can be compared alike
+func (r *CrawlRun) Result() *CrawlResult {
+	return &CrawlResult{
+		ID:                     r.CrawlID,
+		URL:                    r.URL,
+		Title:                  r.Title,
+		HTMLVersion:            r.HTMLVersion,
+		InternalLinksCount:     r.InternalLinksCount,
+		ExternalLinksCount:     r.ExternalLinksCount,
+		OtherLinksCount:        r.OtherLinksCount,
+		InaccessibleLinksCount: r.InaccessibleLinksCount,
+		HasLoginForm:           r.HasLoginForm,
+		HeadingCounts:          r.HeadingCounts,
+		BrokenLinks:            r.BrokenLinks,
+		ExternalLinks:          r.ExternalLinks,
+		CheckedLinks:           r.CheckedLinks,
+		Status:                 r.Status,
+		ErrorMessage:           r.ErrorMessage,
+		Attempts:               r.Attempts,
+		UpdatedAt:              r.FinishedAt,
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// CrawlDiffSide identifies one of the two crawls being compared
+type CrawlDiffSide struct {
+	ID        string      `json:"id"`
+	RunNumber *int        `json:"runNumber,omitempty"` // set when a past run was compared rather than the latest result
+	URL       string      `json:"url"`
+	Status    CrawlStatus `json:"status"`
+	CrawledAt time.Time   `json:"crawledAt"`
+}
+
+// StringChange records a text field that differs between two crawls
+type StringChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// BoolChange records a flag that differs between two crawls
+type BoolChange struct {
+	From bool `json:"from"`
+	To   bool `json:"to"`
+}
+
+// LinkCountDeltas holds how much each link count changed from a to b
+type LinkCountDeltas struct {
+	Internal     int `json:"internal"`
+	External     int `json:"external"`
+	Other        int `json:"other"`
+	Inaccessible int `json:"inaccessible"`
+}
+
+// CrawlDiff is a structured comparison of two crawl results, from a to b.
+// Deltas are b minus a; unchanged text fields are omitted.
+type CrawlDiff struct {
+	A CrawlDiffSide `json:"a"`
+	B CrawlDiffSide `json:"b"`
+
+	Changed       bool            `json:"changed"` // whether any compared field differs
+	Title         *StringChange   `json:"title,omitempty"`
+	HTMLVersion   *StringChange   `json:"htmlVersion,omitempty"`
+	LoginForm     *BoolChange     `json:"loginForm,omitempty"`
+	HeadingDeltas HeadingCounts   `json:"headingDeltas"`
+	LinkDeltas    LinkCountDeltas `json:"linkDeltas"`
+
+	ExternalLinksAdded   []string     `json:"externalLinksAdded"`
+	ExternalLinksRemoved []string     `json:"externalLinksRemoved"`
+	NewlyBrokenLinks     []BrokenLink `json:"newlyBrokenLinks"` // broken in b but not in a
+	FixedLinks           []BrokenLink `json:"fixedLinks"`       // broken in a, checked and not broken in b
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// CrawlEventType names a change in the state of a crawl
+type CrawlEventType string
+
+const (
+	CrawlEventQueued    CrawlEventType = "queued"
+	CrawlEventRunning   CrawlEventType = "running"
+	CrawlEventPhase     CrawlEventType = "phase"
+	CrawlEventCompleted CrawlEventType = "completed"
+	CrawlEventError     CrawlEventType = "error"
+	CrawlEventCancelled CrawlEventType = "cancelled"
+)
+
+// CrawlPhase names a step of a running crawl
+type CrawlPhase string
+
+const (
+	CrawlPhaseFetching      CrawlPhase = "fetching"
+	CrawlPhaseAnalyzing     CrawlPhase = "analyzing"
+	CrawlPhaseCheckingLinks CrawlPhase = "checking_links"
+	CrawlPhaseSaving        CrawlPhase = "saving"
+)
+
+// CrawlEvent records a state change of a crawl. IDs increase monotonically,
+// so a client can resume a stream after the last ID it received.
+type CrawlEvent struct {
+	ID        int64          `json:"id" db:"id"`
+	CrawlID   string         `json:"crawlId" db:"crawl_id"`
+	Type      CrawlEventType `json:"type" db:"type"`
+	Status    CrawlStatus    `json:"status" db:"status"`
+	Phase     CrawlPhase     `json:"phase,omitempty" db:"phase"`
+	Attempt   int            `json:"attempt,omitempty" db:"attempt"`
+	Message   string         `json:"message,omitempty" db:"message"`
+	CreatedAt time.Time      `json:"createdAt" db:"created_at"`
+}
+
+// CrawlEventFilter selects the crawls a stream follows
+type CrawlEventFilter struct {
+	CrawlID string // a single crawl
+	Owner   string // every crawl requested with an API key, when no crawl is given
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// IdempotencyRecord remembers the response to a request sent with an
+// Idempotency-Key, so that retries of the request get the same response
+type IdempotencyRecord struct {
+	Owner          string    `json:"-" db:"owner"` // name of the API key that sent the request
+	Key            string    `json:"key" db:"idem_key"`
+	RequestHash    string    `json:"-" db:"request_hash"`    // hash of the method, URI and body of the request
+	ResponseStatus *int      `json:"-" db:"response_status"` // nil while the request is still being handled
+	ContentType    string    `json:"-" db:"content_type"`
+	ResponseBody   []byte    `json:"-" db:"response_body"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+	ExpiresAt      time.Time `json:"expiresAt" db:"expires_at"`
+}
+
+// Pending reports whether the original request has not finished yet
+func (r *IdempotencyRecord) Pending() bool {
+	return r.ResponseStatus == nil
+}
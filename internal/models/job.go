@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// CrawlJob is a durable queue entry for a crawl result. Workers claim queued
+// jobs and hold a lease on them while crawling.
+type CrawlJob struct {
+	ID              string        `json:"id" db:"id"` // same as the crawl result ID
+	URL             string        `json:"url" db:"url"`
+	SiteCrawlID     *string       `json:"siteCrawlId,omitempty" db:"site_crawl_id"`
+	ParentID        *string       `json:"parentId,omitempty" db:"parent_id"`
+	Depth           int           `json:"depth" db:"depth"`
+	Priority        CrawlPriority `json:"priority" db:"priority"`
+	Status          CrawlStatus   `json:"status" db:"status"`
+	Attempts        int           `json:"attempts" db:"attempts"` // claims so far, including the current one
+	LastError       *string       `json:"lastError,omitempty" db:"last_error"`
+	AvailableAt     time.Time     `json:"availableAt" db:"available_at"` // earliest time the job may be claimed
+	LeaseOwner      *string       `json:"leaseOwner,omitempty" db:"lease_owner"`
+	LeaseExpiresAt  *time.Time    `json:"leaseExpiresAt,omitempty" db:"lease_expires_at"`
+	CancelRequested bool          `json:"cancelRequested" db:"cancel_requested"` // set while a running job is being aborted
+	CreatedAt       time.Time     `json:"createdAt" db:"created_at"`
+	UpdatedAt       time.Time     `json:"updatedAt" db:"updated_at"`
+}
+
+// ReclaimedJobs describes what became of jobs whose lease expired
+type ReclaimedJobs struct {
+	Requeued  int
+	Failed    []CrawlJob // failed after using up their attempts
+	Cancelled []CrawlJob // cancelled because a cancellation was requested
+}
+
+// Total returns the number of jobs reclaimed
+func (r *ReclaimedJobs) Total() int {
+	return r.Requeued + len(r.Failed) + len(r.Cancelled)
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// ScheduleRequest represents a request to crawl a URL on a recurring schedule
+type ScheduleRequest struct {
+	URL      string        `json:"url" validate:"required,url"`
+	Cron     string        `json:"cron" validate:"required"` // five-field cron expression or @daily style shorthand
+	Timezone string        `json:"timezone,omitempty"`       // IANA zone the expression is evaluated in, defaults to UTC
+	Priority CrawlPriority `json:"priority,omitempty" validate:"omitempty,oneof=high normal low"`
+}
+
+// DefaultScheduleTimezone is used when a schedule does not name a timezone
+const DefaultScheduleTimezone = "UTC"
+
+// ApplyDefaults fills in unset schedule options
+func (r *ScheduleRequest) ApplyDefaults() {
+	if r.Timezone == "" {
+		r.Timezone = DefaultScheduleTimezone
+	}
+	r.Priority = r.Priority.OrDefault()
+}
+
+// Schedule is a recurring crawl of a single URL
+type Schedule struct {
+	ID          string        `json:"id" db:"id"`
+	URL         string        `json:"url" db:"url"`
+	Cron        string        `json:"cron" db:"cron"`
+	Timezone    string        `json:"timezone" db:"timezone"`
+	Priority    CrawlPriority `json:"priority" db:"priority"`
+	Owner       string        `json:"-" db:"owner"` // name of the API key that created it, owns the crawls it enqueues
+	NextRunAt   time.Time     `json:"nextRunAt" db:"next_run_at"`
+	LastRunAt   *time.Time    `json:"lastRunAt,omitempty" db:"last_run_at"`     // tick of the most recent run
+	LastCrawlID *string       `json:"lastCrawlId,omitempty" db:"last_crawl_id"` // crawl enqueued by the most recent run
+	LastError   *string       `json:"lastError,omitempty" db:"last_error"`      // why the most recent run could not enqueue
+	CreatedAt   time.Time     `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updatedAt" db:"updated_at"`
+}
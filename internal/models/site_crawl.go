@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// SiteCrawlRequest represents a request to crawl a site starting from a seed URL
+type SiteCrawlRequest struct {
+	URL        string        `json:"url" validate:"required,url"`
+	MaxDepth   *int          `json:"maxDepth,omitempty" validate:"omitempty,min=0,max=10"` // 0 crawls only the seed page
+	MaxPages   int           `json:"maxPages" validate:"min=0,max=1000"`
+	SameDomain *bool         `json:"sameDomain,omitempty"`
+	Priority   CrawlPriority `json:"priority,omitempty" validate:"omitempty,oneof=high normal low"` // lane for every page of the crawl
+}
+
+// Default site crawl limits used when the request leaves them unset
+const (
+	DefaultSiteCrawlMaxDepth = 2
+	DefaultSiteCrawlMaxPages = 50
+)
+
+// ApplyDefaults fills in unset site crawl limits
+func (r *SiteCrawlRequest) ApplyDefaults() {
+	if r.MaxDepth == nil {
+		maxDepth := DefaultSiteCrawlMaxDepth
+		r.MaxDepth = &maxDepth
+	}
+	if r.MaxPages == 0 {
+		r.MaxPages = DefaultSiteCrawlMaxPages
+	}
+	if r.SameDomain == nil {
+		sameDomain := true
+		r.SameDomain = &sameDomain
+	}
+}
+
+// SiteCrawl represents a multi-page crawl job discovered from a seed URL
+type SiteCrawl struct {
+	ID         string      `json:"id" db:"id"`
+	SeedURL    string      `json:"seedUrl" db:"seed_url"`
+	MaxDepth   int         `json:"maxDepth" db:"max_depth"`
+	MaxPages   int         `json:"maxPages" db:"max_pages"`
+	SameDomain bool        `json:"sameDomain" db:"same_domain"`
+	Status     CrawlStatus `json:"status" db:"status"`
+	Owner      string      `json:"-" db:"owner"` // name of the API key that started the crawl
+	CreatedAt  time.Time   `json:"createdAt" db:"created_at"`
+	UpdatedAt  time.Time   `json:"updatedAt" db:"updated_at"`
+}
+
+// SiteCrawlStats aggregates the results of the pages in a site crawl
+type SiteCrawlStats struct {
+	TotalPages           int `json:"totalPages"`
+	Queued               int `json:"queued"`
+	Running              int `json:"running"`
+	Completed            int `json:"completed"`
+	Error                int `json:"error"`
+	Cancelled            int `json:"cancelled"`
+	PagesWithBrokenLinks int `json:"pagesWithBrokenLinks"`
+	PagesWithLoginForms  int `json:"pagesWithLoginForms"`
+}
+
+// SiteCrawlResults represents a site crawl with its aggregate stats and per-page results
+type SiteCrawlResults struct {
+	SiteCrawl SiteCrawl      `json:"siteCrawl"`
+	Stats     SiteCrawlStats `json:"stats"`
+	Pages     []CrawlResult  `json:"pages"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// SitemapCrawlRequest represents a request to seed crawls from a sitemap
+type SitemapCrawlRequest struct {
+	URL        string        `json:"url" validate:"required,url"`
+	PathPrefix string        `json:"pathPrefix,omitempty"`
+	Pattern    string        `json:"pattern,omitempty"`
+	Limit      int           `json:"limit" validate:"min=0,max=1000"`
+	Priority   CrawlPriority `json:"priority,omitempty" validate:"omitempty,oneof=high normal low"`
+}
+
+// DefaultSitemapCrawlLimit caps how many sitemap URLs are enqueued when no limit is given
+const DefaultSitemapCrawlLimit = 100
+
+// ApplyDefaults fills in unset sitemap crawl options
+func (r *SitemapCrawlRequest) ApplyDefaults() {
+	if r.Limit == 0 {
+		r.Limit = DefaultSitemapCrawlLimit
+	}
+}
+
+// SitemapEntry represents a single <url> entry of a sitemap
+type SitemapEntry struct {
+	URL      string     `json:"url"`
+	LastMod  *time.Time `json:"lastmod,omitempty"`
+	Priority float64    `json:"priority"`
+}
+
+// SitemapCrawl represents a crawl enqueued from a sitemap entry
+type SitemapCrawl struct {
+	ID string `json:"id"`
+	SitemapEntry
+}
+
+// SitemapCrawlResponse represents the outcome of seeding crawls from a sitemap
+type SitemapCrawlResponse struct {
+	BatchID     string         `json:"batchId"`
+	Sitemap     string         `json:"sitemap"`
+	Found       int            `json:"found"`
+	Accepted    int            `json:"accepted"`
+	Skipped     int            `json:"skipped"`
+	Rejected    int            `json:"rejected"`
+	Unprocessed int            `json:"unprocessed,omitempty"` // matching URLs left out when the request ran out of time
+	Crawls      []SitemapCrawl `json:"crawls"`
+	Errors      []string       `json:"errors,omitempty"`
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookScope selects which crawls a webhook is notified about
+type WebhookScope string
+
+const (
+	// WebhookScopeKey webhooks receive every crawl requested with the owning API key
+	WebhookScopeKey WebhookScope = "key"
+	// WebhookScopeCrawl webhooks receive only the crawl they were registered with
+	WebhookScopeCrawl WebhookScope = "crawl"
+)
+
+// WebhookEvent names the event a delivery reports
+type WebhookEvent string
+
+const (
+	WebhookEventCrawlCompleted WebhookEvent = "crawl.completed"
+	WebhookEventCrawlError     WebhookEvent = "crawl.error"
+)
+
+// WebhookDeliveryStatus represents the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookRequest registers a webhook, either on its own or alongside a crawl request
+type WebhookRequest struct {
+	URL    string `json:"url" validate:"required,url"`
+	Secret string `json:"secret,omitempty" validate:"omitempty,min=16,max=255"` // generated when empty
+}
+
+// Webhook is an endpoint notified when crawls finish
+type Webhook struct {
+	ID        string       `json:"id" db:"id"`
+	Owner     string       `json:"-" db:"owner"` // name of the API key that registered it
+	URL       string       `json:"url" db:"url"`
+	Secret    string       `json:"-" db:"secret"` // HMAC-SHA256 signing key, only returned on registration
+	Scope     WebhookScope `json:"scope" db:"scope"`
+	CreatedAt time.Time    `json:"createdAt" db:"created_at"`
+}
+
+// WebhookRegistration is returned once when a webhook is registered, with its signing secret
+type WebhookRegistration struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery is one notification of a finished crawl to a webhook
+type WebhookDelivery struct {
+	ID             string                   `json:"id" db:"id"`
+	WebhookID      string                   `json:"webhookId" db:"webhook_id"`
+	CrawlID        string                   `json:"crawlId" db:"crawl_id"`
+	Event          WebhookEvent             `json:"event" db:"event"`
+	Payload        json.RawMessage          `json:"-" db:"payload"` // the final crawl result, sent as the request body
+	Status         WebhookDeliveryStatus    `json:"status" db:"status"`
+	Attempts       int                      `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time                `json:"nextAttemptAt" db:"next_attempt_at"`
+	LastStatusCode *int                     `json:"lastStatusCode,omitempty" db:"last_status_code"`
+	LastError      *string                  `json:"lastError,omitempty" db:"last_error"`
+	DeliveredAt    *time.Time               `json:"deliveredAt,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time                `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time                `json:"updatedAt" db:"updated_at"`
+	AttemptLog     []WebhookDeliveryAttempt `json:"attemptLog,omitempty" db:"-"`
+}
+
+// WebhookDeliveryAttempt records the outcome of a single delivery request
+type WebhookDeliveryAttempt struct {
+	DeliveryID string    `json:"-" db:"delivery_id"`
+	Attempt    int       `json:"attempt" db:"attempt"`
+	StatusCode *int      `json:"statusCode,omitempty" db:"status_code"` // nil when no response was received
+	Error      *string   `json:"error,omitempty" db:"error"`
+	DurationMs int64     `json:"durationMs" db:"duration_ms"`
+	CreatedAt  time.Time `json:"createdAt" db:"created_at"`
+}
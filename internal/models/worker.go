@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Worker describes a crawl worker process and its latest heartbeat
+type Worker struct {
+	ID              string    `json:"id" db:"id"` // also the owner of the job leases it holds
+	Hostname        string    `json:"hostname" db:"hostname"`
+	PID             int       `json:"pid" db:"pid"`
+	Concurrency     int       `json:"concurrency" db:"concurrency"`  // worker goroutines in the process
+	ActiveTasks     int       `json:"activeTasks" db:"active_tasks"` // crawls running at the last heartbeat
+	StartedAt       time.Time `json:"startedAt" db:"started_at"`
+	LastHeartbeatAt time.Time `json:"lastHeartbeatAt" db:"last_heartbeat_at"`
+}
@@ -23,7 +23,8 @@ func (s *Server) RegisterRoutes(authCfg config.AuthConfig) http.Handler {
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     []string{"https://*", "http://*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Last-Event-ID", customMiddleware.IdempotencyKeyHeader},
+		ExposeHeaders:    []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -31,13 +32,16 @@ func (s *Server) RegisterRoutes(authCfg config.AuthConfig) http.Handler {
 	// Setup authentication using provided configuration
 	authConfig := customMiddleware.NewAuthConfig(authCfg)
 
-	// Rate limiting using configuration
-	rateLimitConfig := customMiddleware.NewRateLimitConfig(authCfg)
+	// Rate limiting using configuration, shared across instances with the mysql backend
+	rateLimitConfig := customMiddleware.NewRateLimitConfig(authCfg, s.crawlStorage)
 
 	// Apply authentication and rate limiting middleware
 	e.Use(customMiddleware.AuthMiddleware(authConfig))
 	e.Use(customMiddleware.RateLimitMiddleware(rateLimitConfig))
 
+	// Retries of creating and bulk-modifying requests may carry an Idempotency-Key
+	idempotent := customMiddleware.IdempotencyMiddleware(customMiddleware.NewIdempotencyConfig(authCfg, s.crawlStorage))
+
 	// Basic routes (no auth required)
 	e.GET("/", s.APIInfoHandler)
 	e.GET("/health", s.healthHandler)
@@ -52,7 +56,7 @@ func (s *Server) RegisterRoutes(authCfg config.AuthConfig) http.Handler {
 	crawlGroup := api.Group("/crawl")
 	{
 		// Create new crawl request
-		crawlGroup.POST("", s.crawlHandler.CreateCrawlRequest)
+		crawlGroup.POST("", s.crawlHandler.CreateCrawlRequest, idempotent)
 
 		// Get all crawl results (with pagination, filtering, sorting)
 		crawlGroup.GET("", s.crawlHandler.GetCrawlResults)
@@ -60,13 +64,65 @@ func (s *Server) RegisterRoutes(authCfg config.AuthConfig) http.Handler {
 		// Get crawl statistics
 		crawlGroup.GET("/stats", s.crawlHandler.GetCrawlStats)
 
+		// Compare two crawl results or runs
+		crawlGroup.GET("/diff", s.crawlHandler.GetCrawlDiff)
+
+		// Live crawl state changes as Server-Sent Events
+		crawlGroup.GET("/events", s.crawlHandler.StreamCrawlEvents)
+
+		// Multi-page site crawls
+		crawlGroup.POST("/site", s.crawlHandler.CreateSiteCrawl)
+		crawlGroup.GET("/site/:id", s.crawlHandler.GetSiteCrawl)
+
+		// Seed crawls from a sitemap or sitemap index
+		crawlGroup.POST("/sitemap", s.crawlHandler.CreateSitemapCrawl)
+
+		// Batch submission of many URLs
+		crawlGroup.POST("/batch", s.crawlHandler.CreateBatchCrawl)
+
 		// Bulk operations
-		crawlGroup.POST("/rerun", s.crawlHandler.RerunCrawlResults)
-		crawlGroup.DELETE("", s.crawlHandler.DeleteCrawlResults)
+		crawlGroup.POST("/rerun", s.crawlHandler.RerunCrawlResults, idempotent)
+		crawlGroup.POST("/cancel", s.crawlHandler.CancelCrawlResults)
+		crawlGroup.DELETE("", s.crawlHandler.DeleteCrawlResults, idempotent)
 
 		// Individual crawl result operations
 		crawlGroup.GET("/:id", s.crawlHandler.GetCrawlResult)
 		crawlGroup.GET("/:id/status", s.crawlHandler.GetCrawlStatus)
+		crawlGroup.POST("/:id/cancel", s.crawlHandler.CancelCrawl)
+		crawlGroup.GET("/:id/events", s.crawlHandler.StreamCrawlEventsForCrawl)
+
+		// Run history of a crawl
+		crawlGroup.GET("/:id/runs", s.crawlHandler.GetCrawlRuns)
+		crawlGroup.GET("/:id/runs/:n", s.crawlHandler.GetCrawlRun)
+	}
+
+	// Progress of batch submissions
+	batchGroup := api.Group("/batches")
+	{
+		batchGroup.GET("/:id", s.crawlHandler.GetBatch)
+	}
+
+	// Recurring crawl schedules
+	scheduleGroup := api.Group("/schedules")
+	{
+		scheduleGroup.POST("", s.crawlHandler.CreateSchedule)
+		scheduleGroup.GET("", s.crawlHandler.GetSchedules)
+		scheduleGroup.GET("/:id", s.crawlHandler.GetSchedule)
+		scheduleGroup.DELETE("/:id", s.crawlHandler.DeleteSchedule)
+	}
+
+	// Webhook notifications for finished crawls
+	webhookGroup := api.Group("/webhooks")
+	{
+		webhookGroup.POST("", s.crawlHandler.CreateWebhook)
+		webhookGroup.GET("", s.crawlHandler.GetWebhooks)
+		webhookGroup.GET("/:id", s.crawlHandler.GetWebhook)
+		webhookGroup.DELETE("/:id", s.crawlHandler.DeleteWebhook)
+
+		// Delivery log and manual redelivery
+		webhookGroup.GET("/:id/deliveries", s.crawlHandler.GetWebhookDeliveries)
+		webhookGroup.GET("/deliveries/:id", s.crawlHandler.GetWebhookDelivery)
+		webhookGroup.POST("/deliveries/:id/redeliver", s.crawlHandler.RedeliverWebhook)
 	}
 
 	return e
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -22,24 +23,18 @@ type Server struct {
 	// Services
 	crawlerService services.Crawler
 	queueService   *services.QueueService
+	scheduler      *services.Scheduler
+	webhooks       *services.WebhookService
 	crawlStorage   *database.CrawlStorage
 
 	// Handlers
 	crawlHandler *handlers.CrawlHandler
 }
 
-func NewServer() *http.Server {
-	// Load configuration
-	cfg := config.Load()
-
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Configuration validation failed: %v", err)
-	}
-
-	// Log configuration (without sensitive data)
-	cfg.LogConfig()
-
+// NewServer creates the HTTP API server. Crawl workers run in the same process
+// when the configured run mode includes them. The returned function stops the
+// background services and closes the database once the HTTP server has shut down.
+func NewServer(cfg *config.Config) (*http.Server, func(context.Context)) {
 	// Initialize database service with configuration
 	dbService := database.New(cfg.Database)
 
@@ -47,30 +42,47 @@ func NewServer() *http.Server {
 	db := dbService.GetDB()
 	crawlStorage := database.NewCrawlStorage(db)
 
-	// Initialize Firecrawl crawler service with configuration
-	crawlerService := services.NewFirecrawlService(cfg.Crawler)
-	if crawlerService == nil {
-		log.Fatal("Failed to initialize Firecrawl service. Please ensure FIRECRAWL_API_KEY is set.")
-	}
-	log.Printf("Initialized Firecrawl crawler service")
+	// Initialize the configured crawler backend
+	crawlerService := newCrawlerService(cfg.Crawler)
 
 	// Initialize queue service with configuration
-	queueService := services.NewQueueService(cfg.Queue.Workers, crawlerService, crawlStorage)
+	queueService := services.NewQueueServiceWithConfig(cfg.Queue, crawlerService, crawlStorage)
+
+	// Recurring crawls are enqueued through the same queue
+	scheduler := services.NewScheduler(cfg.Scheduler, crawlStorage, queueService)
+
+	// Finished crawls are reported to their webhooks
+	webhookService := services.NewWebhookService(cfg.Webhooks, cfg.Crawler, crawlStorage)
+	queueService.SetNotifier(webhookService)
+
+	// Crawl state changes are recorded for live event streams
+	crawlEvents := services.NewCrawlEvents(cfg.Events, crawlStorage)
+	queueService.SetEventPublisher(crawlEvents)
+
+	// Sitemap seeding applies the same URL policies as the crawler
+	sitemapFetcher := services.NewSitemapFetcher(cfg.Crawler, services.NewURLValidator(cfg.Crawler))
 
 	// Initialize handlers
-	crawlHandler := handlers.NewCrawlHandler(queueService, crawlStorage)
+	crawlHandler := handlers.NewCrawlHandler(queueService, crawlStorage, sitemapFetcher, scheduler, webhookService, crawlEvents, cfg.Server.WriteTimeout)
 
 	newServer := &Server{
 		port:           cfg.Server.Port,
 		db:             dbService,
 		crawlerService: crawlerService,
 		queueService:   queueService,
+		scheduler:      scheduler,
+		webhooks:       webhookService,
 		crawlStorage:   crawlStorage,
 		crawlHandler:   crawlHandler,
 	}
 
-	// Start the queue service
-	queueService.Start()
+	// Start the queue workers, scheduler and webhook delivery; in API-only mode
+	// they are left to worker processes
+	if cfg.Server.RunsWorkers() {
+		queueService.Start()
+		scheduler.Start()
+		webhookService.Start()
+	}
 
 	// Declare Server config with proper configuration values
 	server := &http.Server{
@@ -81,5 +93,77 @@ func NewServer() *http.Server {
 		WriteTimeout: cfg.Server.WriteTimeout,
 	}
 
-	return server
+	// Event streams never go idle, so they are ended for Shutdown not to wait on them
+	server.RegisterOnShutdown(crawlEvents.Close)
+
+	return server, newServer.shutdown
+}
+
+// NewWorker starts crawl workers, the scheduler and webhook delivery without the HTTP API, for
+// scaling workers out separately. The returned function stops them on shutdown.
+func NewWorker(cfg *config.Config) func(context.Context) {
+	dbService := database.New(cfg.Database)
+	crawlStorage := database.NewCrawlStorage(dbService.GetDB())
+
+	queueService := services.NewQueueServiceWithConfig(cfg.Queue, newCrawlerService(cfg.Crawler), crawlStorage)
+	scheduler := services.NewScheduler(cfg.Scheduler, crawlStorage, queueService)
+	webhookService := services.NewWebhookService(cfg.Webhooks, cfg.Crawler, crawlStorage)
+	queueService.SetNotifier(webhookService)
+	queueService.SetEventPublisher(services.NewCrawlEvents(cfg.Events, crawlStorage))
+	queueService.Start()
+	scheduler.Start()
+	webhookService.Start()
+
+	worker := &Server{
+		db:           dbService,
+		queueService: queueService,
+		scheduler:    scheduler,
+		webhooks:     webhookService,
+	}
+	return worker.shutdown
+}
+
+// shutdown stops the background services in order and closes the database.
+// Scheduling stops first so nothing new is enqueued, then running crawls get
+// until ctx is done to finish before the rest are queued again. Webhook
+// deliveries not yet sent stay in the database for the next process.
+func (s *Server) shutdown(ctx context.Context) {
+	log.Println("Shutdown: stopping the scheduler")
+	s.scheduler.Stop()
+
+	log.Println("Shutdown: draining the crawl queue")
+	s.queueService.Shutdown(ctx)
+
+	log.Println("Shutdown: stopping webhook delivery")
+	s.webhooks.Stop()
+
+	log.Println("Shutdown: closing the database")
+	if err := s.db.Close(); err != nil {
+		log.Printf("Failed to close the database: %v", err)
+	}
+}
+
+// newCrawlerService selects the crawler backend based on configuration.
+// In auto mode Firecrawl is used when an API key is present, otherwise the
+// native HTTP crawler is used.
+func newCrawlerService(cfg config.CrawlerConfig) services.Crawler {
+	backend := cfg.Backend
+	if backend == config.CrawlerBackendAuto {
+		backend = config.CrawlerBackendHTTP
+		if cfg.FirecrawlAPIKey != "" {
+			backend = config.CrawlerBackendFirecrawl
+		}
+	}
+
+	if backend == config.CrawlerBackendFirecrawl {
+		firecrawlService := services.NewFirecrawlService(cfg)
+		if firecrawlService == nil {
+			log.Fatal("Failed to initialize Firecrawl service. Please ensure FIRECRAWL_API_KEY is set.")
+		}
+		log.Printf("Initialized Firecrawl crawler service")
+		return firecrawlService
+	}
+
+	log.Printf("Initialized HTTP crawler service")
+	return services.NewHTTPCrawlerService(cfg)
 }
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"url-crawler/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// EnqueueBatch validates and de-duplicates a list of URLs and enqueues the valid
+// ones as a batch. Invalid URLs are reported in the submission rather than
+// failing the batch, but a batch that does not fit in the free queue capacity
+// is refused as a whole with ErrQueueFull. When no URL is valid no batch is
+// created and the returned submission has no batch ID.
+//
+// Validation stops once ctx is done, and the URLs not validated by then are
+// reported as unprocessed. The URLs validated so far are still enqueued.
+func (q *QueueService) EnqueueBatch(ctx context.Context, urls []string, source models.BatchSource, opts EnqueueOptions) (*models.BatchSubmission, error) {
+	submission := &models.BatchSubmission{
+		Submitted: len(urls),
+		Crawls:    []models.BatchCrawl{},
+	}
+
+	seen := make(map[string]bool, len(urls))
+	var valid []string
+	for i, rawURL := range urls {
+		if ctx.Err() != nil {
+			submission.Unprocessed = urls[i:]
+			break
+		}
+
+		rawURL = strings.TrimSpace(rawURL)
+		if seen[rawURL] {
+			submission.Duplicates++
+			continue
+		}
+		seen[rawURL] = true
+
+		if err := q.crawler.ValidateURL(ctx, rawURL); err != nil {
+			submission.Rejected++
+			submission.Errors = append(submission.Errors, fmt.Sprintf("%s: invalid URL: %v", rawURL, err))
+			continue
+		}
+		valid = append(valid, rawURL)
+	}
+
+	if len(valid) == 0 {
+		return submission, nil
+	}
+
+	// The valid URLs are recorded even when the validation budget ran out
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), q.storageTimeout)
+	defer cancel()
+
+	if q.isStopped() {
+		return nil, ErrQueueStopped
+	}
+
+	free, err := q.freeCapacity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(valid) > free {
+		return nil, fmt.Errorf("%w: the batch has %d URLs but only %d can be queued", ErrQueueFull, len(valid), free)
+	}
+
+	batch := &models.Batch{
+		ID:        uuid.New().String(),
+		Owner:     opts.Owner,
+		Source:    source,
+		CreatedAt: time.Now(),
+	}
+	if err := q.storage.SaveBatch(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to save batch: %w", err)
+	}
+	submission.BatchID = batch.ID
+
+	// Capacity was checked for the whole batch, so concurrent submissions may briefly overfill the queue
+	for _, rawURL := range valid {
+		result, err := q.insertTask(ctx, &CrawlTask{
+			ID:        uuid.New().String(),
+			URL:       rawURL,
+			CreatedAt: time.Now(),
+			Status:    models.CrawlStatusQueued,
+			Priority:  opts.Priority.OrDefault(),
+			Owner:     opts.Owner,
+			BatchID:   batch.ID,
+		})
+		if err != nil {
+			submission.Rejected++
+			submission.Errors = append(submission.Errors, rawURL+": "+err.Error())
+			continue
+		}
+
+		submission.Accepted++
+		submission.Crawls = append(submission.Crawls, models.BatchCrawl{ID: result.ID, URL: result.URL})
+	}
+
+	log.Printf("Enqueued batch %s: %d of %d URLs accepted", batch.ID, submission.Accepted, submission.Submitted)
+	return submission, nil
+}
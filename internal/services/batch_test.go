@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+// httpOnlyCrawler accepts only http and https URLs
+type httpOnlyCrawler struct {
+	fakeCrawler
+}
+
+func (h *httpOnlyCrawler) ValidateURL(ctx context.Context, targetURL string) error {
+	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
+		return errors.New("URL must use http or https")
+	}
+	return nil
+}
+
+func TestEnqueueBatch(t *testing.T) {
+	storage := newMemoryStorage()
+	queue := newTestQueue(&httpOnlyCrawler{}, storage)
+
+	submission, err := queue.EnqueueBatch(context.Background(), []string{
+		"https://example.com/a",
+		" https://example.com/a ",
+		"ftp://example.com/file",
+		"https://example.com/b",
+	}, models.BatchSourceLines, EnqueueOptions{Owner: "team-a"})
+	if err != nil {
+		t.Fatalf("EnqueueBatch() error = %v", err)
+	}
+
+	if submission.Submitted != 4 || submission.Accepted != 2 || submission.Duplicates != 1 || submission.Rejected != 1 {
+		t.Errorf("submission = %+v, expected 4 submitted, 2 accepted, 1 duplicate and 1 rejected", submission)
+	}
+	if len(submission.Errors) != 1 || !strings.HasPrefix(submission.Errors[0], "ftp://example.com/file: ") {
+		t.Errorf("errors = %v, expected the ftp URL to be reported", submission.Errors)
+	}
+
+	batch, ok := storage.batches[submission.BatchID]
+	if !ok {
+		t.Fatalf("batch %q was not saved", submission.BatchID)
+	}
+	if batch.Owner != "team-a" || batch.Source != models.BatchSourceLines {
+		t.Errorf("batch = %+v, expected team-a's line-delimited batch", batch)
+	}
+
+	for _, crawl := range submission.Crawls {
+		result, err := storage.GetCrawlResult(context.Background(), crawl.ID)
+		if err != nil {
+			t.Fatalf("GetCrawlResult(%s) error = %v", crawl.ID, err)
+		}
+		if result.BatchID == nil || *result.BatchID != submission.BatchID {
+			t.Errorf("crawl %s batch = %v, expected %s", crawl.URL, result.BatchID, submission.BatchID)
+		}
+	}
+}
+
+func TestEnqueueBatchWithoutValidURLs(t *testing.T) {
+	storage := newMemoryStorage()
+	queue := newTestQueue(&httpOnlyCrawler{}, storage)
+
+	submission, err := queue.EnqueueBatch(context.Background(), []string{"mailto:someone@example.com"}, models.BatchSourceJSON, EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueBatch() error = %v", err)
+	}
+
+	if submission.BatchID != "" || submission.Rejected != 1 {
+		t.Errorf("submission = %+v, expected no batch and 1 rejected URL", submission)
+	}
+	if len(storage.batches) != 0 {
+		t.Errorf("saved %d batches, expected none", len(storage.batches))
+	}
+}
+
+func TestEnqueueBatchRespectsCapacity(t *testing.T) {
+	storage := newMemoryStorage()
+	queue := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 3}, &fakeCrawler{}, storage)
+
+	if _, err := queue.EnqueueURL(context.Background(), "https://example.com/", EnqueueOptions{}); err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+
+	// Two slots are left, so a batch of three is refused as a whole
+	_, err := queue.EnqueueBatch(context.Background(), []string{
+		"https://example.com/a",
+		"https://example.com/b",
+		"https://example.com/c",
+	}, models.BatchSourceJSON, EnqueueOptions{})
+	if !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("EnqueueBatch() error = %v, expected ErrQueueFull", err)
+	}
+	if len(storage.batches) != 0 || len(storage.results) != 1 {
+		t.Errorf("saved %d batches and %d results, expected the refused batch to leave no trace", len(storage.batches), len(storage.results))
+	}
+
+	submission, err := queue.EnqueueBatch(context.Background(), []string{
+		"https://example.com/a",
+		"https://example.com/b",
+	}, models.BatchSourceJSON, EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueBatch() error = %v", err)
+	}
+	if submission.Accepted != 2 {
+		t.Errorf("accepted %d URLs, expected 2", submission.Accepted)
+	}
+}
+
+// cancellingCrawler cancels the submission's context once it has validated a number of URLs
+type cancellingCrawler struct {
+	fakeCrawler
+	validations int
+	cancelAfter int
+	cancel      context.CancelFunc
+}
+
+func (c *cancellingCrawler) ValidateURL(ctx context.Context, targetURL string) error {
+	c.validations++
+	if c.validations == c.cancelAfter {
+		c.cancel()
+	}
+	return nil
+}
+
+func TestEnqueueBatchReportsUnprocessedURLs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage := newMemoryStorage()
+	crawler := &cancellingCrawler{cancelAfter: 2, cancel: cancel}
+	queue := newTestQueue(crawler, storage)
+
+	submission, err := queue.EnqueueBatch(ctx, []string{
+		"https://example.com/a",
+		"https://example.com/b",
+		"https://example.com/c",
+		"https://example.com/d",
+	}, models.BatchSourceJSON, EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueBatch() error = %v", err)
+	}
+
+	if submission.Accepted != 2 || submission.BatchID == "" {
+		t.Errorf("submission = %+v, expected the 2 validated URLs to be enqueued", submission)
+	}
+	if strings.Join(submission.Unprocessed, " ") != "https://example.com/c https://example.com/d" {
+		t.Errorf("unprocessed = %v, expected the URLs after the budget ran out", submission.Unprocessed)
+	}
+	if crawler.validations != 2 {
+		t.Errorf("validated %d URLs, expected validation to stop with the context", crawler.validations)
+	}
+}
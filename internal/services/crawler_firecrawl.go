@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"regexp"
 	"strings"
 	"time"
@@ -16,7 +18,10 @@ import (
 
 // FirecrawlService implements the crawler interface using Firecrawl SDK
 type FirecrawlService struct {
-	app *firecrawl.FirecrawlApp
+	app         *firecrawl.FirecrawlApp
+	subdomains  bool
+	validator   *URLValidator
+	linkChecker *LinkChecker
 }
 
 // NewFirecrawlServiceWithConfig creates a new Firecrawl-based crawler service using configuration
@@ -41,14 +46,28 @@ func NewFirecrawlService(cfg config.CrawlerConfig) *FirecrawlService {
 		return nil
 	}
 
+	// Surface rate limits and server errors with their status and Retry-After for the retry policy.
+	// ScrapeURL makes a single attempt, so this costs no SDK retry: a 502 from the
+	// scrape endpoint is retried by the queue with backoff instead.
+	transport := app.Client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	app.Client.Transport = &statusErrorTransport{base: transport}
+
+	validator := NewURLValidator(cfg)
+
 	log.Printf("Firecrawl service initialized with API URL: %s (using config)", apiUrl)
 	return &FirecrawlService{
-		app: app,
+		app:         app,
+		subdomains:  cfg.SubdomainsInternal,
+		validator:   validator,
+		linkChecker: NewLinkChecker(cfg, validator),
 	}
 }
 
 // AnalyzeURL performs comprehensive analysis using Firecrawl
-func (fs *FirecrawlService) AnalyzeURL(targetURL string) (*models.CrawlResult, error) {
+func (fs *FirecrawlService) AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error) {
 	if fs.app == nil {
 		return nil, fmt.Errorf("firecrawl service not properly initialized")
 	}
@@ -76,22 +95,40 @@ func (fs *FirecrawlService) AnalyzeURL(targetURL string) (*models.CrawlResult, e
 		IncludeTags: []string{"title", "h1", "h2", "h3", "h4", "h5", "h6", "form", "input", "a", "link"},
 		WaitFor:     &waitFor,
 	}
-	scrapeResponse, err := fs.app.ScrapeURL(targetURL, scrapeParams)
+	reportPhase(ctx, models.CrawlPhaseFetching)
+	scrapeResponse, err := fs.scrape(ctx, targetURL, scrapeParams)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		result.Status = models.CrawlStatusError
 		errorMsg := fmt.Sprintf("Firecrawl scrape failed: %v", err)
 		result.ErrorMessage = &errorMsg
 		return result, fmt.Errorf("failed to scrape URL with Firecrawl: %w", err)
 	}
 
+	// Error pages fail the crawl like they do with the HTTP backend, so 5xx and 429 are retried
+	if statusErr := pageStatusError(scrapeResponse); statusErr != nil {
+		result.Status = models.CrawlStatusError
+		errorMsg := statusErr.Error()
+		result.ErrorMessage = &errorMsg
+		return result, fmt.Errorf("failed to fetch URL: %w", statusErr)
+	}
+
 	log.Printf("Firecrawl successfully scraped URL: %s", targetURL)
 
 	// Extract data from Firecrawl response
-	if err := fs.extractDataFromFirecrawlDocument(scrapeResponse, result); err != nil {
+	reportPhase(ctx, models.CrawlPhaseAnalyzing)
+	if err := fs.extractDataFromFirecrawlDocument(ctx, scrapeResponse, result); err != nil {
 		log.Printf("Warning: Failed to extract some data from response: %v", err)
 		// Don't fail the entire operation, just log the warning
 	}
 
+	// A partial link check must not be reported as a completed analysis
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	// Set completion status
 	result.Status = models.CrawlStatusCompleted
 	result.UpdatedAt = time.Now()
@@ -100,8 +137,31 @@ func (fs *FirecrawlService) AnalyzeURL(targetURL string) (*models.CrawlResult, e
 	return result, nil
 }
 
+// scrape runs a Firecrawl scrape, returning as soon as ctx is done.
+// The SDK does not accept a context, so an abandoned request finishes in the background
+// bounded by the SDK's own HTTP client timeout.
+func (fs *FirecrawlService) scrape(ctx context.Context, targetURL string, params *firecrawl.ScrapeParams) (*firecrawl.FirecrawlDocument, error) {
+	type scrapeResult struct {
+		doc *firecrawl.FirecrawlDocument
+		err error
+	}
+
+	done := make(chan scrapeResult, 1)
+	go func() {
+		doc, err := fs.app.ScrapeURL(targetURL, params)
+		done <- scrapeResult{doc: doc, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.doc, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // extractDataFromFirecrawlDocument extracts relevant data from Firecrawl document
-func (fs *FirecrawlService) extractDataFromFirecrawlDocument(doc *firecrawl.FirecrawlDocument, result *models.CrawlResult) error {
+func (fs *FirecrawlService) extractDataFromFirecrawlDocument(ctx context.Context, doc *firecrawl.FirecrawlDocument, result *models.CrawlResult) error {
 	// Extract title from metadata
 	if doc.Metadata != nil && doc.Metadata.Title != nil {
 		result.Title = strings.TrimSpace(*doc.Metadata.Title)
@@ -109,12 +169,23 @@ func (fs *FirecrawlService) extractDataFromFirecrawlDocument(doc *firecrawl.Fire
 
 	// Extract HTML content
 	if doc.HTML != "" {
-		fs.analyzeHTMLContent(doc.HTML, result)
+		analysis, err := AnalyzeHTML(strings.NewReader(doc.HTML))
+		if err != nil {
+			return err
+		}
+		// Links are classified against the page Firecrawl ended up on after redirects
+		pageURL := firecrawlPageURL(doc, result.URL)
+		analysis.applyTo(result, pageURL, fs.subdomains)
+
+		// Verify the extracted links
+		reportPhase(ctx, models.CrawlPhaseCheckingLinks)
+		result.BrokenLinks, result.CheckedLinks = fs.linkChecker.CheckLinks(ctx, analysis.BaseURL(pageURL), analysis.Links)
+		result.InaccessibleLinksCount = len(result.BrokenLinks)
 	}
 
 	// Extract markdown content
 	if doc.Markdown != "" {
-		fs.analyzeMarkdownContent(doc.Markdown, result)
+		analyzeMarkdownContent(doc.Markdown, result)
 	}
 
 	// Extract metadata if available
@@ -125,23 +196,17 @@ func (fs *FirecrawlService) extractDataFromFirecrawlDocument(doc *firecrawl.Fire
 	return nil
 }
 
-// analyzeHTMLContent analyzes HTML content for various elements
-func (fs *FirecrawlService) analyzeHTMLContent(html string, result *models.CrawlResult) {
-	// Detect login forms
-	result.HasLoginForm = fs.detectLoginForm(html)
-
-	// Count headings
-	fs.countHeadings(html, result)
-
-	// Analyze links
-	fs.analyzeLinks(html, result)
-
-	// Detect HTML version
-	result.HTMLVersion = fs.detectHTMLVersion(html)
+// firecrawlPageURL returns the URL of the scraped page as reported by Firecrawl,
+// falling back to the requested URL
+func firecrawlPageURL(doc *firecrawl.FirecrawlDocument, requested string) string {
+	if doc.Metadata != nil && doc.Metadata.SourceURL != nil && *doc.Metadata.SourceURL != "" {
+		return *doc.Metadata.SourceURL
+	}
+	return requested
 }
 
 // analyzeMarkdownContent analyzes markdown content for additional insights
-func (fs *FirecrawlService) analyzeMarkdownContent(markdown string, result *models.CrawlResult) {
+func analyzeMarkdownContent(markdown string, result *models.CrawlResult) {
 	// Count headings in markdown (as backup/validation)
 	headingRegex := regexp.MustCompile(`(?m)^#+\s+`)
 	headingMatches := headingRegex.FindAllString(markdown, -1)
@@ -153,225 +218,28 @@ func (fs *FirecrawlService) analyzeMarkdownContent(markdown string, result *mode
 	}
 }
 
-// detectLoginForm analyzes HTML for login form patterns
-func (fs *FirecrawlService) detectLoginForm(html string) bool {
-	htmlLower := strings.ToLower(html)
-
-	// Look for password fields (most reliable indicator)
-	passwordPatterns := []string{
-		`type="password"`,
-		`type='password'`,
-		`input[type="password"]`,
-		`input[type='password']`,
-	}
-
-	hasPasswordField := false
-	for _, pattern := range passwordPatterns {
-		if strings.Contains(htmlLower, pattern) {
-			hasPasswordField = true
-			break
-		}
-	}
-
-	// Look for email fields (for email-only login forms)
-	emailPatterns := []string{
-		`type="email"`,
-		`type='email'`,
-		`input[type="email"]`,
-		`input[type='email']`,
-		`name="email"`,
-		`name='email'`,
-		`name="username"`,
-		`name='username'`,
-		`name="user"`,
-		`name='user'`,
-		`placeholder="email"`,
-		`placeholder='email'`,
-		`placeholder="username"`,
-		`placeholder='username'`,
-		`placeholder="user"`,
-		`placeholder='user'`,
-	}
-
-	hasEmailField := false
-	for _, pattern := range emailPatterns {
-		if strings.Contains(htmlLower, pattern) {
-			hasEmailField = true
-			break
-		}
-	}
-
-	// If we have neither password nor email fields, it's not a login form
-	if !hasPasswordField && !hasEmailField {
-		return false
-	}
-
-	// Look for additional login indicators
-	loginIndicators := []string{
-		"login", "signin", "sign-in", "log-in", "auth", "authentication",
-		"username", "email", "user", "account",
-		"password", "pwd", "pass",
-		"submit", "button",
-		"loginform", "authform", "signupform",
-	}
-
-	indicatorCount := 0
-	for _, indicator := range loginIndicators {
-		if strings.Contains(htmlLower, indicator) {
-			indicatorCount++
-		}
-	}
-
-	// If we have a password field and multiple login indicators, it's likely a login form
-	// OR if we have an email field and multiple login indicators, it's likely a login form
-	if hasPasswordField && indicatorCount >= 2 {
-		return true
-	}
-
-	// For email-only forms, we might want to be a bit more strict
-	// since email fields are more common in non-login contexts
-	if hasEmailField && indicatorCount >= 3 {
-		return true
-	}
-
-	return false
-}
-
-// countHeadings counts H1-H6 headings in HTML
-func (fs *FirecrawlService) countHeadings(html string, result *models.CrawlResult) {
-	// Count each heading level
-	for i := 1; i <= 6; i++ {
-		pattern := fmt.Sprintf(`(?i)<h%d[^>]*>`, i)
-		regex := regexp.MustCompile(pattern)
-		matches := regex.FindAllString(html, -1)
-		count := len(matches)
-
-		switch i {
-		case 1:
-			result.HeadingCounts.H1 = count
-		case 2:
-			result.HeadingCounts.H2 = count
-		case 3:
-			result.HeadingCounts.H3 = count
-		case 4:
-			result.HeadingCounts.H4 = count
-		case 5:
-			result.HeadingCounts.H5 = count
-		case 6:
-			result.HeadingCounts.H6 = count
-		}
-	}
-
-	log.Printf("Heading counts: H1=%d, H2=%d, H3=%d, H4=%d, H5=%d, H6=%d",
-		result.HeadingCounts.H1, result.HeadingCounts.H2, result.HeadingCounts.H3,
-		result.HeadingCounts.H4, result.HeadingCounts.H5, result.HeadingCounts.H6)
-}
-
-// analyzeLinks analyzes links in the HTML content
-func (fs *FirecrawlService) analyzeLinks(html string, result *models.CrawlResult) {
-	// Simple link counting for now
-	// In a production environment, you'd want more sophisticated link analysis
-
-	// Count internal vs external links
-	linkRegex := regexp.MustCompile(`(?i)href=["']([^"']+)["']`)
-	matches := linkRegex.FindAllStringSubmatch(html, -1)
-
-	log.Printf("Link analysis: Found %d total link matches", len(matches))
-
-	internalCount := 0
-	externalCount := 0
-	var externalLinks []string
-
-	for _, match := range matches {
-		if len(match) > 1 {
-			href := match[1]
-			// Clean up and validate the href
-			href = strings.TrimSpace(href)
-
-			if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
-				// This is an external link
-				externalCount++
-				externalLinks = append(externalLinks, href)
-			} else if strings.HasPrefix(href, "/") || strings.HasPrefix(href, "#") || !strings.Contains(href, "://") {
-				// This is an internal link (relative paths, anchors, or no protocol)
-				internalCount++
-			}
-		}
-	}
-
-	result.InternalLinksCount = internalCount
-	result.ExternalLinksCount = externalCount
-	result.ExternalLinks = models.ExternalLinks(externalLinks)
-
-	log.Printf("Link analysis: Internal=%d, External=%d, External URLs captured=%d",
-		internalCount, externalCount, len(externalLinks))
-
-	// Log first few external links for debugging (limit to avoid spam)
-	if len(externalLinks) > 0 {
-		maxToShow := 5
-		if len(externalLinks) < maxToShow {
-			maxToShow = len(externalLinks)
-		}
-		log.Printf("Sample external links: %v", externalLinks[:maxToShow])
+// pageStatusError returns an HTTPStatusError when Firecrawl reports that the
+// page itself answered with an error status
+func pageStatusError(doc *firecrawl.FirecrawlDocument) *HTTPStatusError {
+	if doc == nil || doc.Metadata == nil || doc.Metadata.StatusCode == nil || *doc.Metadata.StatusCode < 400 {
+		return nil
 	}
-}
-
-// detectHTMLVersion detects HTML version from DOCTYPE or content
-func (fs *FirecrawlService) detectHTMLVersion(html string) string {
-	htmlUpper := strings.ToUpper(html)
 
-	if strings.Contains(htmlUpper, "<!DOCTYPE HTML>") {
-		return "HTML5"
-	}
-	if strings.Contains(htmlUpper, "HTML 4.01") {
-		return "HTML 4.01"
-	}
-	if strings.Contains(htmlUpper, "XHTML 1.0") {
-		return "XHTML 1.0"
+	code := *doc.Metadata.StatusCode
+	return &HTTPStatusError{
+		StatusCode: code,
+		Status:     strings.TrimSpace(fmt.Sprintf("%d %s", code, http.StatusText(code))),
 	}
-	if strings.Contains(htmlUpper, "XHTML 1.1") {
-		return "XHTML 1.1"
-	}
-
-	// Default assumption for modern websites
-	return "HTML5"
 }
 
 // extractFirecrawlMetadata extracts additional metadata from Firecrawl document metadata
 func (fs *FirecrawlService) extractFirecrawlMetadata(metadata *firecrawl.FirecrawlDocumentMetadata, result *models.CrawlResult) {
-	if metadata.StatusCode != nil && *metadata.StatusCode >= 400 {
-		log.Printf("Warning: HTTP status code %d for URL: %s", *metadata.StatusCode, result.URL)
-	}
-
 	if metadata.Description != nil && *metadata.Description != "" {
 		log.Printf("Page description: %s", *metadata.Description)
 	}
 }
 
 // ValidateURL validates the URL format and content
-func (fs *FirecrawlService) ValidateURL(targetURL string) error {
-	if targetURL == "" {
-		return fmt.Errorf("URL cannot be empty")
-	}
-
-	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
-		return fmt.Errorf("URL must start with http:// or https://")
-	}
-
-	// Basic validation for malicious patterns
-	maliciousPatterns := []string{
-		"javascript:",
-		"data:",
-		"file:",
-		"ftp:",
-	}
-
-	lowerURL := strings.ToLower(targetURL)
-	for _, pattern := range maliciousPatterns {
-		if strings.Contains(lowerURL, pattern) {
-			return fmt.Errorf("potentially malicious URL pattern detected")
-		}
-	}
-
-	return nil
+func (fs *FirecrawlService) ValidateURL(ctx context.Context, targetURL string) error {
+	return fs.validator.Validate(ctx, targetURL)
 }
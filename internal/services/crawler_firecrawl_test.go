@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+func TestFirecrawlFailsOnPageErrorStatus(t *testing.T) {
+	pageStatus := http.StatusNotFound
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"success":true,"data":{"html":"<title>Page</title>","metadata":{"statusCode":%d}}}`, pageStatus)
+	}))
+	defer api.Close()
+
+	crawler := NewFirecrawlService(config.CrawlerConfig{
+		Timeout:         5 * time.Second,
+		FirecrawlAPIKey: "fc-test",
+		FirecrawlAPIURL: api.URL,
+	})
+
+	tests := []struct {
+		status    int
+		transient bool
+	}{
+		{status: http.StatusNotFound, transient: false},
+		{status: http.StatusServiceUnavailable, transient: true},
+	}
+
+	for _, tt := range tests {
+		pageStatus = tt.status
+		result, err := crawler.AnalyzeURL(context.Background(), "https://example.com/page")
+
+		var statusErr *HTTPStatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status {
+			t.Fatalf("AnalyzeURL() with page status %d error = %v, expected an HTTPStatusError", tt.status, err)
+		}
+		if isTransient(err) != tt.transient {
+			t.Errorf("isTransient() for page status %d = %v, expected %v", tt.status, !tt.transient, tt.transient)
+		}
+		if result.Status != models.CrawlStatusError {
+			t.Errorf("result status for page status %d = %s, expected error", tt.status, result.Status)
+		}
+	}
+}
+
+func TestFirecrawlClassifiesLinksAgainstFinalURL(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"success":true,"data":{`+
+			`"html":"<title>Page</title><a href=\"/next\">Next</a><a href=\"https://start.example/old\">Old</a>",`+
+			`"metadata":{"statusCode":200,"sourceURL":"https://final.example/page"}}}`)
+	}))
+	defer api.Close()
+
+	// Only the API is reachable, so the link check rejects every link without a lookup
+	crawler := NewFirecrawlService(config.CrawlerConfig{
+		Timeout:         5 * time.Second,
+		FirecrawlAPIKey: "fc-test",
+		FirecrawlAPIURL: api.URL,
+		AllowedDomains:  []string{"allowed.example"},
+	})
+
+	result, err := crawler.AnalyzeURL(context.Background(), "https://start.example/page")
+	if err != nil {
+		t.Fatalf("AnalyzeURL() error = %v", err)
+	}
+	if result.InternalLinksCount != 1 || result.ExternalLinksCount != 1 {
+		t.Errorf("internal %d, external %d links, expected 1 and 1", result.InternalLinksCount, result.ExternalLinksCount)
+	}
+	if len(result.ExternalLinks) != 1 || result.ExternalLinks[0] != "https://start.example/old" {
+		t.Errorf("external links = %v, expected the link back to the requested host", result.ExternalLinks)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// HTTPCrawlerService implements the crawler interface by fetching pages directly with net/http
+type HTTPCrawlerService struct {
+	client         *http.Client
+	userAgent      string
+	maxContentSize int64
+	subdomains     bool
+	validator      *URLValidator
+	linkChecker    *LinkChecker
+}
+
+// NewHTTPCrawlerService creates a new net/http based crawler service using configuration
+func NewHTTPCrawlerService(cfg config.CrawlerConfig) *HTTPCrawlerService {
+	validator := NewURLValidator(cfg)
+
+	log.Printf("HTTP crawler service initialized (timeout: %s, max redirects: %d)", cfg.Timeout, cfg.MaxRedirects)
+	return &HTTPCrawlerService{
+		client:         newPolicyHTTPClient(cfg, validator),
+		userAgent:      cfg.UserAgent,
+		maxContentSize: cfg.MaxContentSize,
+		subdomains:     cfg.SubdomainsInternal,
+		validator:      validator,
+		linkChecker:    NewLinkChecker(cfg, validator),
+	}
+}
+
+// AnalyzeURL fetches the page over HTTP and analyzes its HTML
+func (hs *HTTPCrawlerService) AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error) {
+	// Initialize result
+	result := &models.CrawlResult{
+		ID:            uuid.New().String(),
+		URL:           targetURL,
+		Status:        models.CrawlStatusRunning,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		HeadingCounts: models.HeadingCounts{},
+		BrokenLinks:   models.BrokenLinks{},
+		ExternalLinks: models.ExternalLinks{},
+	}
+
+	log.Printf("Starting HTTP analysis for URL: %s", targetURL)
+
+	reportPhase(ctx, models.CrawlPhaseFetching)
+	body, finalURL, err := hs.fetchPage(ctx, targetURL)
+	if err != nil {
+		result.Status = models.CrawlStatusError
+		errorMsg := err.Error()
+		result.ErrorMessage = &errorMsg
+		return result, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+
+	reportPhase(ctx, models.CrawlPhaseAnalyzing)
+	analysis, err := AnalyzeHTML(strings.NewReader(body))
+	if err != nil {
+		result.Status = models.CrawlStatusError
+		errorMsg := err.Error()
+		result.ErrorMessage = &errorMsg
+		return result, err
+	}
+	// Links are relative to where redirects ended up, not the submitted URL
+	analysis.applyTo(result, finalURL, hs.subdomains)
+
+	// Verify the extracted links
+	reportPhase(ctx, models.CrawlPhaseCheckingLinks)
+	result.BrokenLinks, result.CheckedLinks = hs.linkChecker.CheckLinks(ctx, analysis.BaseURL(finalURL), analysis.Links)
+	result.InaccessibleLinksCount = len(result.BrokenLinks)
+
+	// A partial link check must not be reported as a completed analysis
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Set completion status
+	result.Status = models.CrawlStatusCompleted
+	result.UpdatedAt = time.Now()
+
+	log.Printf("HTTP analysis completed for URL: %s", targetURL)
+	return result, nil
+}
+
+// fetchPage downloads the HTML body of the given URL, enforcing the content size limit.
+// It returns the body and the final URL after redirects.
+func (hs *HTTPCrawlerService) fetchPage(ctx context.Context, targetURL string) (string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("User-Agent", hs.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", "", newHTTPStatusError(resp)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err == nil && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return "", "", fmt.Errorf("unsupported content type: %s", mediaType)
+		}
+	}
+
+	if hs.maxContentSize > 0 && resp.ContentLength > hs.maxContentSize {
+		return "", "", fmt.Errorf("content length %d exceeds maximum of %d bytes", resp.ContentLength, hs.maxContentSize)
+	}
+
+	reader := io.Reader(resp.Body)
+	if hs.maxContentSize > 0 {
+		// Read one extra byte so oversized bodies without Content-Length can be detected
+		reader = io.LimitReader(resp.Body, hs.maxContentSize+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if hs.maxContentSize > 0 && int64(len(body)) > hs.maxContentSize {
+		return "", "", fmt.Errorf("content exceeds maximum of %d bytes", hs.maxContentSize)
+	}
+
+	return string(body), resp.Request.URL.String(), nil
+}
+
+// ValidateURL validates the URL format and content
+func (hs *HTTPCrawlerService) ValidateURL(ctx context.Context, targetURL string) error {
+	return hs.validator.Validate(ctx, targetURL)
+}
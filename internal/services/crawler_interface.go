@@ -1,12 +1,17 @@
 package services
 
-import "url-crawler/internal/models"
+import (
+	"context"
+
+	"url-crawler/internal/models"
+)
 
 // Crawler interface
 type Crawler interface {
-	// AnalyzeURL performs comprehensive analysis of the given URL
-	AnalyzeURL(targetURL string) (*models.CrawlResult, error)
+	// AnalyzeURL performs comprehensive analysis of the given URL.
+	// It must return promptly with ctx.Err() once ctx is cancelled or its deadline passes.
+	AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error)
 
-	// ValidateURL validates URL format before crawling
-	ValidateURL(targetURL string) error
+	// ValidateURL validates URL format and crawl policies before crawling
+	ValidateURL(ctx context.Context, targetURL string) error
 }
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCronRejectsInvalidExpressions(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@fortnightly",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseCron(expr); !errors.Is(err, ErrInvalidCron) {
+				t.Errorf("ParseCron(%q) error = %v, expected ErrInvalidCron", expr, err)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	utc := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatalf("bad test time %q: %v", value, err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		after    string
+		expected string
+	}{
+		{"every minute", "* * * * *", "2025-03-10 08:15", "2025-03-10 08:16"},
+		{"daily at 9", "0 9 * * *", "2025-03-10 08:15", "2025-03-10 09:00"},
+		{"daily at 9 already passed", "0 9 * * *", "2025-03-10 09:00", "2025-03-11 09:00"},
+		{"every 15 minutes", "*/15 * * * *", "2025-03-10 08:15", "2025-03-10 08:30"},
+		{"range with step", "0-30/10 8 * * *", "2025-03-10 08:21", "2025-03-10 08:30"},
+		{"list", "0 6,18 * * *", "2025-03-10 08:15", "2025-03-10 18:00"},
+		{"weekdays by name", "0 9 * * MON-FRI", "2025-03-14 10:00", "2025-03-17 09:00"},
+		{"sunday as 7", "0 0 * * 7", "2025-03-10 08:15", "2025-03-16 00:00"},
+		{"month rollover", "0 0 1 * *", "2025-03-10 08:15", "2025-04-01 00:00"},
+		{"year rollover", "@yearly", "2025-03-10 08:15", "2026-01-01 00:00"},
+		{"leap day", "0 0 29 FEB *", "2025-03-10 08:15", "2028-02-29 00:00"},
+		{"day of month or weekday", "0 0 13 * FRI", "2025-03-10 08:15", "2025-03-13 00:00"},
+		{"stepped day of month and weekday", "0 0 */2 * MON", "2025-03-10 08:15", "2025-03-17 00:00"},
+		{"day of month and stepped weekday", "0 0 15 * */2", "2025-03-10 08:15", "2025-03-15 00:00"},
+		{"hourly", "@hourly", "2025-03-10 08:15", "2025-03-10 09:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+			}
+			if got := cron.Next(utc(tt.after)); !got.Equal(utc(tt.expected)) {
+				t.Errorf("Next(%s) = %s, expected %s", tt.after, got.Format("2006-01-02 15:04"), tt.expected)
+			}
+		})
+	}
+}
+
+func TestCronNextNeverMatches(t *testing.T) {
+	cron, err := ParseCron("0 0 30 FEB *")
+	if err != nil {
+		t.Fatalf("ParseCron() error = %v", err)
+	}
+	if next := cron.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Next() = %s, expected zero time", next)
+	}
+}
+
+func TestCronNextInTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	cron, err := ParseCron("0 9 * * *")
+	if err != nil {
+		t.Fatalf("ParseCron() error = %v", err)
+	}
+
+	// 9:00 in New York is 13:00 UTC during daylight saving time and 14:00 outside it
+	summer := cron.Next(time.Date(2025, 7, 1, 0, 0, 0, 0, loc))
+	if expected := time.Date(2025, 7, 1, 13, 0, 0, 0, time.UTC); !summer.Equal(expected) {
+		t.Errorf("summer Next() = %s, expected %s", summer.UTC(), expected)
+	}
+	winter := cron.Next(time.Date(2025, 1, 1, 0, 0, 0, 0, loc))
+	if expected := time.Date(2025, 1, 1, 14, 0, 0, 0, time.UTC); !winter.Equal(expected) {
+		t.Errorf("winter Next() = %s, expected %s", winter.UTC(), expected)
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"errors"
+
+	"url-crawler/internal/models"
+)
+
+// ErrCrawlNotCompleted is returned when a compared crawl has no completed analysis
+var ErrCrawlNotCompleted = errors.New("crawl has not completed")
+
+// DiffCrawlResults compares two completed crawl results, reporting the changes from a to b
+func DiffCrawlResults(a, b *models.CrawlResult) (*models.CrawlDiff, error) {
+	if a.Status != models.CrawlStatusCompleted || b.Status != models.CrawlStatusCompleted {
+		return nil, ErrCrawlNotCompleted
+	}
+
+	diff := &models.CrawlDiff{
+		A: diffSide(a),
+		B: diffSide(b),
+		HeadingDeltas: models.HeadingCounts{
+			H1: b.HeadingCounts.H1 - a.HeadingCounts.H1,
+			H2: b.HeadingCounts.H2 - a.HeadingCounts.H2,
+			H3: b.HeadingCounts.H3 - a.HeadingCounts.H3,
+			H4: b.HeadingCounts.H4 - a.HeadingCounts.H4,
+			H5: b.HeadingCounts.H5 - a.HeadingCounts.H5,
+			H6: b.HeadingCounts.H6 - a.HeadingCounts.H6,
+		},
+		LinkDeltas: models.LinkCountDeltas{
+			Internal:     b.InternalLinksCount - a.InternalLinksCount,
+			External:     b.ExternalLinksCount - a.ExternalLinksCount,
+			Other:        b.OtherLinksCount - a.OtherLinksCount,
+			Inaccessible: b.InaccessibleLinksCount - a.InaccessibleLinksCount,
+		},
+	}
+
+	if a.Title != b.Title {
+		diff.Title = &models.StringChange{From: a.Title, To: b.Title}
+	}
+	if a.HTMLVersion != b.HTMLVersion {
+		diff.HTMLVersion = &models.StringChange{From: a.HTMLVersion, To: b.HTMLVersion}
+	}
+	if a.HasLoginForm != b.HasLoginForm {
+		diff.LoginForm = &models.BoolChange{From: a.HasLoginForm, To: b.HasLoginForm}
+	}
+
+	diff.ExternalLinksAdded = missingFrom(b.ExternalLinks, a.ExternalLinks)
+	diff.ExternalLinksRemoved = missingFrom(a.ExternalLinks, b.ExternalLinks)
+	diff.NewlyBrokenLinks = brokenMissingFrom(b.BrokenLinks, a.BrokenLinks)
+	// A link only counts as fixed if b checked it; links past b's link check cut-off are unknown
+	diff.FixedLinks = checkedIn(brokenMissingFrom(a.BrokenLinks, b.BrokenLinks), b.CheckedLinks)
+
+	diff.Changed = diff.Title != nil || diff.HTMLVersion != nil || diff.LoginForm != nil ||
+		diff.HeadingDeltas != (models.HeadingCounts{}) || diff.LinkDeltas != (models.LinkCountDeltas{}) ||
+		len(diff.ExternalLinksAdded) > 0 || len(diff.ExternalLinksRemoved) > 0 ||
+		len(diff.NewlyBrokenLinks) > 0 || len(diff.FixedLinks) > 0
+
+	return diff, nil
+}
+
+// diffSide describes a compared crawl result
+func diffSide(result *models.CrawlResult) models.CrawlDiffSide {
+	return models.CrawlDiffSide{
+		ID:        result.ID,
+		URL:       result.URL,
+		Status:    result.Status,
+		CrawledAt: result.UpdatedAt,
+	}
+}
+
+// missingFrom returns the links of from that are not in other, in order and without duplicates
+func missingFrom(from, other []string) []string {
+	present := make(map[string]bool, len(other))
+	for _, link := range other {
+		present[link] = true
+	}
+
+	missing := []string{}
+	for _, link := range from {
+		if !present[link] {
+			missing = append(missing, link)
+			present[link] = true
+		}
+	}
+	return missing
+}
+
+// brokenMissingFrom returns the broken links of from whose URL is not broken in other
+func brokenMissingFrom(from, other models.BrokenLinks) []models.BrokenLink {
+	present := make(map[string]bool, len(other))
+	for _, link := range other {
+		present[link.URL] = true
+	}
+
+	missing := []models.BrokenLink{}
+	for _, link := range from {
+		if !present[link.URL] {
+			missing = append(missing, link)
+			present[link.URL] = true
+		}
+	}
+	return missing
+}
+
+// checkedIn returns the broken links whose URL is among the checked links
+func checkedIn(links []models.BrokenLink, checked models.CheckedLinks) []models.BrokenLink {
+	present := make(map[string]bool, len(checked))
+	for _, link := range checked {
+		present[link] = true
+	}
+
+	found := []models.BrokenLink{}
+	for _, link := range links {
+		if present[link.URL] {
+			found = append(found, link)
+		}
+	}
+	return found
+}
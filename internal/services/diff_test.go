@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"url-crawler/internal/models"
+)
+
+func TestDiffCrawlResults(t *testing.T) {
+	a := &models.CrawlResult{
+		ID:                 "a",
+		Status:             models.CrawlStatusCompleted,
+		Title:              "Shop",
+		HTMLVersion:        "HTML5",
+		InternalLinksCount: 10,
+		ExternalLinksCount: 2,
+		HeadingCounts:      models.HeadingCounts{H1: 1, H2: 4},
+		ExternalLinks:      models.ExternalLinks{"https://cdn.example.net/", "https://old-partner.example.org/"},
+		BrokenLinks: models.BrokenLinks{
+			{URL: "https://shop.example.com/sale", StatusCode: 404, StatusText: "Not Found"},
+			{URL: "https://shop.example.com/cart", StatusCode: 500, StatusText: "Internal Server Error"},
+			{URL: "https://shop.example.com/gift-cards", StatusCode: 404, StatusText: "Not Found"},
+		},
+	}
+	b := &models.CrawlResult{
+		ID:                 "b",
+		Status:             models.CrawlStatusCompleted,
+		Title:              "Shop - Spring Sale",
+		HTMLVersion:        "HTML5",
+		InternalLinksCount: 12,
+		ExternalLinksCount: 2,
+		HasLoginForm:       true,
+		HeadingCounts:      models.HeadingCounts{H1: 1, H2: 3, H3: 2},
+		ExternalLinks:      models.ExternalLinks{"https://cdn.example.net/", "https://new-partner.example.org/"},
+		BrokenLinks: models.BrokenLinks{
+			{URL: "https://shop.example.com/cart", StatusCode: 503, StatusText: "Service Unavailable"},
+			{URL: "https://shop.example.com/account", StatusCode: 404, StatusText: "Not Found"},
+		},
+		// The link check cut-off was reached before /gift-cards
+		CheckedLinks: models.CheckedLinks{
+			"https://shop.example.com/sale",
+			"https://shop.example.com/cart",
+			"https://shop.example.com/account",
+		},
+	}
+
+	diff, err := DiffCrawlResults(a, b)
+	if err != nil {
+		t.Fatalf("DiffCrawlResults() error = %v", err)
+	}
+
+	if !diff.Changed {
+		t.Error("Changed = false, expected true")
+	}
+	if diff.Title == nil || diff.Title.From != "Shop" || diff.Title.To != "Shop - Spring Sale" {
+		t.Errorf("Title = %+v, expected Shop -> Shop - Spring Sale", diff.Title)
+	}
+	if diff.HTMLVersion != nil {
+		t.Errorf("HTMLVersion = %+v, expected no change", diff.HTMLVersion)
+	}
+	if diff.LoginForm == nil || diff.LoginForm.From || !diff.LoginForm.To {
+		t.Errorf("LoginForm = %+v, expected false -> true", diff.LoginForm)
+	}
+	if expected := (models.HeadingCounts{H2: -1, H3: 2}); diff.HeadingDeltas != expected {
+		t.Errorf("HeadingDeltas = %+v, expected %+v", diff.HeadingDeltas, expected)
+	}
+	if expected := (models.LinkCountDeltas{Internal: 2}); diff.LinkDeltas != expected {
+		t.Errorf("LinkDeltas = %+v, expected %+v", diff.LinkDeltas, expected)
+	}
+	if expected := []string{"https://new-partner.example.org/"}; !reflect.DeepEqual(diff.ExternalLinksAdded, expected) {
+		t.Errorf("ExternalLinksAdded = %v, expected %v", diff.ExternalLinksAdded, expected)
+	}
+	if expected := []string{"https://old-partner.example.org/"}; !reflect.DeepEqual(diff.ExternalLinksRemoved, expected) {
+		t.Errorf("ExternalLinksRemoved = %v, expected %v", diff.ExternalLinksRemoved, expected)
+	}
+
+	// A link broken in both crawls is neither newly broken nor fixed
+	if len(diff.NewlyBrokenLinks) != 1 || diff.NewlyBrokenLinks[0].URL != "https://shop.example.com/account" {
+		t.Errorf("NewlyBrokenLinks = %+v, expected only /account", diff.NewlyBrokenLinks)
+	}
+	// A link b did not check is not known to be fixed
+	if len(diff.FixedLinks) != 1 || diff.FixedLinks[0].URL != "https://shop.example.com/sale" {
+		t.Errorf("FixedLinks = %+v, expected only /sale", diff.FixedLinks)
+	}
+}
+
+func TestDiffCrawlResultsUnchanged(t *testing.T) {
+	result := &models.CrawlResult{
+		Status:        models.CrawlStatusCompleted,
+		Title:         "Home",
+		HeadingCounts: models.HeadingCounts{H1: 1},
+		ExternalLinks: models.ExternalLinks{"https://example.org/"},
+		BrokenLinks:   models.BrokenLinks{{URL: "https://example.com/missing", StatusCode: 404}},
+	}
+
+	diff, err := DiffCrawlResults(result, result)
+	if err != nil {
+		t.Fatalf("DiffCrawlResults() error = %v", err)
+	}
+
+	if diff.Changed {
+		t.Errorf("Changed = true for identical results: %+v", diff)
+	}
+	if diff.Title != nil || diff.LoginForm != nil {
+		t.Errorf("unexpected field changes: title %+v, login form %+v", diff.Title, diff.LoginForm)
+	}
+	if len(diff.ExternalLinksAdded)+len(diff.ExternalLinksRemoved)+len(diff.NewlyBrokenLinks)+len(diff.FixedLinks) != 0 {
+		t.Errorf("unexpected link changes: %+v", diff)
+	}
+}
+
+func TestDiffCrawlResultsRequiresCompletedCrawls(t *testing.T) {
+	completed := &models.CrawlResult{ID: "done", Status: models.CrawlStatusCompleted}
+
+	for _, status := range []models.CrawlStatus{models.CrawlStatusQueued, models.CrawlStatusRunning, models.CrawlStatusError} {
+		other := &models.CrawlResult{ID: "other", Status: status}
+		if _, err := DiffCrawlResults(completed, other); !errors.Is(err, ErrCrawlNotCompleted) {
+			t.Errorf("DiffCrawlResults() with b %s error = %v, expected ErrCrawlNotCompleted", status, err)
+		}
+		if _, err := DiffCrawlResults(other, completed); !errors.Is(err, ErrCrawlNotCompleted) {
+			t.Errorf("DiffCrawlResults() with a %s error = %v, expected ErrCrawlNotCompleted", status, err)
+		}
+	}
+}
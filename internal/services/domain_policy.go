@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// ErrDomainNotAllowed is returned when a URL's host is rejected by the domain policy
+var ErrDomainNotAllowed = errors.New("domain not allowed by crawler policy")
+
+// DomainPolicy enforces the configured allowed and blocked domain lists.
+//
+// Supported patterns:
+//   - "example.com"       matches exactly example.com on any port
+//   - "*.example.com"     matches any subdomain of example.com, but not example.com itself
+//   - ".example.com"      matches example.com and all of its subdomains
+//   - "example.com:8080"  any of the above restricted to a specific port
+type DomainPolicy struct {
+	allowed []domainPattern
+	blocked []domainPattern
+}
+
+// domainPattern is a parsed allowed/blocked domain entry
+type domainPattern struct {
+	host     string
+	port     string // empty matches any port
+	wildcard bool   // subdomains only
+	suffix   bool   // the domain itself and its subdomains
+}
+
+// NewDomainPolicy creates a new domain policy from the allowed and blocked lists
+func NewDomainPolicy(allowed, blocked []string) *DomainPolicy {
+	return &DomainPolicy{
+		allowed: parseDomainPatterns(allowed),
+		blocked: parseDomainPatterns(blocked),
+	}
+}
+
+// Check returns ErrDomainNotAllowed if the URL's host is blocked or not on the allowlist
+func (dp *DomainPolicy) Check(targetURL string) error {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	port := u.Port()
+	if port == "" {
+		port = defaultPort(u.Scheme)
+	}
+
+	for _, pattern := range dp.blocked {
+		if pattern.matches(host, port) {
+			return fmt.Errorf("%w: %s is blocked", ErrDomainNotAllowed, u.Host)
+		}
+	}
+
+	if len(dp.allowed) == 0 {
+		return nil
+	}
+
+	for _, pattern := range dp.allowed {
+		if pattern.matches(host, port) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s is not in the allowed domains", ErrDomainNotAllowed, u.Host)
+}
+
+// matches reports whether the host and port satisfy the pattern
+func (p domainPattern) matches(host, port string) bool {
+	if p.port != "" && p.port != port {
+		return false
+	}
+
+	switch {
+	case p.wildcard:
+		return strings.HasSuffix(host, "."+p.host)
+	case p.suffix:
+		return host == p.host || strings.HasSuffix(host, "."+p.host)
+	default:
+		return host == p.host
+	}
+}
+
+// parseDomainPatterns converts configured domain strings into patterns
+func parseDomainPatterns(domains []string) []domainPattern {
+	var patterns []domainPattern
+
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" {
+			continue
+		}
+
+		pattern := domainPattern{}
+
+		if host, port, err := net.SplitHostPort(domain); err == nil {
+			domain = host
+			pattern.port = port
+		}
+
+		switch {
+		case strings.HasPrefix(domain, "*."):
+			pattern.wildcard = true
+			domain = strings.TrimPrefix(domain, "*.")
+		case strings.HasPrefix(domain, "."):
+			pattern.suffix = true
+			domain = strings.TrimPrefix(domain, ".")
+		}
+
+		pattern.host = strings.TrimSuffix(domain, ".")
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns
+}
+
+// defaultPort returns the implicit port for a URL scheme
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "https":
+		return "443"
+	case "http":
+		return "80"
+	default:
+		return ""
+	}
+}
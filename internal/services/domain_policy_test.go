@@ -0,0 +1,53 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDomainPolicy(t *testing.T) {
+	policy := NewDomainPolicy(
+		[]string{"example.com", "*.example.org", ".example.net", "internal.test:8443"},
+		[]string{"blocked.example.org", "example.com:8080"},
+	)
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/page", true},
+		{"https://EXAMPLE.com./page", true},
+		{"https://www.example.com/", false},
+		{"http://example.com:8080/", false},
+		{"https://api.example.org/", true},
+		{"https://example.org/", false},
+		{"https://blocked.example.org/", false},
+		{"https://example.net/", true},
+		{"https://deep.sub.example.net/", true},
+		{"https://notexample.net/", false},
+		{"https://internal.test:8443/", true},
+		{"https://internal.test/", false},
+		{"https://other.com/", false},
+	}
+
+	for _, tt := range tests {
+		err := policy.Check(tt.url)
+		if tt.allowed && err != nil {
+			t.Errorf("Check(%q) returned %v, expected allowed", tt.url, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrDomainNotAllowed) {
+			t.Errorf("Check(%q) returned %v, expected ErrDomainNotAllowed", tt.url, err)
+		}
+	}
+}
+
+func TestDomainPolicyBlockedOnly(t *testing.T) {
+	policy := NewDomainPolicy(nil, []string{"*.ads.example"})
+
+	if err := policy.Check("https://anything.com/"); err != nil {
+		t.Errorf("expected unlisted domain to be allowed, got %v", err)
+	}
+	if err := policy.Check("https://tracker.ads.example/"); !errors.Is(err, ErrDomainNotAllowed) {
+		t.Errorf("expected blocked subdomain to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+const (
+	// Defaults used when the events configuration leaves a value unset
+	defaultEventRetention    = time.Hour
+	defaultEventPollInterval = time.Second
+
+	// eventBatchSize caps how many events a stream reads from storage at once
+	eventBatchSize = 100
+
+	// eventKeepAlive is how often an idle stream is sent a keep-alive so proxies do not close it
+	eventKeepAlive = 15 * time.Second
+)
+
+// CrawlEventStore persists the log of recent crawl state changes
+type CrawlEventStore interface {
+	SaveCrawlEvent(ctx context.Context, event *models.CrawlEvent) error
+	GetCrawlEvents(ctx context.Context, filter models.CrawlEventFilter, afterID int64, limit int) ([]models.CrawlEvent, error)
+	GetLatestCrawlEventID(ctx context.Context) (int64, error)
+	PruneCrawlEvents(ctx context.Context, maxAge time.Duration) (int, error)
+}
+
+// CrawlEventPublisher is told about every state change of a crawl
+type CrawlEventPublisher interface {
+	PublishCrawlEvent(ctx context.Context, event *models.CrawlEvent)
+}
+
+// CrawlEvents records crawl state changes and streams them to subscribers.
+// Events are kept in a shared store, so streams see changes made by worker
+// processes too: local events wake streams immediately, others are polled.
+type CrawlEvents struct {
+	storage      CrawlEventStore
+	retention    time.Duration
+	pollInterval time.Duration
+	mu           sync.Mutex
+	subscribers  map[chan struct{}]struct{}
+	lastPrune    time.Time
+	closed       chan struct{}
+	closeOnce    sync.Once
+}
+
+// NewCrawlEvents creates a crawl event log backed by the given store
+func NewCrawlEvents(cfg config.EventsConfig, storage CrawlEventStore) *CrawlEvents {
+	events := &CrawlEvents{
+		storage:      storage,
+		retention:    cfg.Retention,
+		pollInterval: cfg.PollInterval,
+		subscribers:  make(map[chan struct{}]struct{}),
+		lastPrune:    time.Now(),
+		closed:       make(chan struct{}),
+	}
+	if events.retention <= 0 {
+		events.retention = defaultEventRetention
+	}
+	if events.pollInterval <= 0 {
+		events.pollInterval = defaultEventPollInterval
+	}
+	return events
+}
+
+// PublishCrawlEvent records an event and wakes the streams on this instance
+func (e *CrawlEvents) PublishCrawlEvent(ctx context.Context, event *models.CrawlEvent) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if err := e.storage.SaveCrawlEvent(ctx, event); err != nil {
+		log.Printf("Failed to record %s event for crawl %s: %v", event.Type, event.CrawlID, err)
+		return
+	}
+
+	e.mu.Lock()
+	for wake := range e.subscribers {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	prune := time.Since(e.lastPrune) >= e.retention/10
+	if prune {
+		e.lastPrune = time.Now()
+	}
+	e.mu.Unlock()
+
+	// Old events are dropped now and then, by whichever instance is publishing
+	if prune {
+		if _, err := e.storage.PruneCrawlEvents(ctx, e.retention); err != nil {
+			log.Printf("Failed to prune crawl events: %v", err)
+		}
+	}
+}
+
+// Close ends every open stream, so that clients reconnect to another instance
+// while this one shuts down
+func (e *CrawlEvents) Close() {
+	e.closeOnce.Do(func() {
+		close(e.closed)
+	})
+}
+
+// subscribe returns a channel that is signalled whenever an event is published
+// on this instance, and a function that cancels the subscription
+func (e *CrawlEvents) subscribe() (<-chan struct{}, func()) {
+	wake := make(chan struct{}, 1)
+
+	e.mu.Lock()
+	e.subscribers[wake] = struct{}{}
+	e.mu.Unlock()
+
+	return wake, func() {
+		e.mu.Lock()
+		delete(e.subscribers, wake)
+		e.mu.Unlock()
+	}
+}
+
+// LatestID returns the ID of the most recent event, where a stream that should
+// only see new events starts
+func (e *CrawlEvents) LatestID(ctx context.Context) (int64, error) {
+	return e.storage.GetLatestCrawlEventID(ctx)
+}
+
+// Stream sends the events matching filter that follow afterID to emit, in order,
+// until ctx is done, emit fails or the event log is closed. keepAlive is called
+// while the stream is idle.
+func (e *CrawlEvents) Stream(ctx context.Context, filter models.CrawlEventFilter, afterID int64, emit func(models.CrawlEvent) error, keepAlive func() error) error {
+	wake, unsubscribe := e.subscribe()
+	defer unsubscribe()
+
+	poll := time.NewTicker(e.pollInterval)
+	defer poll.Stop()
+	idle := time.NewTicker(eventKeepAlive)
+	defer idle.Stop()
+
+	for {
+		for {
+			events, err := e.storage.GetCrawlEvents(ctx, filter, afterID, eventBatchSize)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				log.Printf("Failed to read crawl events: %v", err)
+				break
+			}
+			for _, event := range events {
+				if err := emit(event); err != nil {
+					return err
+				}
+				afterID = event.ID
+			}
+			if len(events) < eventBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-wake:
+		case <-poll.C:
+		case <-idle.C:
+			if err := keepAlive(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.closed:
+			return nil
+		}
+	}
+}
+
+// phaseReporterKey is the context key of a crawl's phase reporter
+type phaseReporterKey struct{}
+
+// withPhaseReporter returns a context through which a crawler reports the phases of a crawl
+func withPhaseReporter(ctx context.Context, report func(models.CrawlPhase)) context.Context {
+	return context.WithValue(ctx, phaseReporterKey{}, report)
+}
+
+// reportPhase reports that a crawl has entered a new phase, if anyone is listening
+func reportPhase(ctx context.Context, phase models.CrawlPhase) {
+	if report, ok := ctx.Value(phaseReporterKey{}).(func(models.CrawlPhase)); ok {
+		report(phase)
+	}
+}
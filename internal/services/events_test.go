@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+// memoryEventStore is an in-memory CrawlEventStore for tests
+type memoryEventStore struct {
+	mu     sync.Mutex
+	events []models.CrawlEvent
+	owners map[string]string // crawl ID -> owner
+}
+
+func newMemoryEventStore() *memoryEventStore {
+	return &memoryEventStore{owners: make(map[string]string)}
+}
+
+func (m *memoryEventStore) SaveCrawlEvent(ctx context.Context, event *models.CrawlEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event.ID = int64(len(m.events) + 1)
+	m.events = append(m.events, *event)
+	return nil
+}
+
+func (m *memoryEventStore) GetCrawlEvents(ctx context.Context, filter models.CrawlEventFilter, afterID int64, limit int) ([]models.CrawlEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []models.CrawlEvent{}
+	for _, event := range m.events {
+		if event.ID <= afterID || len(events) >= limit {
+			continue
+		}
+		if filter.CrawlID != "" && event.CrawlID != filter.CrawlID {
+			continue
+		}
+		if filter.CrawlID == "" && m.owners[event.CrawlID] != filter.Owner {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (m *memoryEventStore) GetLatestCrawlEventID(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return int64(len(m.events)), nil
+}
+
+func (m *memoryEventStore) PruneCrawlEvents(ctx context.Context, maxAge time.Duration) (int, error) {
+	return 0, nil
+}
+
+// types returns the types of the recorded events of a crawl, in order
+func (m *memoryEventStore) types(crawlID string) []models.CrawlEventType {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	types := []models.CrawlEventType{}
+	for _, event := range m.events {
+		if event.CrawlID == crawlID {
+			types = append(types, event.Type)
+		}
+	}
+	return types
+}
+
+// collectEvents streams events into a channel until the returned function is called
+func collectEvents(events *CrawlEvents, filter models.CrawlEventFilter, afterID int64) (<-chan models.CrawlEvent, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan models.CrawlEvent, 100)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		events.Stream(ctx, filter, afterID, func(event models.CrawlEvent) error {
+			received <- event
+			return nil
+		}, func() error { return nil })
+	}()
+
+	return received, func() {
+		cancel()
+		<-done
+	}
+}
+
+// nextEvent waits for the next streamed event
+func nextEvent(t *testing.T, received <-chan models.CrawlEvent) models.CrawlEvent {
+	t.Helper()
+
+	select {
+	case event := <-received:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+		return models.CrawlEvent{}
+	}
+}
+
+func TestCrawlEventsStreamResumesAndFollows(t *testing.T) {
+	store := newMemoryEventStore()
+	store.owners["a"] = "team-a"
+	store.owners["b"] = "team-b"
+	// A long poll interval shows that local events wake the stream
+	events := NewCrawlEvents(config.EventsConfig{PollInterval: time.Hour}, store)
+	ctx := context.Background()
+
+	events.PublishCrawlEvent(ctx, &models.CrawlEvent{CrawlID: "a", Type: models.CrawlEventQueued, Status: models.CrawlStatusQueued})
+	events.PublishCrawlEvent(ctx, &models.CrawlEvent{CrawlID: "a", Type: models.CrawlEventRunning, Status: models.CrawlStatusRunning})
+
+	// Resuming after the first event skips it
+	received, stop := collectEvents(events, models.CrawlEventFilter{Owner: "team-a"}, 1)
+	defer stop()
+
+	if event := nextEvent(t, received); event.ID != 2 || event.Type != models.CrawlEventRunning {
+		t.Errorf("first streamed event = %+v, expected the running event", event)
+	}
+
+	events.PublishCrawlEvent(ctx, &models.CrawlEvent{CrawlID: "b", Type: models.CrawlEventQueued, Status: models.CrawlStatusQueued})
+	events.PublishCrawlEvent(ctx, &models.CrawlEvent{CrawlID: "a", Type: models.CrawlEventCompleted, Status: models.CrawlStatusCompleted})
+
+	// Another key's crawl is not streamed
+	if event := nextEvent(t, received); event.CrawlID != "a" || event.Type != models.CrawlEventCompleted {
+		t.Errorf("next streamed event = %+v, expected a's completion", event)
+	}
+}
+
+func TestCrawlEventsStreamStopsWhenEmitFails(t *testing.T) {
+	store := newMemoryEventStore()
+	events := NewCrawlEvents(config.EventsConfig{PollInterval: 10 * time.Millisecond}, store)
+	events.PublishCrawlEvent(context.Background(), &models.CrawlEvent{CrawlID: "a", Type: models.CrawlEventQueued})
+
+	errGone := errors.New("client went away")
+	err := events.Stream(context.Background(), models.CrawlEventFilter{CrawlID: "a"}, 0, func(models.CrawlEvent) error {
+		return errGone
+	}, func() error { return nil })
+
+	if !errors.Is(err, errGone) {
+		t.Errorf("Stream() error = %v, expected the emit error", err)
+	}
+}
+
+func TestCrawlEventsCloseEndsStreams(t *testing.T) {
+	events := NewCrawlEvents(config.EventsConfig{PollInterval: time.Hour}, newMemoryEventStore())
+
+	ended := make(chan error, 1)
+	go func() {
+		ended <- events.Stream(context.Background(), models.CrawlEventFilter{CrawlID: "a"}, 0, func(models.CrawlEvent) error {
+			return nil
+		}, func() error { return nil })
+	}()
+
+	events.Close()
+	select {
+	case err := <-ended:
+		if err != nil {
+			t.Errorf("Stream() error = %v, expected a clean end", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stream() did not end when the event log was closed")
+	}
+}
+
+// phasedCrawler reports the phases of the HTTP crawler without fetching anything
+type phasedCrawler struct{}
+
+func (phasedCrawler) AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error) {
+	reportPhase(ctx, models.CrawlPhaseFetching)
+	reportPhase(ctx, models.CrawlPhaseAnalyzing)
+	return &models.CrawlResult{URL: targetURL}, nil
+}
+
+func (phasedCrawler) ValidateURL(ctx context.Context, targetURL string) error {
+	return nil
+}
+
+func TestQueuePublishesCrawlLifecycle(t *testing.T) {
+	storage := newMemoryStorage()
+	store := newMemoryEventStore()
+	queue := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 10}, phasedCrawler{}, storage)
+	queue.SetEventPublisher(NewCrawlEvents(config.EventsConfig{}, store))
+	queue.Start()
+	defer queue.Stop()
+
+	result, err := queue.EnqueueURL(context.Background(), "https://example.com/", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+	waitForFinalStatus(t, queue, storage, result.ID)
+
+	expected := []models.CrawlEventType{
+		models.CrawlEventQueued,
+		models.CrawlEventRunning,
+		models.CrawlEventPhase, // fetching
+		models.CrawlEventPhase, // analyzing
+		models.CrawlEventPhase, // saving
+		models.CrawlEventCompleted,
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	types := store.types(result.ID)
+	for len(types) < len(expected) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		types = store.types(result.ID)
+	}
+
+	if len(types) != len(expected) {
+		t.Fatalf("events = %v, expected %v", types, expected)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Errorf("event %d = %s, expected %s (all: %v)", i, types[i], expected[i], types)
+		}
+	}
+}
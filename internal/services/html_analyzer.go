@@ -0,0 +1,389 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/publicsuffix"
+
+	"url-crawler/internal/models"
+)
+
+// HTMLAnalysis holds the page facts extracted from a parsed HTML document
+type HTMLAnalysis struct {
+	Title         string
+	BaseHref      string // href of the first <base> element, if any
+	HTMLVersion   string
+	HeadingCounts models.HeadingCounts
+	Links         []string // href values of <a> and <area> elements in document order
+	HasLoginForm  bool
+}
+
+// formStats accumulates the login signals found inside a single form
+type formStats struct {
+	passwordFields    int
+	newPasswordFields int
+	currentPassword   bool
+	identifierFields  int
+	hints             []string
+}
+
+// loginHints are words in a form's attributes or submit controls that indicate a sign-in form
+var loginHints = []string{"login", "log in", "log-in", "signin", "sign in", "sign-in", "authenticate", "session"}
+
+// signupHints are words that indicate account creation rather than sign-in
+var signupHints = []string{"signup", "sign up", "sign-up", "register", "create account", "join"}
+
+// AnalyzeHTML parses an HTML document and extracts the data stored in a CrawlResult.
+// Content inside comments, <script>, <style> and <template> is ignored.
+func AnalyzeHTML(r io.Reader) (*HTMLAnalysis, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	analysis := &HTMLAnalysis{
+		// Documents without a DOCTYPE are assumed to be modern HTML
+		HTMLVersion: "HTML5",
+	}
+
+	// Fields outside any <form> are grouped together so script-driven login
+	// widgets are detected; unrelated controls end up in the same group, so it
+	// only counts when it contains a password field
+	looseFields := &formStats{}
+	var forms []*formStats
+
+	var walk func(n *html.Node, form *formStats)
+	walk = func(n *html.Node, form *formStats) {
+		switch n.Type {
+		case html.DoctypeNode:
+			analysis.HTMLVersion = htmlVersionFromDoctype(n)
+		case html.ElementNode:
+			// Only HTML elements count; <title> inside <svg> is not the page title
+			if n.Namespace != "" {
+				return
+			}
+
+			switch n.DataAtom {
+			case atom.Template, atom.Script, atom.Style:
+				return
+			case atom.Title:
+				if analysis.Title == "" {
+					analysis.Title = strings.TrimSpace(textContent(n))
+				}
+			case atom.Base:
+				if href, ok := attr(n, "href"); ok && analysis.BaseHref == "" {
+					analysis.BaseHref = strings.TrimSpace(href)
+				}
+			case atom.H1:
+				analysis.HeadingCounts.H1++
+			case atom.H2:
+				analysis.HeadingCounts.H2++
+			case atom.H3:
+				analysis.HeadingCounts.H3++
+			case atom.H4:
+				analysis.HeadingCounts.H4++
+			case atom.H5:
+				analysis.HeadingCounts.H5++
+			case atom.H6:
+				analysis.HeadingCounts.H6++
+			case atom.A, atom.Area:
+				if href, ok := attr(n, "href"); ok {
+					analysis.Links = append(analysis.Links, strings.TrimSpace(href))
+				}
+			case atom.Form:
+				form = &formStats{}
+				forms = append(forms, form)
+				form.addHints(attrValue(n, "action"), attrValue(n, "id"), attrValue(n, "name"),
+					attrValue(n, "class"), attrValue(n, "aria-label"))
+			case atom.Input:
+				form.addInput(n)
+			case atom.Button:
+				buttonType := strings.ToLower(attrValue(n, "type"))
+				if buttonType == "" || buttonType == "submit" {
+					form.addHints(textContent(n), attrValue(n, "value"))
+				}
+			}
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, form)
+		}
+	}
+	walk(doc, looseFields)
+
+	for _, form := range forms {
+		if form.isLoginForm() {
+			analysis.HasLoginForm = true
+			break
+		}
+	}
+	if looseFields.passwordFields > 0 && looseFields.isLoginForm() {
+		analysis.HasLoginForm = true
+	}
+
+	return analysis, nil
+}
+
+// applyTo copies the analysis into a crawl result. An existing title is kept.
+// Links are classified relative to pageURL; when subdomainsInternal is set, links to
+// any host under the same registrable domain count as internal.
+func (a *HTMLAnalysis) applyTo(result *models.CrawlResult, pageURL string, subdomainsInternal bool) {
+	if result.Title == "" {
+		result.Title = a.Title
+	}
+	result.HTMLVersion = a.HTMLVersion
+	result.HeadingCounts = a.HeadingCounts
+	result.HasLoginForm = a.HasLoginForm
+
+	log.Printf("Heading counts: H1=%d, H2=%d, H3=%d, H4=%d, H5=%d, H6=%d",
+		a.HeadingCounts.H1, a.HeadingCounts.H2, a.HeadingCounts.H3,
+		a.HeadingCounts.H4, a.HeadingCounts.H5, a.HeadingCounts.H6)
+
+	a.classifyLinks(result, pageURL, subdomainsInternal)
+}
+
+// BaseURL returns the URL that relative links resolve against, honoring <base href>
+func (a *HTMLAnalysis) BaseURL(pageURL string) string {
+	base, err := url.Parse(pageURL)
+	if err != nil || a.BaseHref == "" {
+		return pageURL
+	}
+
+	ref, err := url.Parse(a.BaseHref)
+	if err != nil {
+		return pageURL
+	}
+
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return pageURL
+	}
+	return resolved.String()
+}
+
+// classifyLinks counts internal, external and non-navigational links.
+// Counts include repeated links; the external link list is de-duplicated.
+func (a *HTMLAnalysis) classifyLinks(result *models.CrawlResult, pageURL string, subdomainsInternal bool) {
+	page, pageErr := url.Parse(pageURL)
+	base, baseErr := url.Parse(a.BaseURL(pageURL))
+
+	internalCount := 0
+	externalCount := 0
+	otherCount := 0
+	externalLinks := models.ExternalLinks{}
+	var discoveredLinks []string
+	seen := make(map[string]bool)
+
+	for _, href := range a.Links {
+		ref, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+
+		resolved := ref
+		if baseErr == nil {
+			resolved = base.ResolveReference(ref)
+		}
+
+		// mailto:, tel:, javascript: and similar hrefs don't lead to another page
+		if resolved.Scheme != "http" && resolved.Scheme != "https" {
+			otherCount++
+			continue
+		}
+
+		resolved.Fragment = ""
+		link := resolved.String()
+		firstSeen := !seen[link]
+		seen[link] = true
+
+		if pageErr == nil && sameSite(page.Hostname(), resolved.Hostname(), subdomainsInternal) {
+			internalCount++
+			if firstSeen {
+				discoveredLinks = append(discoveredLinks, link)
+			}
+			continue
+		}
+
+		externalCount++
+		if firstSeen {
+			externalLinks = append(externalLinks, link)
+		}
+	}
+
+	result.InternalLinksCount = internalCount
+	result.ExternalLinksCount = externalCount
+	result.OtherLinksCount = otherCount
+	result.ExternalLinks = externalLinks
+	result.DiscoveredLinks = discoveredLinks
+
+	log.Printf("Link analysis: Internal=%d, External=%d, Other=%d, Unique external URLs=%d",
+		internalCount, externalCount, otherCount, len(externalLinks))
+}
+
+// sameSite reports whether two hosts belong to the same site. Without subdomain
+// matching the hosts must be identical; otherwise their registrable domains must match.
+func sameSite(pageHost, linkHost string, subdomainsInternal bool) bool {
+	pageHost = strings.TrimSuffix(strings.ToLower(pageHost), ".")
+	linkHost = strings.TrimSuffix(strings.ToLower(linkHost), ".")
+
+	if pageHost == linkHost {
+		return true
+	}
+	if !subdomainsInternal {
+		return false
+	}
+
+	return registrableDomain(pageHost) == registrableDomain(linkHost)
+}
+
+// registrableDomain returns the eTLD+1 of a host, or the host itself for IPs and single labels
+func registrableDomain(host string) string {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// addInput records the login signals of an <input> element
+func (f *formStats) addInput(n *html.Node) {
+	inputType := strings.ToLower(attrValue(n, "type"))
+	autocomplete := strings.ToLower(attrValue(n, "autocomplete"))
+
+	switch inputType {
+	case "password":
+		f.passwordFields++
+		if strings.Contains(autocomplete, "new-password") {
+			f.newPasswordFields++
+		}
+		if strings.Contains(autocomplete, "current-password") {
+			f.currentPassword = true
+		}
+	case "email":
+		f.identifierFields++
+	case "", "text", "tel":
+		if isIdentifierField(attrValue(n, "name"), attrValue(n, "id"), autocomplete) {
+			f.identifierFields++
+		}
+	case "submit", "button", "image":
+		f.addHints(attrValue(n, "value"), attrValue(n, "alt"))
+	}
+}
+
+// addHints records lowercase text that may describe the form's purpose
+func (f *formStats) addHints(values ...string) {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			f.hints = append(f.hints, strings.ToLower(value))
+		}
+	}
+}
+
+// isLoginForm decides whether the collected signals describe a sign-in form
+func (f *formStats) isLoginForm() bool {
+	if f.currentPassword {
+		return true
+	}
+
+	login := f.hasHint(loginHints)
+	signup := f.hasHint(signupHints)
+
+	if f.passwordFields > 0 {
+		// Registration forms ask for a new password, usually twice
+		if f.newPasswordFields > 0 || f.passwordFields > 1 {
+			return login && !signup
+		}
+		return login || !signup
+	}
+
+	// Identifier-first flows ask for the username before the password
+	return f.identifierFields > 0 && login && !signup
+}
+
+// hasHint reports whether any recorded hint contains one of the given words
+func (f *formStats) hasHint(words []string) bool {
+	for _, hint := range f.hints {
+		for _, word := range words {
+			if strings.Contains(hint, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isIdentifierField reports whether a text input collects a username or email
+func isIdentifierField(values ...string) bool {
+	for _, value := range values {
+		value = strings.ToLower(value)
+		for _, word := range []string{"user", "email", "login"} {
+			if strings.Contains(value, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// htmlVersionFromDoctype maps a DOCTYPE declaration to an HTML version label
+func htmlVersionFromDoctype(n *html.Node) string {
+	var declaration strings.Builder
+	declaration.WriteString(n.Data)
+	for _, a := range n.Attr {
+		declaration.WriteString(" " + a.Val)
+	}
+	doctype := strings.ToUpper(declaration.String())
+
+	switch {
+	case strings.Contains(doctype, "XHTML 1.1"):
+		return "XHTML 1.1"
+	case strings.Contains(doctype, "XHTML 1.0"):
+		return "XHTML 1.0"
+	case strings.Contains(doctype, "HTML 4.01"):
+		return "HTML 4.01"
+	case strings.Contains(doctype, "HTML 4.0"):
+		return "HTML 4.0"
+	case strings.Contains(doctype, "HTML 3.2"):
+		return "HTML 3.2"
+	default:
+		return "HTML5"
+	}
+}
+
+// textContent returns the concatenated text of a node and its descendants
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(n)
+	return sb.String()
+}
+
+// attr returns the value of an attribute and whether it was present
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// attrValue returns the value of an attribute or an empty string
+func attrValue(n *html.Node, key string) string {
+	value, _ := attr(n, key)
+	return value
+}
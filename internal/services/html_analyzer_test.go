@@ -0,0 +1,195 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"url-crawler/internal/models"
+)
+
+func TestAnalyzeHTMLFixtures(t *testing.T) {
+	tests := []struct {
+		fixture      string
+		title        string
+		htmlVersion  string
+		headings     models.HeadingCounts
+		links        []string
+		hasLoginForm bool
+	}{
+		{
+			fixture:     "hidden_headings.html",
+			title:       "Hidden & Visible Headings",
+			htmlVersion: "HTML5",
+			headings:    models.HeadingCounts{H1: 1, H2: 2},
+			links:       []string{"/about"},
+		},
+		{
+			fixture:      "login_form.html",
+			title:        "Sign in",
+			htmlVersion:  "HTML5",
+			headings:     models.HeadingCounts{H1: 1},
+			hasLoginForm: true,
+		},
+		{
+			fixture:     "signup_form.html",
+			title:       "Create your account",
+			htmlVersion: "HTML5",
+			headings:    models.HeadingCounts{H1: 1},
+		},
+		{
+			fixture:     "newsletter_with_login_words.html",
+			title:       "Blog",
+			htmlVersion: "HTML5",
+			headings:    models.HeadingCounts{H2: 1},
+			links:       []string{"/login", "/signin"},
+		},
+		{
+			fixture:      "identifier_first_login.html",
+			title:        "Account",
+			htmlVersion:  "HTML5",
+			hasLoginForm: true,
+		},
+		{
+			fixture:      "scripted_login.html",
+			title:        "App",
+			htmlVersion:  "HTML5",
+			hasLoginForm: true,
+		},
+		{
+			fixture:     "loose_controls.html",
+			title:       "Home",
+			htmlVersion: "HTML5",
+		},
+		{
+			fixture:     "xhtml.html",
+			title:       "XHTML page",
+			htmlVersion: "XHTML 1.0",
+			headings:    models.HeadingCounts{H3: 1},
+		},
+		{
+			fixture:     "html401_malformed.html",
+			htmlVersion: "HTML 4.01",
+			headings:    models.HeadingCounts{H1: 1, H2: 1, H5: 1, H6: 1},
+			links:       []string{"/relative", "https://example.org/page", "/area-link"},
+		},
+		{
+			fixture:     "no_doctype.html",
+			title:       "Legacy",
+			htmlVersion: "HTML5",
+			headings:    models.HeadingCounts{H1: 1},
+			links:       []string{"mailto:someone@example.com", "#top"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", "html", tt.fixture))
+			if err != nil {
+				t.Fatalf("failed to open fixture: %v", err)
+			}
+			defer f.Close()
+
+			analysis, err := AnalyzeHTML(f)
+			if err != nil {
+				t.Fatalf("AnalyzeHTML() error = %v", err)
+			}
+
+			if analysis.Title != tt.title {
+				t.Errorf("title = %q, expected %q", analysis.Title, tt.title)
+			}
+			if analysis.HTMLVersion != tt.htmlVersion {
+				t.Errorf("html version = %q, expected %q", analysis.HTMLVersion, tt.htmlVersion)
+			}
+			if analysis.HeadingCounts != tt.headings {
+				t.Errorf("headings = %+v, expected %+v", analysis.HeadingCounts, tt.headings)
+			}
+			if len(analysis.Links) != 0 || len(tt.links) != 0 {
+				if !reflect.DeepEqual(analysis.Links, tt.links) {
+					t.Errorf("links = %v, expected %v", analysis.Links, tt.links)
+				}
+			}
+			if analysis.HasLoginForm != tt.hasLoginForm {
+				t.Errorf("hasLoginForm = %v, expected %v", analysis.HasLoginForm, tt.hasLoginForm)
+			}
+		})
+	}
+}
+
+func TestClassifyLinks(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "html", "link_classification.html"))
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	defer f.Close()
+
+	analysis, err := AnalyzeHTML(f)
+	if err != nil {
+		t.Fatalf("AnalyzeHTML() error = %v", err)
+	}
+
+	if base := analysis.BaseURL("https://www.example.com/start"); base != "https://www.example.com/docs/" {
+		t.Errorf("BaseURL() = %q, expected the <base href>", base)
+	}
+
+	tests := []struct {
+		name               string
+		subdomainsInternal bool
+		internal           int
+		external           int
+		externalLinks      models.ExternalLinks
+		discoveredLinks    []string
+	}{
+		{
+			name:               "subdomains internal",
+			subdomainsInternal: true,
+			internal:           5,
+			external:           3,
+			externalLinks:      models.ExternalLinks{"https://cdn.other.org/lib.js", "https://other.org/page"},
+			discoveredLinks: []string{
+				"https://www.example.com/docs/intro.html",
+				"https://www.example.com/pricing",
+				"https://www.example.com/docs/",
+				"https://www.example.com/contact",
+				"https://blog.example.com/post",
+			},
+		},
+		{
+			name:               "exact host only",
+			subdomainsInternal: false,
+			internal:           4,
+			external:           4,
+			externalLinks:      models.ExternalLinks{"https://blog.example.com/post", "https://cdn.other.org/lib.js", "https://other.org/page"},
+			discoveredLinks: []string{
+				"https://www.example.com/docs/intro.html",
+				"https://www.example.com/pricing",
+				"https://www.example.com/docs/",
+				"https://www.example.com/contact",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &models.CrawlResult{}
+			analysis.applyTo(result, "https://www.example.com/start", tt.subdomainsInternal)
+
+			if result.InternalLinksCount != tt.internal {
+				t.Errorf("internal = %d, expected %d", result.InternalLinksCount, tt.internal)
+			}
+			if result.ExternalLinksCount != tt.external {
+				t.Errorf("external = %d, expected %d", result.ExternalLinksCount, tt.external)
+			}
+			if result.OtherLinksCount != 3 {
+				t.Errorf("other = %d, expected 3", result.OtherLinksCount)
+			}
+			if !reflect.DeepEqual(result.ExternalLinks, tt.externalLinks) {
+				t.Errorf("external links = %v, expected %v", result.ExternalLinks, tt.externalLinks)
+			}
+			if !reflect.DeepEqual(result.DiscoveredLinks, tt.discoveredLinks) {
+				t.Errorf("discovered links = %v, expected %v", result.DiscoveredLinks, tt.discoveredLinks)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"url-crawler/internal/config"
+)
+
+// newCrawlerHTTPClient builds the HTTP client used for outbound crawler requests.
+// When a target guard is provided every connection is dialed through it.
+func newCrawlerHTTPClient(cfg config.CrawlerConfig, guard *TargetGuard) *http.Client {
+	maxRedirects := cfg.MaxRedirects
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if guard != nil {
+		// Proxies would bypass the pinned dialer, so connect directly
+		transport.Proxy = nil
+		transport.DialContext = guard.DialContext
+	}
+	transport.ResponseHeaderTimeout = cfg.Timeout
+	transport.IdleConnTimeout = 90 * time.Second
+
+	return &http.Client{
+		Timeout:   cfg.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+// newPolicyHTTPClient builds a crawler HTTP client that also applies the
+// validator's crawl policies to every redirect hop, so an allowed page cannot
+// lead the crawler to a blocked domain or a path robots.txt disallows
+func newPolicyHTTPClient(cfg config.CrawlerConfig, validator *URLValidator) *http.Client {
+	if validator == nil {
+		return newCrawlerHTTPClient(cfg, nil)
+	}
+
+	client := newCrawlerHTTPClient(cfg, validator.guard)
+	checkRedirectCount := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := checkRedirectCount(req, via); err != nil {
+			return err
+		}
+		if err := validator.CheckLink(req.Context(), req.URL.String()); err != nil {
+			return fmt.Errorf("redirect to %s blocked: %w", req.URL, err)
+		}
+		return nil
+	}
+	return client
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+)
+
+func TestPolicyHTTPClientChecksRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		case "/to-blocked-domain":
+			http.Redirect(w, r, "http://blocked.example/", http.StatusFound)
+		case "/to-private":
+			http.Redirect(w, r, "/private/page", http.StatusFound)
+		case "/to-public":
+			http.Redirect(w, r, "/public/page", http.StatusFound)
+		default:
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.CrawlerConfig{
+		Timeout:          5 * time.Second,
+		UserAgent:        "test-crawler",
+		MaxRedirects:     5,
+		BlockedDomains:   []string{"blocked.example"},
+		RespectRobotsTxt: true,
+	}
+	client := newPolicyHTTPClient(cfg, NewURLValidator(cfg))
+
+	tests := []struct {
+		path     string
+		expected error
+	}{
+		{path: "/to-public"},
+		{path: "/to-blocked-domain", expected: ErrDomainNotAllowed},
+		{path: "/to-private", expected: ErrDisallowedByRobots},
+	}
+
+	for _, tt := range tests {
+		resp, err := client.Get(server.URL + tt.path)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if tt.expected == nil && err != nil {
+			t.Errorf("GET %s error = %v, expected the redirect to be followed", tt.path, err)
+		}
+		if tt.expected != nil && !errors.Is(err, tt.expected) {
+			t.Errorf("GET %s error = %v, expected %v", tt.path, err, tt.expected)
+		}
+	}
+}
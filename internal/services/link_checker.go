@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+// LinkChecker verifies that links extracted from a page are reachable
+type LinkChecker struct {
+	client       *http.Client
+	userAgent    string
+	maxLinks     int
+	requestDelay time.Duration
+	workers      int
+	validator    *URLValidator
+}
+
+// NewLinkChecker creates a new link checker using configuration.
+// Links rejected by the validator's crawl policies are skipped rather than probed.
+func NewLinkChecker(cfg config.CrawlerConfig, validator *URLValidator) *LinkChecker {
+	workers := cfg.LinkCheckWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	return &LinkChecker{
+		client:       newPolicyHTTPClient(cfg, validator),
+		userAgent:    cfg.UserAgent,
+		maxLinks:     cfg.MaxLinksToCheck,
+		requestDelay: cfg.RequestDelay,
+		workers:      workers,
+		validator:    validator,
+	}
+}
+
+// CheckLinks resolves hrefs against the page URL and probes each unique HTTP(S) link.
+// It returns the links that answered with a 4xx/5xx status or could not be reached,
+// and every link it probed. Probing stops early when ctx is done; links that were
+// not probed are not reported.
+func (lc *LinkChecker) CheckLinks(ctx context.Context, pageURL string, hrefs []string) (models.BrokenLinks, models.CheckedLinks) {
+	links := lc.resolveLinks(ctx, pageURL, hrefs)
+	if len(links) == 0 {
+		return models.BrokenLinks{}, models.CheckedLinks{}
+	}
+
+	log.Printf("Link check: probing %d links for %s", len(links), pageURL)
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		broken  = models.BrokenLinks{}
+		checked = models.CheckedLinks{}
+		sem     = make(chan struct{}, lc.workers)
+	)
+
+probing:
+	for i, link := range links {
+		// Space out request starts to avoid hammering the target
+		if delay := lc.delayFor(ctx, link); i > 0 && delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				break probing
+			}
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break probing
+		}
+
+		checked = append(checked, link)
+		wg.Add(1)
+		go func(link string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if brokenLink, ok := lc.checkLink(ctx, link); !ok && ctx.Err() == nil {
+				mu.Lock()
+				broken = append(broken, brokenLink)
+				mu.Unlock()
+			}
+		}(link)
+	}
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		log.Printf("Link check: stopped early for %s: %v", pageURL, err)
+	}
+
+	log.Printf("Link check: %d of %d links inaccessible for %s", len(broken), len(links), pageURL)
+	return broken, checked
+}
+
+// resolveLinks turns hrefs into absolute, de-duplicated HTTP(S) URLs. At most
+// maxLinks unique links are considered, including those the crawl policies reject,
+// so a page full of blocked links cannot keep the validator busy.
+func (lc *LinkChecker) resolveLinks(ctx context.Context, pageURL string, hrefs []string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var links []string
+
+	for _, href := range hrefs {
+		if (lc.maxLinks > 0 && len(seen) >= lc.maxLinks) || ctx.Err() != nil {
+			break
+		}
+
+		ref, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+
+		resolved := base.ResolveReference(ref)
+		if resolved.Scheme != "http" && resolved.Scheme != "https" {
+			continue
+		}
+		resolved.Fragment = ""
+
+		link := resolved.String()
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+
+		if lc.validator != nil {
+			if err := lc.validator.CheckLink(ctx, link); err != nil {
+				log.Printf("Link check: skipping %s: %v", link, err)
+				continue
+			}
+		}
+
+		links = append(links, link)
+	}
+
+	return links
+}
+
+// delayFor returns the pause before probing a link, honoring robots.txt Crawl-delay
+func (lc *LinkChecker) delayFor(ctx context.Context, link string) time.Duration {
+	delay := lc.requestDelay
+	if lc.validator != nil {
+		if crawlDelay := lc.validator.CrawlDelay(ctx, link); crawlDelay > delay {
+			delay = crawlDelay
+		}
+	}
+	return delay
+}
+
+// checkLink probes a single link with HEAD, falling back to GET when HEAD fails
+func (lc *LinkChecker) checkLink(ctx context.Context, link string) (models.BrokenLink, bool) {
+	statusCode, err := lc.probe(ctx, http.MethodHead, link)
+	if err == nil && statusCode < 400 {
+		return models.BrokenLink{}, true
+	}
+
+	// Some servers reject or mishandle HEAD, so confirm with GET
+	statusCode, err = lc.probe(ctx, http.MethodGet, link)
+	if err != nil {
+		return models.BrokenLink{
+			URL:        link,
+			StatusCode: 0,
+			StatusText: err.Error(),
+		}, false
+	}
+
+	if statusCode >= 400 {
+		return models.BrokenLink{
+			URL:        link,
+			StatusCode: statusCode,
+			StatusText: http.StatusText(statusCode),
+		}, false
+	}
+
+	return models.BrokenLink{}, true
+}
+
+// probe issues a request and returns the response status code
+func (lc *LinkChecker) probe(ctx context.Context, method, link string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", lc.userAgent)
+
+	resp, err := lc.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+)
+
+func newTestLinkChecker(maxLinks int) *LinkChecker {
+	return NewLinkChecker(config.CrawlerConfig{
+		Timeout:          5 * time.Second,
+		UserAgent:        "test-agent",
+		MaxRedirects:     5,
+		MaxLinksToCheck:  maxLinks,
+		LinkCheckWorkers: 2,
+	}, nil)
+}
+
+func TestCheckLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	lc := newTestLinkChecker(10)
+	hrefs := []string{"/ok", "/no-head", "/missing", "/missing#section", srv.URL + "/error", "mailto:someone@example.com"}
+
+	broken, checked := lc.CheckLinks(context.Background(), srv.URL+"/page", hrefs)
+	if len(broken) != 2 {
+		t.Fatalf("expected 2 broken links, got %d: %+v", len(broken), broken)
+	}
+	if len(checked) != 4 {
+		t.Errorf("expected the 4 unique HTTP links to be checked, got %v", checked)
+	}
+
+	statuses := map[string]int{}
+	for _, link := range broken {
+		statuses[link.URL] = link.StatusCode
+	}
+	if statuses[srv.URL+"/missing"] != http.StatusNotFound {
+		t.Errorf("expected /missing to be reported as 404, got %+v", broken)
+	}
+	if statuses[srv.URL+"/error"] != http.StatusInternalServerError {
+		t.Errorf("expected /error to be reported as 500, got %+v", broken)
+	}
+}
+
+func TestCheckLinksRespectsMaxLinks(t *testing.T) {
+	lc := newTestLinkChecker(2)
+
+	links := lc.resolveLinks(context.Background(), "http://example.com/", []string{"/a", "/b", "/c"})
+	if len(links) != 2 {
+		t.Fatalf("expected links to be capped at 2, got %d", len(links))
+	}
+}
+
+func TestCheckLinksCountsRejectedLinksAgainstMaxLinks(t *testing.T) {
+	cfg := config.CrawlerConfig{BlockedDomains: []string{"blocked.example"}}
+	lc := newTestLinkChecker(2)
+	lc.validator = NewURLValidator(cfg)
+
+	links := lc.resolveLinks(context.Background(), "http://example.com/", []string{
+		"http://blocked.example/a",
+		"http://blocked.example/b",
+		"/c",
+	})
+	if len(links) != 0 {
+		t.Errorf("expected the cap to be used up by the rejected links, got %v", links)
+	}
+}
+
+func TestCheckLinksUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	lc := newTestLinkChecker(10)
+	broken, _ := lc.CheckLinks(context.Background(), srv.URL, []string{srv.URL + "/gone"})
+	if len(broken) != 1 {
+		t.Fatalf("expected 1 broken link, got %d", len(broken))
+	}
+	if broken[0].StatusCode != 0 || broken[0].StatusText == "" {
+		t.Errorf("expected network failure with status text, got %+v", broken[0])
+	}
+}
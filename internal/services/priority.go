@@ -0,0 +1,107 @@
+package services
+
+import (
+	"sort"
+	"sync"
+
+	"url-crawler/internal/models"
+)
+
+// defaultLaneWeights sets how many claims each priority lane gets per round
+// when all lanes have work waiting
+var defaultLaneWeights = map[models.CrawlPriority]int{
+	models.CrawlPriorityHigh:   6,
+	models.CrawlPriorityNormal: 3,
+	models.CrawlPriorityLow:    1,
+}
+
+// laneScheduler decides which priority lane a worker claims from next. It uses
+// smooth weighted round-robin, so every lane with a non-zero weight is served
+// within a bounded number of claims and the low lane is never starved.
+type laneScheduler struct {
+	mu      sync.Mutex
+	lanes   []models.CrawlPriority
+	weights []int
+	current []int
+	total   int
+}
+
+// newLaneScheduler creates a scheduler over the priority lanes using the given weights
+func newLaneScheduler(weights map[models.CrawlPriority]int) *laneScheduler {
+	s := &laneScheduler{
+		lanes:   models.CrawlPriorities,
+		weights: make([]int, len(models.CrawlPriorities)),
+		current: make([]int, len(models.CrawlPriorities)),
+	}
+	for i, lane := range s.lanes {
+		weight := weights[lane]
+		if weight < 1 {
+			weight = 1
+		}
+		s.weights[i] = weight
+		s.total += weight
+	}
+	return s
+}
+
+// next returns the lanes in the order a claim should try them: the lane whose
+// turn it is first, then the others by how soon their turn comes, ties going to
+// the more urgent lane. Falling back to the other lanes keeps workers busy when
+// the chosen lane is empty. Asking for the order does not use up a turn; only
+// claimed does.
+func (s *laneScheduler) next() []models.CrawlPriority {
+	s.mu.Lock()
+	credit := make([]int, len(s.lanes))
+	for i := range s.lanes {
+		credit[i] = s.current[i] + s.weights[i]
+	}
+	s.mu.Unlock()
+
+	order := make([]int, len(s.lanes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return credit[order[a]] > credit[order[b]]
+	})
+
+	lanes := make([]models.CrawlPriority, len(order))
+	for i, lane := range order {
+		lanes[i] = s.lanes[lane]
+	}
+	return lanes
+}
+
+// claimed advances the round-robin after a job was claimed from lane, trying
+// lanes in the given order. Lanes tried before it were empty, so they earn no
+// credit towards later turns; the served lane pays for the turn.
+func (s *laneScheduler) claimed(order []models.CrawlPriority, lane models.CrawlPriority) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	earned, served := 0, -1
+	for _, tried := range order {
+		i := s.index(tried)
+		if tried == lane {
+			served = i
+		}
+		if served < 0 || i < 0 {
+			continue
+		}
+		s.current[i] += s.weights[i]
+		earned += s.weights[i]
+	}
+	if served >= 0 {
+		s.current[served] -= earned
+	}
+}
+
+// index returns the position of lane in s.lanes, or -1 for an unknown lane
+func (s *laneScheduler) index(lane models.CrawlPriority) int {
+	for i, known := range s.lanes {
+		if known == lane {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+// recordingCrawler records the order in which URLs are crawled
+type recordingCrawler struct {
+	mu      sync.Mutex
+	crawled []string
+}
+
+func (r *recordingCrawler) AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error) {
+	r.mu.Lock()
+	r.crawled = append(r.crawled, targetURL)
+	r.mu.Unlock()
+
+	return &models.CrawlResult{
+		URL:           targetURL,
+		HeadingCounts: models.HeadingCounts{},
+		BrokenLinks:   models.BrokenLinks{},
+	}, nil
+}
+
+func (r *recordingCrawler) ValidateURL(ctx context.Context, targetURL string) error {
+	return nil
+}
+
+func (r *recordingCrawler) order() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.crawled...)
+}
+
+func TestLaneSchedulerWeights(t *testing.T) {
+	scheduler := newLaneScheduler(defaultLaneWeights)
+
+	served := make(map[models.CrawlPriority]int)
+	for i := 0; i < 100; i++ {
+		order := scheduler.next()
+		scheduler.claimed(order, order[0])
+		served[order[0]]++
+	}
+
+	expected := map[models.CrawlPriority]int{
+		models.CrawlPriorityHigh:   60,
+		models.CrawlPriorityNormal: 30,
+		models.CrawlPriorityLow:    10,
+	}
+	for lane, count := range expected {
+		if served[lane] != count {
+			t.Errorf("%s lane served %d times, expected %d", lane, served[lane], count)
+		}
+	}
+}
+
+func TestLaneSchedulerDoesNotStarveLowLane(t *testing.T) {
+	scheduler := newLaneScheduler(defaultLaneWeights)
+
+	// Every lane gets a turn within one round of the total weight
+	gap := 0
+	for i := 0; i < 1000; i++ {
+		order := scheduler.next()
+		scheduler.claimed(order, order[0])
+		if order[0] == models.CrawlPriorityLow {
+			gap = 0
+			continue
+		}
+		gap++
+		if gap >= 10 {
+			t.Fatalf("low lane not served for %d consecutive claims", gap)
+		}
+	}
+}
+
+func TestLaneSchedulerOnlyAdvancesOnClaims(t *testing.T) {
+	scheduler := newLaneScheduler(defaultLaneWeights)
+
+	// Idle polls find nothing to claim and leave the turn where it is
+	for i := 0; i < 25; i++ {
+		if lane := scheduler.next()[0]; lane != models.CrawlPriorityHigh {
+			t.Fatalf("poll %d chose the %s lane without a claim, expected high", i, lane)
+		}
+	}
+
+	// With the high lane empty, claims fall back and the other lanes keep their ratio
+	served := make(map[models.CrawlPriority]int)
+	for i := 0; i < 40; i++ {
+		order := scheduler.next()
+		lane := order[0]
+		if lane == models.CrawlPriorityHigh {
+			lane = order[1]
+		}
+		scheduler.claimed(order, lane)
+		served[lane]++
+	}
+	if served[models.CrawlPriorityNormal] != 30 || served[models.CrawlPriorityLow] != 10 {
+		t.Errorf("served %v with the high lane empty, expected 30 normal and 10 low", served)
+	}
+}
+
+func TestLaneSchedulerFallsBackToOtherLanes(t *testing.T) {
+	scheduler := newLaneScheduler(map[models.CrawlPriority]int{})
+
+	for i := 0; i < 6; i++ {
+		order := scheduler.next()
+		if len(order) != len(models.CrawlPriorities) {
+			t.Fatalf("next() returned %d lanes, expected %d", len(order), len(models.CrawlPriorities))
+		}
+		seen := make(map[models.CrawlPriority]bool)
+		for _, lane := range order {
+			if seen[lane] {
+				t.Fatalf("next() returned lane %s twice: %v", lane, order)
+			}
+			seen[lane] = true
+		}
+	}
+}
+
+func TestQueueServesPriorityLanes(t *testing.T) {
+	storage := newMemoryStorage()
+	crawler := &recordingCrawler{}
+	queue := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 100}, crawler, storage)
+	ctx := context.Background()
+
+	// Fill every lane before any worker runs, oldest jobs in the low lane
+	var ids []string
+	for _, lane := range []models.CrawlPriority{models.CrawlPriorityLow, models.CrawlPriorityNormal, models.CrawlPriorityHigh} {
+		for i := 0; i < 10; i++ {
+			result, err := queue.EnqueueURL(ctx, fmt.Sprintf("https://example.com/%s/%d", lane, i), EnqueueOptions{Priority: lane})
+			if err != nil {
+				t.Fatalf("EnqueueURL() error = %v", err)
+			}
+			ids = append(ids, result.ID)
+		}
+	}
+
+	depth := queue.GetQueueStats(ctx)["queue_depth"].(map[models.CrawlPriority]int)
+	for _, lane := range models.CrawlPriorities {
+		if depth[lane] != 10 {
+			t.Errorf("queue_depth[%s] = %d, expected 10", lane, depth[lane])
+		}
+	}
+
+	queue.Start()
+	defer queue.Stop()
+	for _, id := range ids {
+		waitForStatus(t, storage, id, models.CrawlStatusCompleted)
+	}
+
+	// The first round is split by lane weight, so the low lane is served despite waiting behind others
+	served := make(map[string]int)
+	for _, crawled := range crawler.order()[:10] {
+		lane := strings.Split(strings.TrimPrefix(crawled, "https://example.com/"), "/")[0]
+		served[lane]++
+	}
+	if served["high"] != 6 || served["normal"] != 3 || served["low"] != 1 {
+		t.Errorf("first round served %v, expected 6 high, 3 normal and 1 low", served)
+	}
+
+	job, err := storage.GetJob(ctx, ids[0])
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if job.Priority != models.CrawlPriorityLow {
+		t.Errorf("job priority = %s, expected low", job.Priority)
+	}
+}
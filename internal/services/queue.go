@@ -2,8 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,46 +17,163 @@ import (
 	"github.com/google/uuid"
 )
 
-// QueueService manages background crawling tasks
+// Queue errors
+var (
+	ErrQueueFull     = errors.New("queue is full, please try again later")
+	ErrQueueStopped  = errors.New("queue service is stopped")
+	ErrTaskNotActive = errors.New("crawl task is not queued or running")
+	ErrTaskActive    = errors.New("crawl task is already queued or running")
+)
+
+const (
+	// defaultTaskTimeout bounds a single crawl, including its link checks, when no timeout is configured
+	defaultTaskTimeout = 2 * time.Minute
+
+	// storageTimeout bounds writes that record a task outcome after its own context has ended
+	storageTimeout = 10 * time.Second
+
+	// defaultLeaseDuration is how long a claimed job stays leased without a heartbeat
+	defaultLeaseDuration = time.Minute
+
+	// defaultPollInterval is how often idle workers look for jobs queued by other instances
+	defaultPollInterval = time.Second
+
+	// workerRetention is how many lease durations a silent worker stays registered
+	workerRetention = 10
+
+	// shutdownPollInterval is how often Shutdown checks whether running crawls have finished
+	shutdownPollInterval = 100 * time.Millisecond
+
+	// releasedTaskNote is recorded on crawls that were interrupted by a shutdown and queued again
+	releasedTaskNote = "Interrupted by a worker shutdown, queued again"
+)
+
+// QueueService manages background crawling tasks. Tasks are persisted as jobs
+// in a shared job store; workers claim them with a renewable lease, so queued
+// work survives restarts and jobs orphaned by a crashed worker are reclaimed.
 type QueueService struct {
-	queue       chan *CrawlTask
-	workers     int
-	bufferSize  int
-	maxRetries  int
-	retryDelay  time.Duration
-	crawler     Crawler
-	storage     CrawlStorage
-	running     bool
-	wg          sync.WaitGroup
-	ctx         context.Context
-	cancel      context.CancelFunc
-	mu          sync.RWMutex
-	activeTasks map[string]*CrawlTask
-}
-
-// CrawlTask represents a crawling task
+	workers        int
+	bufferSize     int
+	maxRetries     int
+	retryDelay     time.Duration
+	taskTimeout    time.Duration
+	leaseDuration  time.Duration
+	pollInterval   time.Duration
+	storageTimeout time.Duration
+	workerID       string
+	hostname       string
+	startedAt      time.Time
+	crawler        Crawler
+	storage        CrawlStorage
+	notifier       CrawlNotifier
+	events         CrawlEventPublisher
+	wake           chan struct{}
+	lanes          *laneScheduler
+	running        bool
+	draining       bool
+	stopped        bool
+	wg             sync.WaitGroup
+	ctx            context.Context
+	cancel         context.CancelFunc
+	mu             sync.RWMutex
+	activeTasks    map[string]*CrawlTask
+}
+
+// CrawlTask represents a crawling task running on this instance
 type CrawlTask struct {
 	ID        string
 	URL       string
 	CreatedAt time.Time
 	Status    models.CrawlStatus
+	Priority  models.CrawlPriority
+
+	// Site crawl membership, empty for standalone crawls
+	SiteCrawlID string
+	ParentID    string
+	Depth       int
+
+	// Requester of the crawl and the webhook registered with it, empty when not given
+	Owner     string
+	WebhookID string
+
+	// Batch the URL was submitted in, empty when submitted alone
+	BatchID string
+
+	// Retry state: the current attempt (1-based) and why the previous one failed
+	Attempt   int
+	LastError string
+
+	// Cancellation state, guarded by the queue mutex
+	cancel    context.CancelFunc
+	cancelled bool
+	leaseLost bool
 }
 
 // CrawlStorage interface for persisting crawl results
 type CrawlStorage interface {
-	SaveCrawlResult(result *models.CrawlResult) error
-	UpdateCrawlStatus(id string, status models.CrawlStatus, errorMsg *string) error
-	GetCrawlResult(id string) (*models.CrawlResult, error)
+	SaveCrawlResult(ctx context.Context, result *models.CrawlResult) error
+	UpdateCrawlStatus(ctx context.Context, id string, status models.CrawlStatus, errorMsg *string) error
+	GetCrawlResult(ctx context.Context, id string) (*models.CrawlResult, error)
+	UpdateCrawlAttempts(ctx context.Context, id string, attempts int, lastError *string) error
+	RecordCrawlRun(ctx context.Context, id string) error
+	SaveSiteCrawl(ctx context.Context, siteCrawl *models.SiteCrawl) error
+	UpdateSiteCrawlStatus(ctx context.Context, id string, status models.CrawlStatus) error
+	GetSiteCrawl(ctx context.Context, id string) (*models.SiteCrawl, error)
+	EnqueueSiteCrawlJobs(ctx context.Context, siteCrawlID string, maxPages int, jobs []*models.CrawlJob) (int, error)
+	CompleteSiteCrawlIfDone(ctx context.Context, siteCrawlID string) (bool, error)
+	SaveBatch(ctx context.Context, batch *models.Batch) error
+	JobStore
+	WorkerRegistry
+}
+
+// JobStore persists the job queue shared by all workers
+type JobStore interface {
+	EnqueueJob(ctx context.Context, job *models.CrawlJob) error
+	ClaimJob(ctx context.Context, owner string, lease time.Duration, lanes []models.CrawlPriority) (*models.CrawlJob, error)
+	ExtendJobLease(ctx context.Context, id, owner string, lease time.Duration) (held bool, cancelRequested bool, err error)
+	CompleteJob(ctx context.Context, id, owner string) error
+	FailJob(ctx context.Context, id, owner string, errorMsg string) error
+	AbortJob(ctx context.Context, id, owner string) (bool, error)
+	RetryJob(ctx context.Context, id, owner string, delay time.Duration, lastError string) (bool, error)
+	ReleaseJob(ctx context.Context, id, owner string) (bool, error)
+	RequestJobCancel(ctx context.Context, id string) (*models.CrawlJob, error)
+	ReclaimExpiredJobs(ctx context.Context, maxAttempts int) (*models.ReclaimedJobs, error)
+	CountQueuedJobs(ctx context.Context) (map[models.CrawlPriority]int, error)
+	GetJob(ctx context.Context, id string) (*models.CrawlJob, error)
+}
+
+// CrawlNotifier is told when a crawl reaches a final completed or error state
+type CrawlNotifier interface {
+	NotifyCrawlFinished(ctx context.Context, id string)
+}
+
+// EnqueueOptions describes how a single URL is queued
+type EnqueueOptions struct {
+	Priority  models.CrawlPriority // defaults to normal
+	Owner     string               // name of the API key requesting the crawl
+	WebhookID string               // webhook registered with the crawl request
+	BatchID   string               // batch the URL was submitted in
+}
+
+// WorkerRegistry tracks the worker processes sharing the job store
+type WorkerRegistry interface {
+	HeartbeatWorker(ctx context.Context, worker *models.Worker) error
+	RemoveWorker(ctx context.Context, id string) error
+	PruneWorkers(ctx context.Context, maxAge time.Duration) (int, error)
+	GetLiveWorkers(ctx context.Context, maxAge time.Duration) ([]models.Worker, error)
 }
 
 // NewQueueService creates a new queue service (backward compatibility)
 func NewQueueService(workers int, crawler Crawler, storage CrawlStorage) *QueueService {
 	// Create default config
 	defaultConfig := config.QueueConfig{
-		Workers:    workers,
-		BufferSize: 100,
-		MaxRetries: 3,
-		RetryDelay: 5 * time.Second,
+		Workers:       workers,
+		BufferSize:    100,
+		MaxRetries:    3,
+		RetryDelay:    5 * time.Second,
+		TaskTimeout:   defaultTaskTimeout,
+		LeaseDuration: defaultLeaseDuration,
+		PollInterval:  defaultPollInterval,
 	}
 	return NewQueueServiceWithConfig(defaultConfig, crawler, storage)
 }
@@ -61,18 +182,77 @@ func NewQueueService(workers int, crawler Crawler, storage CrawlStorage) *QueueS
 func NewQueueServiceWithConfig(cfg config.QueueConfig, crawler Crawler, storage CrawlStorage) *QueueService {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	leaseDuration := cfg.LeaseDuration
+	if leaseDuration <= 0 {
+		leaseDuration = defaultLeaseDuration
+	}
+	pollInterval := cfg.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
 	return &QueueService{
-		queue:       make(chan *CrawlTask, cfg.BufferSize),
-		workers:     cfg.Workers,
-		bufferSize:  cfg.BufferSize,
-		maxRetries:  cfg.MaxRetries,
-		retryDelay:  cfg.RetryDelay,
-		crawler:     crawler,
-		storage:     storage,
-		ctx:         ctx,
-		cancel:      cancel,
-		activeTasks: make(map[string]*CrawlTask),
+		workers:        cfg.Workers,
+		bufferSize:     cfg.BufferSize,
+		maxRetries:     cfg.MaxRetries,
+		retryDelay:     cfg.RetryDelay,
+		taskTimeout:    cfg.TaskTimeout,
+		leaseDuration:  leaseDuration,
+		pollInterval:   pollInterval,
+		storageTimeout: storageTimeout,
+		workerID:       fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8]),
+		hostname:       hostname,
+		crawler:        crawler,
+		storage:        storage,
+		wake:           make(chan struct{}, max(cfg.Workers, 1)),
+		lanes:          newLaneScheduler(defaultLaneWeights),
+		ctx:            ctx,
+		cancel:         cancel,
+		activeTasks:    make(map[string]*CrawlTask),
+	}
+}
+
+// SetNotifier registers a notifier for finished crawls. It must be called before Start.
+func (q *QueueService) SetNotifier(notifier CrawlNotifier) {
+	q.notifier = notifier
+}
+
+// SetEventPublisher registers a publisher for crawl state changes. It must be called before Start.
+func (q *QueueService) SetEventPublisher(events CrawlEventPublisher) {
+	q.events = events
+}
+
+// publish reports a state change of a task to the event publisher, if any
+func (q *QueueService) publish(ctx context.Context, task *CrawlTask, eventType models.CrawlEventType, status models.CrawlStatus, message string) {
+	if q.events == nil {
+		return
 	}
+	q.events.PublishCrawlEvent(ctx, &models.CrawlEvent{
+		CrawlID: task.ID,
+		Type:    eventType,
+		Status:  status,
+		Attempt: task.Attempt,
+		Message: message,
+	})
+}
+
+// publishPhase reports that a running task entered a new phase
+func (q *QueueService) publishPhase(ctx context.Context, task *CrawlTask, phase models.CrawlPhase) {
+	if q.events == nil {
+		return
+	}
+	q.events.PublishCrawlEvent(ctx, &models.CrawlEvent{
+		CrawlID: task.ID,
+		Type:    models.CrawlEventPhase,
+		Status:  models.CrawlStatusRunning,
+		Phase:   phase,
+		Attempt: task.Attempt,
+	})
 }
 
 // Start begins processing crawl tasks
@@ -80,11 +260,20 @@ func (q *QueueService) Start() {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if q.running {
+	if q.running || q.stopped {
 		return
 	}
 
 	q.running = true
+	q.startedAt = time.Now()
+
+	// Register this process and keep its heartbeat current
+	q.wg.Add(1)
+	go q.heartbeat()
+
+	// Jobs left running by a crashed instance are picked up again once their lease expires
+	q.wg.Add(1)
+	go q.reclaimer()
 
 	// Start worker goroutines
 	for i := 0; i < q.workers; i++ {
@@ -92,121 +281,617 @@ func (q *QueueService) Start() {
 		go q.worker(i)
 	}
 
-	log.Printf("Queue service started with %d workers", q.workers)
+	log.Printf("Queue service started with %d workers (worker ID: %s)", q.workers, q.workerID)
 }
 
-// Stop gracefully stops the queue service
+// Stop stops the queue service. In-flight crawls are aborted and their jobs
+// released back to the queue for the next worker to pick up.
 func (q *QueueService) Stop() {
 	q.mu.Lock()
-	defer q.mu.Unlock()
-
-	if !q.running {
+	if q.stopped {
+		q.mu.Unlock()
 		return
 	}
 
 	q.running = false
+	q.stopped = true
 	q.cancel()
-	close(q.queue)
+	q.mu.Unlock()
 
+	// Workers take the mutex while finishing a task, so wait without holding it
 	log.Println("Waiting for workers to finish...")
 	q.wg.Wait()
+
+	if !q.startedAt.IsZero() {
+		ctx, cancel := context.WithTimeout(context.Background(), q.storageTimeout)
+		defer cancel()
+		if err := q.storage.RemoveWorker(ctx, q.workerID); err != nil {
+			log.Printf("Failed to deregister worker %s: %v", q.workerID, err)
+		}
+	}
 	log.Println("Queue service stopped")
 }
 
+// Shutdown stops the queue service gracefully. It stops accepting and claiming
+// crawls, gives the crawls running on this instance until ctx is done to finish,
+// then stops the service, releasing any crawls still running back to the queue.
+// Queued crawls stay in the job store for the next worker.
+func (q *QueueService) Shutdown(ctx context.Context) {
+	q.mu.Lock()
+	q.draining = true
+	running := len(q.activeTasks)
+	q.mu.Unlock()
+
+	if running > 0 {
+		log.Printf("Queue service no longer accepting crawls, waiting for %d running crawls to finish", running)
+	} else {
+		log.Println("Queue service no longer accepting crawls")
+	}
+
+	if remaining := q.waitForActiveTasks(ctx); remaining > 0 {
+		log.Printf("Grace period over, releasing %d running crawls back to the queue", remaining)
+	}
+	q.Stop()
+
+	countCtx, cancel := context.WithTimeout(context.Background(), q.storageTimeout)
+	defer cancel()
+	if depth, err := q.storage.CountQueuedJobs(countCtx); err == nil {
+		queued := 0
+		for _, count := range depth {
+			queued += count
+		}
+		log.Printf("%d crawls remain queued for the next worker", queued)
+	}
+}
+
+// waitForActiveTasks waits until no crawl is running on this instance or ctx is
+// done, returning how many crawls are still running
+func (q *QueueService) waitForActiveTasks(ctx context.Context) int {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.mu.RLock()
+		running := len(q.activeTasks)
+		q.mu.RUnlock()
+
+		if running == 0 {
+			return 0
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return running
+		}
+	}
+}
+
+// signal wakes an idle worker to claim newly available jobs
+func (q *QueueService) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// isStopped reports whether the queue service has stopped accepting crawls
+func (q *QueueService) isStopped() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return q.stopped || q.draining
+}
+
+// checkCapacity returns ErrQueueFull when the number of queued jobs has reached the buffer size
+func (q *QueueService) checkCapacity(ctx context.Context) error {
+	free, err := q.freeCapacity(ctx)
+	if err != nil {
+		return err
+	}
+	if free == 0 {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// freeCapacity returns how many more jobs can be queued before the buffer is full
+func (q *QueueService) freeCapacity(ctx context.Context) (int, error) {
+	depth, err := q.storage.CountQueuedJobs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check queue capacity: %w", err)
+	}
+	queued := 0
+	for _, count := range depth {
+		queued += count
+	}
+	return max(q.bufferSize-queued, 0), nil
+}
+
 // EnqueueURL adds a URL to the crawling queue
-func (q *QueueService) EnqueueURL(url string) (*models.CrawlResult, error) {
+func (q *QueueService) EnqueueURL(ctx context.Context, url string, opts EnqueueOptions) (*models.CrawlResult, error) {
 	// Validate URL
-	if err := q.crawler.ValidateURL(url); err != nil {
+	if err := q.crawler.ValidateURL(ctx, url); err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 
+	return q.enqueue(ctx, &CrawlTask{
+		ID:        uuid.New().String(),
+		URL:       url,
+		CreatedAt: time.Now(),
+		Status:    models.CrawlStatusQueued,
+		Priority:  opts.Priority.OrDefault(),
+		Owner:     opts.Owner,
+		WebhookID: opts.WebhookID,
+		BatchID:   opts.BatchID,
+	})
+}
+
+// job returns the queue entry for a task
+func (t *CrawlTask) job() *models.CrawlJob {
+	job := &models.CrawlJob{
+		ID:          t.ID,
+		URL:         t.URL,
+		Depth:       t.Depth,
+		Priority:    t.Priority.OrDefault(),
+		Status:      models.CrawlStatusQueued,
+		AvailableAt: t.CreatedAt,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.CreatedAt,
+	}
+	if t.SiteCrawlID != "" {
+		siteCrawlID := t.SiteCrawlID
+		job.SiteCrawlID = &siteCrawlID
+	}
+	if t.ParentID != "" {
+		parentID := t.ParentID
+		job.ParentID = &parentID
+	}
+	return job
+}
+
+// enqueue saves the initial record for a task and adds its job to the queue
+func (q *QueueService) enqueue(ctx context.Context, task *CrawlTask) (*models.CrawlResult, error) {
+	if q.isStopped() {
+		return nil, ErrQueueStopped
+	}
+
+	if err := q.checkCapacity(ctx); err != nil {
+		return nil, err
+	}
+
+	return q.insertTask(ctx, task)
+}
+
+// insertTask saves the result record and job of a task whose capacity was already checked
+func (q *QueueService) insertTask(ctx context.Context, task *CrawlTask) (*models.CrawlResult, error) {
 	// Create crawl result record
 	result := &models.CrawlResult{
-		ID:            uuid.New().String(),
-		URL:           url,
+		ID:            task.ID,
+		URL:           task.URL,
 		Status:        models.CrawlStatusQueued,
 		CreatedAt:     time.Now(),
 		UpdatedAt:     time.Now(),
 		HeadingCounts: models.HeadingCounts{},
 		BrokenLinks:   models.BrokenLinks{},
+		Depth:         task.Depth,
+	}
+	if task.SiteCrawlID != "" {
+		result.SiteCrawlID = &task.SiteCrawlID
+	}
+	if task.ParentID != "" {
+		result.ParentID = &task.ParentID
+	}
+	if task.Owner != "" {
+		result.Owner = &task.Owner
+	}
+	if task.WebhookID != "" {
+		result.WebhookID = &task.WebhookID
+	}
+	if task.BatchID != "" {
+		result.BatchID = &task.BatchID
 	}
 
 	// Save initial record to database
-	if err := q.storage.SaveCrawlResult(result); err != nil {
+	if err := q.storage.SaveCrawlResult(ctx, result); err != nil {
 		return nil, fmt.Errorf("failed to save crawl result: %w", err)
 	}
 
-	// Create task
-	task := &CrawlTask{
-		ID:        result.ID,
-		URL:       url,
-		CreatedAt: time.Now(),
-		Status:    models.CrawlStatusQueued,
+	if err := q.storage.EnqueueJob(ctx, task.job()); err != nil {
+		q.recordEnqueueFailure(ctx, task, err)
+		return nil, err
 	}
+	q.signal()
+	q.publish(ctx, task, models.CrawlEventQueued, models.CrawlStatusQueued, "")
 
-	// Add to active tasks
-	q.mu.Lock()
-	q.activeTasks[task.ID] = task
-	q.mu.Unlock()
+	log.Printf("Enqueued crawl task for URL: %s (ID: %s)", task.URL, result.ID)
+	return result, nil
+}
 
-	// Try to enqueue (non-blocking)
-	select {
-	case q.queue <- task:
-		log.Printf("Enqueued crawl task for URL: %s (ID: %s)", url, result.ID)
-	default:
-		// Queue is full
-		q.mu.Lock()
-		delete(q.activeTasks, task.ID)
-		q.mu.Unlock()
+// recordEnqueueFailure marks a task that could not be queued as failed
+func (q *QueueService) recordEnqueueFailure(ctx context.Context, task *CrawlTask, err error) {
+	errorMsg := "Failed to queue crawl"
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		errorMsg = "Queue is full"
+	case errors.Is(err, ErrQueueStopped):
+		errorMsg = "Queue service is stopped"
+	}
+	q.storage.UpdateCrawlStatus(ctx, task.ID, models.CrawlStatusError, &errorMsg)
+	q.publish(ctx, task, models.CrawlEventError, models.CrawlStatusError, errorMsg)
+}
 
-		// Update status to error
-		errorMsg := "Queue is full"
-		q.storage.UpdateCrawlStatus(result.ID, models.CrawlStatusError, &errorMsg)
+// EnqueueSiteCrawl starts a site crawl by enqueuing its seed URL. Every page of
+// the crawl belongs to owner, the API key that started it.
+func (q *QueueService) EnqueueSiteCrawl(ctx context.Context, req models.SiteCrawlRequest, owner string) (*models.SiteCrawl, error) {
+	req.ApplyDefaults()
 
-		return nil, fmt.Errorf("queue is full, please try again later")
+	if err := q.crawler.ValidateURL(ctx, req.URL); err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
 	}
 
-	return result, nil
+	seed, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	seed.Fragment = ""
+
+	siteCrawl := &models.SiteCrawl{
+		ID:         uuid.New().String(),
+		SeedURL:    seed.String(),
+		MaxDepth:   *req.MaxDepth,
+		MaxPages:   req.MaxPages,
+		SameDomain: *req.SameDomain,
+		Status:     models.CrawlStatusRunning,
+		Owner:      owner,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	if err := q.storage.SaveSiteCrawl(ctx, siteCrawl); err != nil {
+		return nil, fmt.Errorf("failed to save site crawl: %w", err)
+	}
+
+	_, err = q.enqueue(ctx, &CrawlTask{
+		ID:          uuid.New().String(),
+		URL:         siteCrawl.SeedURL,
+		CreatedAt:   time.Now(),
+		Status:      models.CrawlStatusQueued,
+		Priority:    req.Priority.OrDefault(),
+		Owner:       owner,
+		SiteCrawlID: siteCrawl.ID,
+	})
+	if err != nil {
+		siteCrawl.Status = models.CrawlStatusError
+		q.storage.UpdateSiteCrawlStatus(ctx, siteCrawl.ID, models.CrawlStatusError)
+		return nil, err
+	}
+
+	log.Printf("Started site crawl %s from %s (depth %d, pages %d)", siteCrawl.ID, siteCrawl.SeedURL, siteCrawl.MaxDepth, siteCrawl.MaxPages)
+	return siteCrawl, nil
+}
+
+// expandSiteCrawl enqueues the unvisited internal links of a site crawl page.
+// Storage drops links the crawl already contains and enforces the page budget.
+func (q *QueueService) expandSiteCrawl(ctx context.Context, task *CrawlTask, result *models.CrawlResult) {
+	siteCrawl, err := q.storage.GetSiteCrawl(ctx, task.SiteCrawlID)
+	if err != nil {
+		log.Printf("Site crawl %s: failed to load: %v", task.SiteCrawlID, err)
+		return
+	}
+	if task.Depth >= siteCrawl.MaxDepth {
+		return
+	}
+
+	seedHost := ""
+	if seed, err := url.Parse(siteCrawl.SeedURL); err == nil {
+		seedHost = seed.Hostname()
+	}
+
+	seen := make(map[string]bool)
+	var children []*models.CrawlJob
+	for _, link := range result.DiscoveredLinks {
+		if len(children) >= siteCrawl.MaxPages {
+			break
+		}
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		if siteCrawl.SameDomain && !sameHost(link, seedHost) {
+			continue
+		}
+		if err := q.crawler.ValidateURL(ctx, link); err != nil {
+			log.Printf("Site crawl %s: skipping %s: %v", task.SiteCrawlID, link, err)
+			continue
+		}
+
+		child := &CrawlTask{
+			ID:          uuid.New().String(),
+			URL:         link,
+			CreatedAt:   time.Now(),
+			Priority:    task.Priority,
+			SiteCrawlID: task.SiteCrawlID,
+			ParentID:    task.ID,
+			Depth:       task.Depth + 1,
+		}
+		children = append(children, child.job())
+	}
+
+	if len(children) == 0 {
+		return
+	}
+
+	added, err := q.storage.EnqueueSiteCrawlJobs(ctx, task.SiteCrawlID, siteCrawl.MaxPages, children)
+	if err != nil {
+		log.Printf("Site crawl %s: failed to enqueue pages: %v", task.SiteCrawlID, err)
+		return
+	}
+	if added > 0 {
+		q.signal()
+		log.Printf("Site crawl %s: enqueued %d pages at depth %d", task.SiteCrawlID, added, task.Depth+1)
+	}
 }
 
-// GetActiveTask returns an active task by ID
+// finishSitePage finishes a site crawl once none of its pages are queued or running
+func (q *QueueService) finishSitePage(ctx context.Context, siteCrawlID string) {
+	if siteCrawlID == "" {
+		return
+	}
+
+	done, err := q.storage.CompleteSiteCrawlIfDone(ctx, siteCrawlID)
+	if err != nil {
+		log.Printf("Failed to update site crawl %s status: %v", siteCrawlID, err)
+		return
+	}
+	if done {
+		log.Printf("Site crawl %s finished", siteCrawlID)
+	}
+}
+
+// sameHost reports whether a URL's host matches the given host
+func sameHost(link, host string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Hostname(), host)
+}
+
+// GetActiveTask returns a snapshot of a task running on this instance
 func (q *QueueService) GetActiveTask(id string) (*CrawlTask, bool) {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 
 	task, exists := q.activeTasks[id]
-	return task, exists
+	if !exists {
+		return nil, false
+	}
+
+	snapshot := *task
+	return &snapshot, true
 }
 
-// GetQueueStats returns statistics about the queue
-func (q *QueueService) GetQueueStats() map[string]interface{} {
+// GetJob returns the queue entry of a crawl
+func (q *QueueService) GetJob(ctx context.Context, id string) (*models.CrawlJob, error) {
+	return q.storage.GetJob(ctx, id)
+}
+
+// CancelTask cancels a queued or running task and returns the crawl's status
+// afterwards. A queued job is cancelled immediately. A running crawl keeps the
+// running status until its worker aborts it: straight away on this instance,
+// or at the next lease renewal on another worker.
+func (q *QueueService) CancelTask(ctx context.Context, id string) (models.CrawlStatus, error) {
+	q.mu.Lock()
+	task, exists := q.activeTasks[id]
+	if exists {
+		task.cancelled = true
+		cancel := task.cancel
+		q.mu.Unlock()
+
+		cancel()
+		log.Printf("Cancelled running crawl task %s", id)
+		return models.CrawlStatusRunning, nil
+	}
+	q.mu.Unlock()
+
+	job, err := q.storage.RequestJobCancel(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to cancel job: %w", err)
+	}
+	if job == nil {
+		return "", ErrTaskNotActive
+	}
+
+	if job.Status != models.CrawlStatusCancelled {
+		log.Printf("Requested cancellation of crawl task %s running on another worker", id)
+		return models.CrawlStatusRunning, nil
+	}
+
+	if err := q.storage.UpdateCrawlStatus(ctx, id, models.CrawlStatusCancelled, nil); err != nil {
+		return "", fmt.Errorf("failed to update status: %w", err)
+	}
+	q.publish(ctx, &CrawlTask{ID: id, Attempt: job.Attempts}, models.CrawlEventCancelled, models.CrawlStatusCancelled, "")
+	if job.SiteCrawlID != nil {
+		q.finishSitePage(ctx, *job.SiteCrawlID)
+	}
+
+	log.Printf("Cancelled queued crawl task %s", id)
+	return models.CrawlStatusCancelled, nil
+}
+
+// GetQueueStats returns statistics about the queue, aggregated across all live
+// worker processes. Fields that cannot be read from storage are omitted.
+func (q *QueueService) GetQueueStats(ctx context.Context) map[string]interface{} {
+	stats := map[string]interface{}{}
+
 	q.mu.RLock()
-	defer q.mu.RUnlock()
+	if q.running {
+		stats["worker_id"] = q.workerID
+	}
+	q.mu.RUnlock()
+
+	depth, err := q.storage.CountQueuedJobs(ctx)
+	if err != nil {
+		log.Printf("Failed to count queued jobs: %v", err)
+	} else {
+		queued := 0
+		for _, count := range depth {
+			queued += count
+		}
+		stats["queue_length"] = queued
+		stats["queue_depth"] = depth
+	}
+
+	workers, err := q.storage.GetLiveWorkers(ctx, q.leaseDuration)
+	if err != nil {
+		log.Printf("Failed to list workers: %v", err)
+		return stats
+	}
+
+	concurrency, active := 0, 0
+	for _, worker := range workers {
+		concurrency += worker.Concurrency
+		active += worker.ActiveTasks
+	}
+	stats["workers"] = concurrency
+	stats["active_tasks"] = active
+	stats["running"] = len(workers) > 0
+	stats["instances"] = workers
+
+	return stats
+}
+
+// heartbeat keeps this process registered as a live worker, reporting how many
+// crawls it is running. Heartbeats are sent as often as job leases are renewed.
+func (q *QueueService) heartbeat() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.leaseDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		q.mu.RLock()
+		worker := &models.Worker{
+			ID:          q.workerID,
+			Hostname:    q.hostname,
+			PID:         os.Getpid(),
+			Concurrency: q.workers,
+			ActiveTasks: len(q.activeTasks),
+			StartedAt:   q.startedAt,
+		}
+		q.mu.RUnlock()
+
+		if err := q.storage.HeartbeatWorker(q.ctx, worker); err != nil && q.ctx.Err() == nil {
+			log.Printf("Failed to send worker heartbeat: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-q.ctx.Done():
+			return
+		}
+	}
+}
+
+// reclaimer periodically requeues jobs whose worker stopped renewing their lease
+// and prunes workers that stopped sending heartbeats
+func (q *QueueService) reclaimer() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.leaseDuration)
+	defer ticker.Stop()
+
+	for {
+		reclaimed, err := q.storage.ReclaimExpiredJobs(q.ctx, q.maxRetries+1)
+		if err != nil && q.ctx.Err() == nil {
+			log.Printf("Failed to reclaim expired jobs: %v", err)
+		}
+		if err == nil && reclaimed.Total() > 0 {
+			log.Printf("Reclaimed %d jobs with expired leases", reclaimed.Total())
+			q.reportReclaimed(reclaimed)
+			q.signal()
+		}
+
+		// Crashed workers never deregister; forget them once they are long gone
+		if _, err := q.storage.PruneWorkers(q.ctx, workerRetention*q.leaseDuration); err != nil && q.ctx.Err() == nil {
+			log.Printf("Failed to prune workers: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-q.ctx.Done():
+			return
+		}
+	}
+}
+
+// reportReclaimed finishes the crawls the reclaimer failed or cancelled, the way
+// their worker would have had it not died
+func (q *QueueService) reportReclaimed(reclaimed *models.ReclaimedJobs) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.storageTimeout)
+	defer cancel()
+
+	for _, job := range reclaimed.Failed {
+		task := reclaimedTask(job)
+		q.finishSitePage(ctx, task.SiteCrawlID)
+		message := ""
+		if job.LastError != nil {
+			message = *job.LastError
+		}
+		q.publish(ctx, task, models.CrawlEventError, models.CrawlStatusError, message)
+		q.notify(ctx, task)
+	}
+	for _, job := range reclaimed.Cancelled {
+		task := reclaimedTask(job)
+		q.finishSitePage(ctx, task.SiteCrawlID)
+		q.publish(ctx, task, models.CrawlEventCancelled, models.CrawlStatusCancelled, "")
+	}
+}
 
-	return map[string]interface{}{
-		"queue_length": len(q.queue),
-		"active_tasks": len(q.activeTasks),
-		"workers":      q.workers,
-		"running":      q.running,
+// reclaimedTask describes a reclaimed job for reporting its outcome
+func reclaimedTask(job models.CrawlJob) *CrawlTask {
+	task := &CrawlTask{ID: job.ID, Attempt: job.Attempts}
+	if job.SiteCrawlID != nil {
+		task.SiteCrawlID = *job.SiteCrawlID
 	}
+	return task
 }
 
-// worker processes tasks from the queue
+// worker claims and processes jobs until the queue service stops
 func (q *QueueService) worker(id int) {
 	defer q.wg.Done()
 
 	log.Printf("Worker %d started", id)
 
 	for {
-		select {
-		case task, ok := <-q.queue:
-			if !ok {
-				log.Printf("Worker %d: Queue closed, exiting", id)
-				return
-			}
+		if q.ctx.Err() != nil {
+			log.Printf("Worker %d: Context cancelled, exiting", id)
+			return
+		}
 
-			q.processTask(task, id)
+		// A draining queue claims no more jobs; the worker idles until the service stops
+		if q.isStopped() {
+			<-q.ctx.Done()
+			log.Printf("Worker %d: Context cancelled, exiting", id)
+			return
+		}
+
+		lanes := q.lanes.next()
+		job, err := q.storage.ClaimJob(q.ctx, q.workerID, q.leaseDuration, lanes)
+		if err != nil && q.ctx.Err() == nil {
+			log.Printf("Worker %d: Failed to claim job: %v", id, err)
+		}
+		if job != nil {
+			q.lanes.claimed(lanes, job.Priority)
+			q.processJob(job, id)
+			continue
+		}
 
+		// Nothing to do: wait for a local enqueue, or poll for jobs queued elsewhere
+		select {
+		case <-q.wake:
+		case <-time.After(q.pollInterval):
 		case <-q.ctx.Done():
 			log.Printf("Worker %d: Context cancelled, exiting", id)
 			return
@@ -214,56 +899,338 @@ func (q *QueueService) worker(id int) {
 	}
 }
 
-// processTask handles the actual crawling of a URL
-func (q *QueueService) processTask(task *CrawlTask, workerID int) {
-	log.Printf("Worker %d: Processing task %s for URL: %s", workerID, task.ID, task.URL)
+// startTask tracks a claimed task as running and returns its context, bounded by the task timeout
+func (q *QueueService) startTask(task *CrawlTask) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(q.ctx)
+	if q.taskTimeout > 0 {
+		ctx, cancel = context.WithTimeout(q.ctx, q.taskTimeout)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
-	// Update task status to running
 	task.Status = models.CrawlStatusRunning
-	if err := q.storage.UpdateCrawlStatus(task.ID, models.CrawlStatusRunning, nil); err != nil {
+	task.cancel = cancel
+	q.activeTasks[task.ID] = task
+	return ctx, cancel
+}
+
+// finishTask stops tracking a task on this instance
+func (q *QueueService) finishTask(task *CrawlTask) {
+	q.mu.Lock()
+	delete(q.activeTasks, task.ID)
+	q.mu.Unlock()
+}
+
+// keepLease renews the lease on a task's job until the returned function is called.
+// If the lease is lost to another worker the task is abandoned, and if a cancel
+// was requested through another instance the task is cancelled.
+func (q *QueueService) keepLease(ctx context.Context, task *CrawlTask) func() {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		ticker := time.NewTicker(q.leaseDuration / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			}
+
+			held, cancelRequested, err := q.storage.ExtendJobLease(ctx, task.ID, q.workerID, q.leaseDuration)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("Failed to extend lease on task %s: %v", task.ID, err)
+				}
+				continue
+			}
+			if !held || cancelRequested {
+				if held {
+					log.Printf("Cancellation requested for task %s", task.ID)
+				} else {
+					log.Printf("Lost lease on task %s, abandoning it", task.ID)
+				}
+				q.mu.Lock()
+				task.leaseLost = !held
+				task.cancelled = cancelRequested
+				cancel := task.cancel
+				q.mu.Unlock()
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// shouldRetry reports whether a failed attempt should be retried
+func (q *QueueService) shouldRetry(task *CrawlTask, err error) bool {
+	if q.ctx.Err() != nil || !isTransient(err) {
+		return false
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return !task.cancelled && task.Attempt <= q.maxRetries
+}
+
+// scheduleRetry returns a job to the queue after a jittered exponential backoff,
+// or after the server-requested Retry-After when that is longer
+func (q *QueueService) scheduleRetry(ctx context.Context, task *CrawlTask, err error, errorMsg string) {
+	delay := retryBackoff(q.retryDelay, task.Attempt)
+	if serverDelay := retryAfter(err); serverDelay > delay {
+		delay = serverDelay
+	}
+
+	requeued, err := q.storage.RetryJob(ctx, task.ID, q.workerID, delay, errorMsg)
+	if err != nil {
+		log.Printf("Failed to schedule retry for task %s: %v", task.ID, err)
+		return
+	}
+	if !requeued {
+		// A cancel arrived during the attempt
+		q.cancelTask(ctx, task)
+		return
+	}
+
+	if err := q.storage.UpdateCrawlStatus(ctx, task.ID, models.CrawlStatusQueued, nil); err != nil {
+		log.Printf("Failed to update task %s status for retry: %v", task.ID, err)
+	}
+	q.publish(ctx, task, models.CrawlEventQueued, models.CrawlStatusQueued,
+		fmt.Sprintf("Retrying in %s: %s", delay.Round(time.Millisecond), errorMsg))
+
+	// Wake a local worker when the retry is due rather than waiting for the next poll
+	time.AfterFunc(delay, q.signal)
+
+	log.Printf("Retrying task %s (attempt %d of %d) in %s: %s", task.ID, task.Attempt+1, q.maxRetries+1, delay.Round(time.Millisecond), errorMsg)
+}
+
+// failTask records a task as permanently failed
+func (q *QueueService) failTask(ctx context.Context, task *CrawlTask, errorMsg string) {
+	if err := q.storage.UpdateCrawlStatus(ctx, task.ID, models.CrawlStatusError, &errorMsg); err != nil {
+		log.Printf("Failed to update task %s status to error: %v", task.ID, err)
+	}
+	q.recordRun(ctx, task)
+	if err := q.storage.FailJob(ctx, task.ID, q.workerID, errorMsg); err != nil {
+		log.Printf("Failed to update job %s: %v", task.ID, err)
+	}
+	q.finishSitePage(ctx, task.SiteCrawlID)
+	q.publish(ctx, task, models.CrawlEventError, models.CrawlStatusError, errorMsg)
+	q.notify(ctx, task)
+}
+
+// cancelTask records a task as cancelled, unless its lease has been lost meanwhile
+func (q *QueueService) cancelTask(ctx context.Context, task *CrawlTask) {
+	aborted, err := q.storage.AbortJob(ctx, task.ID, q.workerID)
+	if err != nil {
+		log.Printf("Failed to cancel job %s: %v", task.ID, err)
+		return
+	}
+	if !aborted {
+		log.Printf("Lost lease on task %s before it could be cancelled", task.ID)
+		return
+	}
+
+	if err := q.storage.UpdateCrawlStatus(ctx, task.ID, models.CrawlStatusCancelled, nil); err != nil {
+		log.Printf("Failed to update task %s status to cancelled: %v", task.ID, err)
+	}
+	q.recordRun(ctx, task)
+	q.finishSitePage(ctx, task.SiteCrawlID)
+	q.publish(ctx, task, models.CrawlEventCancelled, models.CrawlStatusCancelled, "")
+	log.Printf("Crawl task %s cancelled", task.ID)
+}
+
+// recordRun adds the outcome of a finished task to its crawl's run history
+func (q *QueueService) recordRun(ctx context.Context, task *CrawlTask) {
+	if err := q.storage.RecordCrawlRun(ctx, task.ID); err != nil {
+		log.Printf("Failed to record run of task %s: %v", task.ID, err)
+	}
+}
+
+// notify tells the notifier, if any, that a task reached its final state
+func (q *QueueService) notify(ctx context.Context, task *CrawlTask) {
+	if q.notifier != nil {
+		q.notifier.NotifyCrawlFinished(ctx, task.ID)
+	}
+}
+
+// releaseTask hands an interrupted task back to the queue so it is not lost on shutdown
+func (q *QueueService) releaseTask(ctx context.Context, task *CrawlTask) {
+	released, err := q.storage.ReleaseJob(ctx, task.ID, q.workerID)
+	if err != nil {
+		log.Printf("Failed to release job %s: %v", task.ID, err)
+		return
+	}
+	if !released {
+		q.cancelTask(ctx, task)
+		return
+	}
+
+	if err := q.storage.UpdateCrawlStatus(ctx, task.ID, models.CrawlStatusQueued, nil); err != nil {
+		log.Printf("Failed to update task %s status to queued: %v", task.ID, err)
+	}
+	// The interrupted attempt is not counted, matching the released job
+	note := releasedTaskNote
+	if err := q.storage.UpdateCrawlAttempts(ctx, task.ID, max(task.Attempt-1, 0), &note); err != nil {
+		log.Printf("Failed to record release of task %s: %v", task.ID, err)
+	}
+	q.publish(ctx, task, models.CrawlEventQueued, models.CrawlStatusQueued, releasedTaskNote)
+	log.Printf("Released task %s back to the queue", task.ID)
+}
+
+// failureMessage describes why a task failed, distinguishing timeouts from ordinary crawl errors
+func (q *QueueService) failureMessage(taskCtx context.Context, err error) string {
+	switch {
+	case errors.Is(taskCtx.Err(), context.DeadlineExceeded):
+		return fmt.Sprintf("Crawl timed out after %s", q.taskTimeout)
+	default:
+		return err.Error()
+	}
+}
+
+// processJob handles the actual crawling of a claimed job
+func (q *QueueService) processJob(job *models.CrawlJob, workerID int) {
+	task := &CrawlTask{
+		ID:        job.ID,
+		URL:       job.URL,
+		CreatedAt: job.CreatedAt,
+		Priority:  job.Priority,
+		Depth:     job.Depth,
+		Attempt:   job.Attempts,
+	}
+	if job.SiteCrawlID != nil {
+		task.SiteCrawlID = *job.SiteCrawlID
+	}
+	if job.ParentID != nil {
+		task.ParentID = *job.ParentID
+	}
+	if job.LastError != nil {
+		task.LastError = *job.LastError
+	}
+
+	taskCtx, cancel := q.startTask(task)
+	defer q.finishTask(task)
+	defer cancel()
+
+	log.Printf("Worker %d: Processing task %s for URL: %s (attempt %d)", workerID, task.ID, task.URL, task.Attempt)
+
+	// Update task status to running
+	if err := q.storage.UpdateCrawlStatus(taskCtx, task.ID, models.CrawlStatusRunning, nil); err != nil {
 		log.Printf("Worker %d: Failed to update task status to running: %v", workerID, err)
 	}
+	q.publish(taskCtx, task, models.CrawlEventRunning, models.CrawlStatusRunning, "")
+
+	// Perform the actual crawling while keeping the job leased
+	stopLease := q.keepLease(taskCtx, task)
+	crawlCtx := withPhaseReporter(taskCtx, func(phase models.CrawlPhase) {
+		q.publishPhase(taskCtx, task, phase)
+	})
+	result, err := q.crawler.AnalyzeURL(crawlCtx, task.URL)
+	if err == nil && taskCtx.Err() != nil {
+		err = taskCtx.Err()
+	}
+	stopLease()
+
+	// Outcomes are recorded even when the task's own context has ended. The
+	// storage timeout only starts now, as the crawl itself may take far longer.
+	persistCtx, persistCancel := context.WithTimeout(context.Background(), q.storageTimeout)
+	defer persistCancel()
+
+	q.mu.RLock()
+	leaseLost, cancelled := task.leaseLost, task.cancelled
+	q.mu.RUnlock()
+
+	if leaseLost {
+		// Another worker owns the job now and will record its outcome
+		return
+	}
 
-	// Perform the actual crawling
-	result, err := q.crawler.AnalyzeURL(task.URL)
 	if err != nil {
-		log.Printf("Worker %d: Failed to crawl URL %s: %v", workerID, task.URL, err)
+		if cancelled {
+			q.cancelTask(persistCtx, task)
+			return
+		}
+		if q.ctx.Err() != nil {
+			q.releaseTask(persistCtx, task)
+			return
+		}
 
-		// Update status to error
-		errorMsg := err.Error()
-		if updateErr := q.storage.UpdateCrawlStatus(task.ID, models.CrawlStatusError, &errorMsg); updateErr != nil {
-			log.Printf("Worker %d: Failed to update task status to error: %v", workerID, updateErr)
+		errorMsg := q.failureMessage(taskCtx, err)
+		log.Printf("Worker %d: Failed to crawl URL %s (attempt %d): %s", workerID, task.URL, task.Attempt, errorMsg)
+
+		if updateErr := q.storage.UpdateCrawlAttempts(persistCtx, task.ID, task.Attempt, &errorMsg); updateErr != nil {
+			log.Printf("Worker %d: Failed to record attempt: %v", workerID, updateErr)
 		}
-	} else {
-		// Update the result with the correct ID and save
-		result.ID = task.ID
-		result.Status = models.CrawlStatusCompleted
-		result.UpdatedAt = time.Now()
-
-		if err := q.storage.SaveCrawlResult(result); err != nil {
-			log.Printf("Worker %d: Failed to save crawl result: %v", workerID, err)
-
-			// Update status to error
-			errorMsg := "Failed to save crawl result"
-			q.storage.UpdateCrawlStatus(task.ID, models.CrawlStatusError, &errorMsg)
-		} else {
-			log.Printf("Worker %d: Successfully completed crawl for URL: %s", workerID, task.URL)
+
+		if q.shouldRetry(task, err) {
+			q.scheduleRetry(persistCtx, task, err, errorMsg)
+			return
 		}
+
+		q.failTask(persistCtx, task, errorMsg)
+		return
 	}
 
-	// Remove from active tasks
-	q.mu.Lock()
-	delete(q.activeTasks, task.ID)
-	q.mu.Unlock()
+	// A crawl deleted while it ran has lost its job, and saving would bring its result back
+	if held, _, err := q.storage.ExtendJobLease(persistCtx, task.ID, q.workerID, q.leaseDuration); err == nil && !held {
+		log.Printf("Worker %d: Task %s was deleted or reassigned, discarding its result", workerID, task.ID)
+		return
+	}
+
+	// Update the result with the correct ID and save
+	result.ID = task.ID
+	result.Status = models.CrawlStatusCompleted
+	result.Attempts = task.Attempt
+	result.UpdatedAt = time.Now()
+
+	q.publishPhase(persistCtx, task, models.CrawlPhaseSaving)
+	if err := q.storage.SaveCrawlResult(persistCtx, result); err != nil {
+		log.Printf("Worker %d: Failed to save crawl result: %v", workerID, err)
+		q.failTask(persistCtx, task, "Failed to save crawl result")
+		return
+	}
+
+	q.recordRun(persistCtx, task)
+	log.Printf("Worker %d: Successfully completed crawl for URL: %s", workerID, task.URL)
+
+	// Child pages are queued before this job completes, so the site crawl cannot look finished in between
+	if task.SiteCrawlID != "" {
+		q.expandSiteCrawl(persistCtx, task, result)
+	}
+
+	if err := q.storage.CompleteJob(persistCtx, task.ID, q.workerID); err != nil {
+		log.Printf("Worker %d: Failed to complete job %s: %v", workerID, task.ID, err)
+	}
+	q.finishSitePage(persistCtx, task.SiteCrawlID)
+	q.publish(persistCtx, task, models.CrawlEventCompleted, models.CrawlStatusCompleted, "")
+	q.notify(persistCtx, task)
 }
 
-// RequeueTask re-adds a task to the queue (for re-running analysis)
-func (q *QueueService) RequeueTask(id string) error {
+// RequeueTask re-adds a task to the queue (for re-running analysis). When the
+// task cannot be queued its stored result is left as it was. Crawls that are
+// still queued or running are refused with ErrTaskActive, since re-enqueuing
+// their job would take it away from the worker holding its lease.
+func (q *QueueService) RequeueTask(ctx context.Context, id string) error {
 	// Get the existing crawl result
-	result, err := q.storage.GetCrawlResult(id)
+	result, err := q.storage.GetCrawlResult(ctx, id)
 	if err != nil {
 		return fmt.Errorf("failed to get crawl result: %w", err)
 	}
+	if result.Status.IsActive() {
+		return ErrTaskActive
+	}
 
 	// Create new task
 	task := &CrawlTask{
@@ -271,31 +1238,42 @@ func (q *QueueService) RequeueTask(id string) error {
 		URL:       result.URL,
 		CreatedAt: time.Now(),
 		Status:    models.CrawlStatusQueued,
+		Priority:  models.CrawlPriorityNormal,
+		Depth:     result.Depth,
+	}
+	// A rerun stays in the lane the crawl was originally queued in
+	if job, err := q.storage.GetJob(ctx, id); err == nil {
+		if job.Status.IsActive() {
+			return ErrTaskActive
+		}
+		task.Priority = job.Priority.OrDefault()
+	}
+	if result.SiteCrawlID != nil {
+		task.SiteCrawlID = *result.SiteCrawlID
+	}
+	if result.ParentID != nil {
+		task.ParentID = *result.ParentID
+	}
+
+	if q.isStopped() {
+		return ErrQueueStopped
+	}
+	if err := q.checkCapacity(ctx); err != nil {
+		return err
 	}
 
 	// Update status to queued
-	if err := q.storage.UpdateCrawlStatus(id, models.CrawlStatusQueued, nil); err != nil {
+	if err := q.storage.UpdateCrawlStatus(ctx, id, models.CrawlStatusQueued, nil); err != nil {
 		return fmt.Errorf("failed to update status: %w", err)
 	}
 
-	// Add to active tasks
-	q.mu.Lock()
-	q.activeTasks[task.ID] = task
-	q.mu.Unlock()
-
-	select {
-	case q.queue <- task:
-		log.Printf("Re-queued crawl task for URL: %s (ID: %s)", result.URL, id)
-		return nil
-	default:
-		// Queue is full
-		q.mu.Lock()
-		delete(q.activeTasks, task.ID)
-		q.mu.Unlock()
-
-		errorMsg := "Queue is full"
-		q.storage.UpdateCrawlStatus(id, models.CrawlStatusError, &errorMsg)
-
-		return fmt.Errorf("queue is full, please try again later")
+	if err := q.storage.EnqueueJob(ctx, task.job()); err != nil {
+		q.storage.UpdateCrawlStatus(ctx, id, result.Status, result.ErrorMessage)
+		return err
 	}
+	q.signal()
+	q.publish(ctx, task, models.CrawlEventQueued, models.CrawlStatusQueued, "")
+
+	log.Printf("Re-queued crawl task for URL: %s (ID: %s)", result.URL, id)
+	return nil
 }
@@ -0,0 +1,1506 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+// fakeCrawler returns canned results keyed by URL
+type fakeCrawler struct {
+	links map[string][]string
+}
+
+func (f *fakeCrawler) AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error) {
+	return &models.CrawlResult{
+		URL:             targetURL,
+		HeadingCounts:   models.HeadingCounts{},
+		BrokenLinks:     models.BrokenLinks{},
+		DiscoveredLinks: f.links[targetURL],
+	}, nil
+}
+
+func (f *fakeCrawler) ValidateURL(ctx context.Context, targetURL string) error {
+	return nil
+}
+
+// blockingCrawler blocks every crawl until its context is done
+type blockingCrawler struct {
+	started chan string
+}
+
+func (b *blockingCrawler) AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error) {
+	b.started <- targetURL
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (b *blockingCrawler) ValidateURL(ctx context.Context, targetURL string) error {
+	return nil
+}
+
+// flakyCrawler fails with a fixed error a number of times before succeeding
+type flakyCrawler struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyCrawler) AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, f.err
+	}
+	return &models.CrawlResult{URL: targetURL}, nil
+}
+
+func (f *flakyCrawler) ValidateURL(ctx context.Context, targetURL string) error {
+	return nil
+}
+
+func (f *flakyCrawler) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+// memoryStorage is an in-memory CrawlStorage
+type memoryStorage struct {
+	mu         sync.Mutex
+	results    map[string]*models.CrawlResult
+	siteCrawls map[string]*models.SiteCrawl
+	jobs       map[string]*models.CrawlJob
+	workers    map[string]*models.Worker
+	runs       map[string][]models.CrawlRun
+	batches    map[string]*models.Batch
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		results:    make(map[string]*models.CrawlResult),
+		siteCrawls: make(map[string]*models.SiteCrawl),
+		jobs:       make(map[string]*models.CrawlJob),
+		runs:       make(map[string][]models.CrawlRun),
+		workers:    make(map[string]*models.Worker),
+		batches:    make(map[string]*models.Batch),
+	}
+}
+
+func (m *memoryStorage) SaveCrawlResult(ctx context.Context, result *models.CrawlResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *result
+	if existing, ok := m.results[result.ID]; ok {
+		saved.ParentID = existing.ParentID
+		saved.SiteCrawlID = existing.SiteCrawlID
+		saved.Depth = existing.Depth
+		saved.LastError = existing.LastError
+		saved.Owner = existing.Owner
+		saved.WebhookID = existing.WebhookID
+		saved.BatchID = existing.BatchID
+	}
+	m.results[result.ID] = &saved
+	return nil
+}
+
+func (m *memoryStorage) UpdateCrawlStatus(ctx context.Context, id string, status models.CrawlStatus, errorMsg *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result, ok := m.results[id]
+	if !ok {
+		return fmt.Errorf("crawl result not found")
+	}
+	result.Status = status
+	result.ErrorMessage = errorMsg
+	return nil
+}
+
+func (m *memoryStorage) UpdateCrawlAttempts(ctx context.Context, id string, attempts int, lastError *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result, ok := m.results[id]
+	if !ok {
+		return fmt.Errorf("crawl result not found")
+	}
+	result.Attempts = attempts
+	result.LastError = lastError
+	return nil
+}
+
+func (m *memoryStorage) RecordCrawlRun(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.recordRun(id)
+	return nil
+}
+
+func (m *memoryStorage) recordRun(id string) {
+	result, ok := m.results[id]
+	if !ok {
+		return
+	}
+	m.runs[id] = append(m.runs[id], models.CrawlRun{
+		CrawlID:      id,
+		RunNumber:    len(m.runs[id]) + 1,
+		URL:          result.URL,
+		Title:        result.Title,
+		Status:       result.Status,
+		ErrorMessage: result.ErrorMessage,
+		Attempts:     result.Attempts,
+		FinishedAt:   result.UpdatedAt,
+	})
+}
+
+func (m *memoryStorage) crawlRuns(id string) []models.CrawlRun {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]models.CrawlRun(nil), m.runs[id]...)
+}
+
+func (m *memoryStorage) GetCrawlResult(ctx context.Context, id string) (*models.CrawlResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result, ok := m.results[id]
+	if !ok {
+		return nil, fmt.Errorf("crawl result not found")
+	}
+	copied := *result
+	return &copied, nil
+}
+
+func (m *memoryStorage) SaveSiteCrawl(ctx context.Context, siteCrawl *models.SiteCrawl) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *siteCrawl
+	m.siteCrawls[siteCrawl.ID] = &saved
+	return nil
+}
+
+func (m *memoryStorage) SaveBatch(ctx context.Context, batch *models.Batch) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *batch
+	m.batches[batch.ID] = &saved
+	return nil
+}
+
+func (m *memoryStorage) UpdateSiteCrawlStatus(ctx context.Context, id string, status models.CrawlStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	siteCrawl, ok := m.siteCrawls[id]
+	if !ok {
+		return fmt.Errorf("site crawl not found")
+	}
+	siteCrawl.Status = status
+	return nil
+}
+
+func (m *memoryStorage) GetSiteCrawl(ctx context.Context, id string) (*models.SiteCrawl, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	siteCrawl, ok := m.siteCrawls[id]
+	if !ok {
+		return nil, fmt.Errorf("site crawl not found")
+	}
+	copied := *siteCrawl
+	return &copied, nil
+}
+
+func (m *memoryStorage) EnqueueSiteCrawlJobs(ctx context.Context, siteCrawlID string, maxPages int, jobs []*models.CrawlJob) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	visited := make(map[string]bool)
+	for _, result := range m.results {
+		if result.SiteCrawlID != nil && *result.SiteCrawlID == siteCrawlID {
+			visited[result.URL] = true
+		}
+	}
+
+	added := 0
+	for _, job := range jobs {
+		if len(visited) >= maxPages {
+			break
+		}
+		if visited[job.URL] {
+			continue
+		}
+		visited[job.URL] = true
+		m.results[job.ID] = &models.CrawlResult{
+			ID:          job.ID,
+			URL:         job.URL,
+			Status:      models.CrawlStatusQueued,
+			ParentID:    job.ParentID,
+			SiteCrawlID: job.SiteCrawlID,
+			Depth:       job.Depth,
+		}
+		if siteCrawl, ok := m.siteCrawls[siteCrawlID]; ok && siteCrawl.Owner != "" {
+			owner := siteCrawl.Owner
+			m.results[job.ID].Owner = &owner
+		}
+		m.enqueueJob(job)
+		added++
+	}
+	return added, nil
+}
+
+func (m *memoryStorage) CompleteSiteCrawlIfDone(ctx context.Context, siteCrawlID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	siteCrawl, ok := m.siteCrawls[siteCrawlID]
+	if !ok || siteCrawl.Status != models.CrawlStatusRunning {
+		return false, nil
+	}
+	status := models.CrawlStatusCompleted
+	for _, job := range m.jobs {
+		if job.SiteCrawlID == nil || *job.SiteCrawlID != siteCrawlID {
+			continue
+		}
+		if job.Status == models.CrawlStatusQueued || job.Status == models.CrawlStatusRunning {
+			return false, nil
+		}
+		if job.Depth == 0 && job.Status == models.CrawlStatusCancelled {
+			status = models.CrawlStatusCancelled
+		}
+	}
+	siteCrawl.Status = status
+	return true, nil
+}
+
+func (m *memoryStorage) enqueueJob(job *models.CrawlJob) {
+	queued := *job
+	queued.Priority = job.Priority.OrDefault()
+	queued.Status = models.CrawlStatusQueued
+	queued.Attempts = 0
+	queued.LastError = nil
+	queued.AvailableAt = time.Now()
+	queued.LeaseOwner = nil
+	queued.LeaseExpiresAt = nil
+	queued.CancelRequested = false
+	m.jobs[job.ID] = &queued
+}
+
+func (m *memoryStorage) EnqueueJob(ctx context.Context, job *models.CrawlJob) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.enqueueJob(job)
+	return nil
+}
+
+func (m *memoryStorage) ClaimJob(ctx context.Context, owner string, lease time.Duration, lanes []models.CrawlPriority) (*models.CrawlJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var next *models.CrawlJob
+	for _, lane := range lanes {
+		for _, job := range m.jobs {
+			if job.Status != models.CrawlStatusQueued || job.Priority != lane || job.AvailableAt.After(now) {
+				continue
+			}
+			if next == nil || job.AvailableAt.Before(next.AvailableAt) {
+				next = job
+			}
+		}
+		if next != nil {
+			break
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	expires := now.Add(lease)
+	next.Status = models.CrawlStatusRunning
+	next.Attempts++
+	next.LeaseOwner = &owner
+	next.LeaseExpiresAt = &expires
+
+	claimed := *next
+	return &claimed, nil
+}
+
+// leasedJob returns the job if owner holds its lease
+func (m *memoryStorage) leasedJob(id, owner string) *models.CrawlJob {
+	job, ok := m.jobs[id]
+	if !ok || job.LeaseOwner == nil || *job.LeaseOwner != owner {
+		return nil
+	}
+	return job
+}
+
+func (m *memoryStorage) ExtendJobLease(ctx context.Context, id, owner string, lease time.Duration) (bool, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job := m.leasedJob(id, owner)
+	if job == nil || job.Status != models.CrawlStatusRunning {
+		return false, false, nil
+	}
+	expires := time.Now().Add(lease)
+	job.LeaseExpiresAt = &expires
+	return true, job.CancelRequested, nil
+}
+
+func (m *memoryStorage) finishJob(id, owner string, status models.CrawlStatus, lastError *string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job := m.leasedJob(id, owner)
+	if job == nil {
+		return false
+	}
+	job.Status = status
+	if lastError != nil {
+		job.LastError = lastError
+	}
+	job.LeaseOwner = nil
+	job.LeaseExpiresAt = nil
+	job.CancelRequested = false
+	return true
+}
+
+func (m *memoryStorage) CompleteJob(ctx context.Context, id, owner string) error {
+	m.finishJob(id, owner, models.CrawlStatusCompleted, nil)
+	return nil
+}
+
+func (m *memoryStorage) FailJob(ctx context.Context, id, owner string, errorMsg string) error {
+	m.finishJob(id, owner, models.CrawlStatusError, &errorMsg)
+	return nil
+}
+
+func (m *memoryStorage) AbortJob(ctx context.Context, id, owner string) (bool, error) {
+	return m.finishJob(id, owner, models.CrawlStatusCancelled, nil), nil
+}
+
+func (m *memoryStorage) RetryJob(ctx context.Context, id, owner string, delay time.Duration, lastError string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job := m.leasedJob(id, owner)
+	if job == nil || job.CancelRequested {
+		return false, nil
+	}
+	job.Status = models.CrawlStatusQueued
+	job.LastError = &lastError
+	job.AvailableAt = time.Now().Add(delay)
+	job.LeaseOwner = nil
+	job.LeaseExpiresAt = nil
+	return true, nil
+}
+
+func (m *memoryStorage) ReleaseJob(ctx context.Context, id, owner string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job := m.leasedJob(id, owner)
+	if job == nil || job.CancelRequested {
+		return false, nil
+	}
+	job.Status = models.CrawlStatusQueued
+	job.Attempts = max(job.Attempts-1, 0)
+	job.AvailableAt = time.Now()
+	job.LeaseOwner = nil
+	job.LeaseExpiresAt = nil
+	return true, nil
+}
+
+func (m *memoryStorage) RequestJobCancel(ctx context.Context, id string) (*models.CrawlJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	switch job.Status {
+	case models.CrawlStatusQueued:
+		job.Status = models.CrawlStatusCancelled
+	case models.CrawlStatusRunning:
+		job.CancelRequested = true
+	default:
+		return nil, nil
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (m *memoryStorage) ReclaimExpiredJobs(ctx context.Context, maxAttempts int) (*models.ReclaimedJobs, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reclaimed := &models.ReclaimedJobs{}
+	for _, job := range m.jobs {
+		if job.Status != models.CrawlStatusRunning || job.LeaseExpiresAt == nil || job.LeaseExpiresAt.After(time.Now()) {
+			continue
+		}
+		status := models.CrawlStatusQueued
+		switch {
+		case job.CancelRequested:
+			status = models.CrawlStatusCancelled
+		case job.Attempts >= maxAttempts:
+			status = models.CrawlStatusError
+			errorMsg := "Worker lease expired too many times"
+			job.LastError = &errorMsg
+		}
+		job.CancelRequested = false
+		job.Status = status
+		job.AvailableAt = time.Now()
+		job.LeaseOwner = nil
+		job.LeaseExpiresAt = nil
+		if result, ok := m.results[job.ID]; ok {
+			result.Status = status
+		}
+		switch status {
+		case models.CrawlStatusQueued:
+			reclaimed.Requeued++
+		case models.CrawlStatusCancelled:
+			m.recordRun(job.ID)
+			reclaimed.Cancelled = append(reclaimed.Cancelled, *job)
+		default:
+			m.recordRun(job.ID)
+			reclaimed.Failed = append(reclaimed.Failed, *job)
+		}
+	}
+	return reclaimed, nil
+}
+
+func (m *memoryStorage) CountQueuedJobs(ctx context.Context) (map[models.CrawlPriority]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[models.CrawlPriority]int)
+	for _, lane := range models.CrawlPriorities {
+		counts[lane] = 0
+	}
+	for _, job := range m.jobs {
+		if job.Status == models.CrawlStatusQueued {
+			counts[job.Priority]++
+		}
+	}
+	return counts, nil
+}
+
+func (m *memoryStorage) GetJob(ctx context.Context, id string) (*models.CrawlJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("crawl job not found")
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (m *memoryStorage) HeartbeatWorker(ctx context.Context, worker *models.Worker) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *worker
+	saved.LastHeartbeatAt = time.Now()
+	m.workers[worker.ID] = &saved
+	return nil
+}
+
+func (m *memoryStorage) RemoveWorker(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.workers, id)
+	return nil
+}
+
+func (m *memoryStorage) PruneWorkers(ctx context.Context, maxAge time.Duration) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pruned := 0
+	for id, worker := range m.workers {
+		if time.Since(worker.LastHeartbeatAt) > maxAge {
+			delete(m.workers, id)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (m *memoryStorage) GetLiveWorkers(ctx context.Context, maxAge time.Duration) ([]models.Worker, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	workers := []models.Worker{}
+	for _, worker := range m.workers {
+		if time.Since(worker.LastHeartbeatAt) <= maxAge {
+			workers = append(workers, *worker)
+		}
+	}
+	return workers, nil
+}
+
+func (m *memoryStorage) siteCrawlStatus(id string) models.CrawlStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.siteCrawls[id].Status
+}
+
+func (m *memoryStorage) pages(siteCrawlID string) map[string]*models.CrawlResult {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pages := make(map[string]*models.CrawlResult)
+	for _, result := range m.results {
+		if result.SiteCrawlID != nil && *result.SiteCrawlID == siteCrawlID {
+			copied := *result
+			pages[result.URL] = &copied
+		}
+	}
+	return pages
+}
+
+func newTestQueue(crawler Crawler, storage CrawlStorage) *QueueService {
+	return NewQueueServiceWithConfig(config.QueueConfig{
+		Workers:    2,
+		BufferSize: 100,
+	}, crawler, storage)
+}
+
+func waitForErrorMessage(t *testing.T, storage *memoryStorage, id, expected string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		result, err := storage.GetCrawlResult(context.Background(), id)
+		if err == nil && result.Status == models.CrawlStatusError && result.ErrorMessage != nil {
+			if *result.ErrorMessage != expected {
+				t.Fatalf("error message = %q, expected %q", *result.ErrorMessage, expected)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("crawl %s was not marked as failed", id)
+}
+
+func waitForStatus(t *testing.T, storage *memoryStorage, id string, expected models.CrawlStatus) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		result, err := storage.GetCrawlResult(context.Background(), id)
+		if err == nil && result.Status == expected {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("crawl %s did not reach status %s", id, expected)
+}
+
+func waitForSiteCrawl(t *testing.T, storage *memoryStorage, id string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if storage.siteCrawlStatus(id) == models.CrawlStatusCompleted {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("site crawl %s did not complete", id)
+}
+
+func TestEnqueueSiteCrawl(t *testing.T) {
+	crawler := &fakeCrawler{links: map[string][]string{
+		"https://example.com/": {
+			"https://example.com/a",
+			"https://example.com/b",
+			"https://blog.example.com/",
+		},
+		"https://example.com/a": {
+			"https://example.com/",
+			"https://example.com/a/deep",
+		},
+		"https://example.com/b": {
+			"https://example.com/b/deep",
+		},
+		"https://example.com/a/deep": {
+			"https://example.com/too-deep",
+		},
+	}}
+
+	sameDomain := true
+	seedOnly, maxDepth := 0, 2
+	tests := []struct {
+		name     string
+		request  models.SiteCrawlRequest
+		expected map[string]int
+	}{
+		{
+			name:    "depth limit",
+			request: models.SiteCrawlRequest{URL: "https://example.com/", MaxDepth: &maxDepth, MaxPages: 50, SameDomain: &sameDomain},
+			expected: map[string]int{
+				"https://example.com/":       0,
+				"https://example.com/a":      1,
+				"https://example.com/b":      1,
+				"https://example.com/a/deep": 2,
+				"https://example.com/b/deep": 2,
+			},
+		},
+		{
+			name:    "page limit",
+			request: models.SiteCrawlRequest{URL: "https://example.com/", MaxDepth: &maxDepth, MaxPages: 2, SameDomain: &sameDomain},
+			expected: map[string]int{
+				"https://example.com/":  0,
+				"https://example.com/a": 1,
+			},
+		},
+		{
+			name:    "seed only",
+			request: models.SiteCrawlRequest{URL: "https://example.com/", MaxDepth: &seedOnly, MaxPages: 50, SameDomain: &sameDomain},
+			expected: map[string]int{
+				"https://example.com/": 0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newMemoryStorage()
+			queue := newTestQueue(crawler, storage)
+			queue.Start()
+			defer queue.Stop()
+
+			siteCrawl, err := queue.EnqueueSiteCrawl(context.Background(), tt.request, "team-a")
+			if err != nil {
+				t.Fatalf("EnqueueSiteCrawl() error = %v", err)
+			}
+			waitForSiteCrawl(t, storage, siteCrawl.ID)
+
+			pages := storage.pages(siteCrawl.ID)
+			if len(pages) != len(tt.expected) {
+				t.Fatalf("crawled %d pages, expected %d: %v", len(pages), len(tt.expected), pages)
+			}
+
+			for url, depth := range tt.expected {
+				page, ok := pages[url]
+				if !ok {
+					t.Errorf("expected %s to be crawled", url)
+					continue
+				}
+				if page.Depth != depth {
+					t.Errorf("%s depth = %d, expected %d", url, page.Depth, depth)
+				}
+				if page.Status != models.CrawlStatusCompleted {
+					t.Errorf("%s status = %s, expected completed", url, page.Status)
+				}
+				if page.Owner == nil || *page.Owner != "team-a" {
+					t.Errorf("%s owner = %v, expected the site crawl's owner", url, page.Owner)
+				}
+				if (depth == 0) != (page.ParentID == nil) {
+					t.Errorf("%s parent = %v, expected a parent only below the seed", url, page.ParentID)
+				}
+			}
+		})
+	}
+}
+
+func TestCancelSiteCrawlSeed(t *testing.T) {
+	storage := newMemoryStorage()
+	queue := newTestQueue(&fakeCrawler{}, storage)
+
+	// Without workers the seed page stays queued
+	ctx := context.Background()
+	siteCrawl, err := queue.EnqueueSiteCrawl(ctx, models.SiteCrawlRequest{URL: "https://example.com/"}, "")
+	if err != nil {
+		t.Fatalf("EnqueueSiteCrawl() error = %v", err)
+	}
+
+	seed := storage.pages(siteCrawl.ID)["https://example.com/"]
+	if seed == nil {
+		t.Fatal("seed page was not queued")
+	}
+	if _, err := queue.CancelTask(ctx, seed.ID); err != nil {
+		t.Fatalf("CancelTask() error = %v", err)
+	}
+
+	if status := storage.siteCrawlStatus(siteCrawl.ID); status != models.CrawlStatusCancelled {
+		t.Errorf("site crawl status = %s, expected cancelled", status)
+	}
+}
+
+func TestQueueTaskTimeout(t *testing.T) {
+	storage := newMemoryStorage()
+	crawler := &blockingCrawler{started: make(chan string, 1)}
+	queue := NewQueueServiceWithConfig(config.QueueConfig{
+		Workers:     1,
+		BufferSize:  10,
+		TaskTimeout: 50 * time.Millisecond,
+	}, crawler, storage)
+	queue.Start()
+	defer queue.Stop()
+
+	result, err := queue.EnqueueURL(context.Background(), "https://example.com/slow", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+
+	waitForErrorMessage(t, storage, result.ID, "Crawl timed out after 50ms")
+}
+
+func TestQueueCancelTask(t *testing.T) {
+	storage := newMemoryStorage()
+	crawler := &blockingCrawler{started: make(chan string, 2)}
+	queue := NewQueueServiceWithConfig(config.QueueConfig{
+		Workers:    1,
+		BufferSize: 10,
+	}, crawler, storage)
+	queue.Start()
+	defer queue.Stop()
+
+	ctx := context.Background()
+
+	running, err := queue.EnqueueURL(ctx, "https://example.com/running", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+	<-crawler.started
+
+	// The only worker is busy, so this task stays queued
+	queued, err := queue.EnqueueURL(ctx, "https://example.com/queued", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+
+	status, err := queue.CancelTask(ctx, queued.ID)
+	if err != nil {
+		t.Fatalf("CancelTask(queued) error = %v", err)
+	}
+	if status != models.CrawlStatusCancelled {
+		t.Errorf("CancelTask(queued) status = %s, expected cancelled", status)
+	}
+	waitForStatus(t, storage, queued.ID, models.CrawlStatusCancelled)
+
+	status, err = queue.CancelTask(ctx, running.ID)
+	if err != nil {
+		t.Fatalf("CancelTask(running) error = %v", err)
+	}
+	if status != models.CrawlStatusRunning {
+		t.Errorf("CancelTask(running) status = %s, expected running until the worker aborts", status)
+	}
+	waitForStatus(t, storage, running.ID, models.CrawlStatusCancelled)
+
+	select {
+	case url := <-crawler.started:
+		t.Errorf("cancelled queued task was crawled: %s", url)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if _, err := queue.CancelTask(ctx, running.ID); !errors.Is(err, ErrTaskNotActive) {
+		t.Errorf("CancelTask(finished) error = %v, expected ErrTaskNotActive", err)
+	}
+}
+
+func TestQueueCancelTaskOnAnotherWorker(t *testing.T) {
+	storage := newMemoryStorage()
+	crawler := &blockingCrawler{started: make(chan string, 1)}
+	worker := NewQueueServiceWithConfig(config.QueueConfig{
+		Workers:       1,
+		BufferSize:    10,
+		LeaseDuration: 30 * time.Millisecond,
+	}, crawler, storage)
+	worker.Start()
+	defer worker.Stop()
+
+	// An API-only instance sharing the store, without local workers
+	api := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 10}, crawler, storage)
+	ctx := context.Background()
+
+	result, err := api.EnqueueURL(ctx, "https://example.com/remote", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+	<-crawler.started
+
+	status, err := api.CancelTask(ctx, result.ID)
+	if err != nil {
+		t.Fatalf("CancelTask() error = %v", err)
+	}
+	if status != models.CrawlStatusRunning {
+		t.Errorf("CancelTask() status = %s, expected running until the worker aborts", status)
+	}
+
+	// The worker notices the request when it renews its lease
+	waitForStatus(t, storage, result.ID, models.CrawlStatusCancelled)
+
+	job, err := storage.GetJob(ctx, result.ID)
+	if err != nil || job.Status != models.CrawlStatusCancelled {
+		t.Errorf("job = %+v (error %v), expected it to be cancelled", job, err)
+	}
+}
+
+func TestQueueStopReleasesRunningTasks(t *testing.T) {
+	storage := newMemoryStorage()
+	crawler := &blockingCrawler{started: make(chan string, 1)}
+	queue := NewQueueServiceWithConfig(config.QueueConfig{
+		Workers:    1,
+		BufferSize: 10,
+	}, crawler, storage)
+	queue.Start()
+
+	ctx := context.Background()
+
+	result, err := queue.EnqueueURL(ctx, "https://example.com/running", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+	<-crawler.started
+
+	stopped := make(chan struct{})
+	go func() {
+		queue.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() did not abort the running task")
+	}
+
+	// The interrupted crawl goes back to the queue for the next worker
+	saved, err := storage.GetCrawlResult(ctx, result.ID)
+	if err != nil || saved.Status != models.CrawlStatusQueued {
+		t.Errorf("crawl after Stop = %+v (error %v), expected it to be queued again", saved, err)
+	}
+	job, err := storage.GetJob(ctx, result.ID)
+	if err != nil || job.Status != models.CrawlStatusQueued || job.Attempts != 0 || job.LeaseOwner != nil {
+		t.Errorf("job after Stop = %+v (error %v), expected an unleased queued job without a counted attempt", job, err)
+	}
+
+	if _, err := queue.EnqueueURL(ctx, "https://example.com/late", EnqueueOptions{}); !errors.Is(err, ErrQueueStopped) {
+		t.Errorf("EnqueueURL() after Stop error = %v, expected ErrQueueStopped", err)
+	}
+}
+
+// gatedCrawler blocks every crawl until the gate opens or its context is done
+type gatedCrawler struct {
+	started chan string
+	gate    chan struct{}
+}
+
+func (g *gatedCrawler) AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error) {
+	g.started <- targetURL
+	select {
+	case <-g.gate:
+		return &models.CrawlResult{URL: targetURL}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *gatedCrawler) ValidateURL(ctx context.Context, targetURL string) error {
+	return nil
+}
+
+func TestQueueDiscardsResultOfDeletedCrawl(t *testing.T) {
+	storage := newMemoryStorage()
+	crawler := &gatedCrawler{started: make(chan string, 1), gate: make(chan struct{})}
+	queue := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 10}, crawler, storage)
+	queue.Start()
+	defer queue.Stop()
+
+	result, err := queue.EnqueueURL(context.Background(), "https://example.com/deleted", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+	<-crawler.started
+
+	// Deleting a crawl removes its result and its job
+	storage.mu.Lock()
+	delete(storage.results, result.ID)
+	delete(storage.jobs, result.ID)
+	storage.mu.Unlock()
+	close(crawler.gate)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, active := queue.GetActiveTask(result.ID); !active {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("task did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := storage.GetCrawlResult(context.Background(), result.ID); err == nil {
+		t.Error("result of the deleted crawl was saved again")
+	}
+}
+
+func TestQueueShutdownWaitsForRunningTasks(t *testing.T) {
+	storage := newMemoryStorage()
+	crawler := &gatedCrawler{started: make(chan string, 1), gate: make(chan struct{})}
+	queue := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 10}, crawler, storage)
+	queue.Start()
+
+	ctx := context.Background()
+	result, err := queue.EnqueueURL(ctx, "https://example.com/running", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+	<-crawler.started
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		queue.Shutdown(shutdownCtx)
+		close(stopped)
+	}()
+
+	// New crawls are refused while the running one is given time to finish
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err := queue.EnqueueURL(ctx, "https://example.com/late", EnqueueOptions{})
+		if errors.Is(err, ErrQueueStopped) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("EnqueueURL() during shutdown error = %v, expected ErrQueueStopped", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-stopped:
+		t.Fatal("Shutdown() returned before the running crawl finished")
+	default:
+	}
+
+	close(crawler.gate)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown() did not return after the running crawl finished")
+	}
+
+	saved, err := storage.GetCrawlResult(ctx, result.ID)
+	if err != nil || saved.Status != models.CrawlStatusCompleted {
+		t.Errorf("crawl after Shutdown = %+v (error %v), expected it to be completed", saved, err)
+	}
+}
+
+func TestQueueShutdownReleasesTasksAfterGracePeriod(t *testing.T) {
+	storage := newMemoryStorage()
+	crawler := &blockingCrawler{started: make(chan string, 1)}
+	queue := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 10}, crawler, storage)
+	queue.Start()
+
+	ctx := context.Background()
+	result, err := queue.EnqueueURL(ctx, "https://example.com/running", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+	<-crawler.started
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	queue.Shutdown(shutdownCtx)
+
+	saved, err := storage.GetCrawlResult(ctx, result.ID)
+	if err != nil {
+		t.Fatalf("GetCrawlResult() error = %v", err)
+	}
+	if saved.Status != models.CrawlStatusQueued || saved.Attempts != 0 {
+		t.Errorf("crawl after Shutdown = %+v, expected it to be queued again without a counted attempt", saved)
+	}
+	if saved.LastError == nil || *saved.LastError != releasedTaskNote {
+		t.Errorf("crawl note = %v, expected %q", saved.LastError, releasedTaskNote)
+	}
+	job, err := storage.GetJob(ctx, result.ID)
+	if err != nil || job.Status != models.CrawlStatusQueued || job.LeaseOwner != nil {
+		t.Errorf("job after Shutdown = %+v (error %v), expected an unleased queued job", job, err)
+	}
+}
+
+func waitForFinalStatus(t *testing.T, queue *QueueService, storage *memoryStorage, id string) *models.CrawlResult {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		result, err := storage.GetCrawlResult(context.Background(), id)
+		_, active := queue.GetActiveTask(id)
+		if err == nil && !active && (result.Status == models.CrawlStatusCompleted || result.Status == models.CrawlStatusError) {
+			return result
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("crawl %s did not finish", id)
+	return nil
+}
+
+func TestQueueRetries(t *testing.T) {
+	unavailable := &HTTPStatusError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"}
+	notFound := &HTTPStatusError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}
+
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		status    models.CrawlStatus
+		attempts  int
+		lastError string
+	}{
+		{
+			name:      "transient failure recovers",
+			failures:  2,
+			err:       unavailable,
+			status:    models.CrawlStatusCompleted,
+			attempts:  3,
+			lastError: "received HTTP status 503 Service Unavailable",
+		},
+		{
+			name:      "transient failure exhausts retries",
+			failures:  10,
+			err:       unavailable,
+			status:    models.CrawlStatusError,
+			attempts:  4,
+			lastError: "received HTTP status 503 Service Unavailable",
+		},
+		{
+			name:      "permanent failure is not retried",
+			failures:  10,
+			err:       notFound,
+			status:    models.CrawlStatusError,
+			attempts:  1,
+			lastError: "received HTTP status 404 Not Found",
+		},
+		{
+			name:      "missing host is not retried",
+			failures:  10,
+			err:       fmt.Errorf("request failed: %w", &net.DNSError{Err: "no such host", Name: "missing.example", IsNotFound: true}),
+			status:    models.CrawlStatusError,
+			attempts:  1,
+			lastError: "request failed: lookup missing.example: no such host",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newMemoryStorage()
+			crawler := &flakyCrawler{failures: tt.failures, err: tt.err}
+			queue := NewQueueServiceWithConfig(config.QueueConfig{
+				Workers:    1,
+				BufferSize: 10,
+				MaxRetries: 3,
+				RetryDelay: time.Millisecond,
+			}, crawler, storage)
+			queue.Start()
+			defer queue.Stop()
+
+			queued, err := queue.EnqueueURL(context.Background(), "https://example.com/", EnqueueOptions{})
+			if err != nil {
+				t.Fatalf("EnqueueURL() error = %v", err)
+			}
+
+			result := waitForFinalStatus(t, queue, storage, queued.ID)
+			if result.Status != tt.status {
+				t.Errorf("status = %s, expected %s", result.Status, tt.status)
+			}
+			if result.Attempts != tt.attempts || crawler.callCount() != tt.attempts {
+				t.Errorf("attempts = %d (crawler calls %d), expected %d", result.Attempts, crawler.callCount(), tt.attempts)
+			}
+			if result.LastError == nil || *result.LastError != tt.lastError {
+				t.Errorf("last error = %v, expected %q", result.LastError, tt.lastError)
+			}
+		})
+	}
+}
+
+func TestQueueReclaimsExpiredJobs(t *testing.T) {
+	storage := newMemoryStorage()
+	ctx := context.Background()
+
+	// Jobs left running by workers that went away, one with an expired lease
+	owner := "crashed-worker"
+	expired := time.Now().Add(-time.Second)
+	live := time.Now().Add(time.Hour)
+	for id, leaseExpiresAt := range map[string]time.Time{"orphaned": expired, "leased": live} {
+		storage.SaveCrawlResult(ctx, &models.CrawlResult{ID: id, URL: "https://example.com/" + id, Status: models.CrawlStatusRunning})
+		storage.jobs[id] = &models.CrawlJob{
+			ID:             id,
+			URL:            "https://example.com/" + id,
+			Priority:       models.CrawlPriorityNormal,
+			Status:         models.CrawlStatusRunning,
+			Attempts:       1,
+			LeaseOwner:     &owner,
+			LeaseExpiresAt: &leaseExpiresAt,
+		}
+	}
+
+	queue := NewQueueServiceWithConfig(config.QueueConfig{
+		Workers:    1,
+		BufferSize: 10,
+		MaxRetries: 3,
+	}, &fakeCrawler{}, storage)
+	queue.Start()
+	defer queue.Stop()
+
+	result := waitForFinalStatus(t, queue, storage, "orphaned")
+	if result.Status != models.CrawlStatusCompleted {
+		t.Errorf("orphaned crawl status = %s, expected completed", result.Status)
+	}
+	if result.Attempts != 2 {
+		t.Errorf("orphaned crawl attempts = %d, expected 2", result.Attempts)
+	}
+
+	job, err := storage.GetJob(ctx, "leased")
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if job.Status != models.CrawlStatusRunning || job.LeaseOwner == nil || *job.LeaseOwner != owner {
+		t.Errorf("job with a live lease was reclaimed: %+v", job)
+	}
+}
+
+// recordingPublisher records the crawl events it is given
+type recordingPublisher struct {
+	events chan *models.CrawlEvent
+}
+
+func (p *recordingPublisher) PublishCrawlEvent(ctx context.Context, event *models.CrawlEvent) {
+	p.events <- event
+}
+
+func TestQueueReportsJobsItFailsOnReclaim(t *testing.T) {
+	storage := newMemoryStorage()
+	ctx := context.Background()
+
+	// Expired jobs that have used up their attempts or were asked to cancel
+	owner := "crashed-worker"
+	expired := time.Now().Add(-time.Second)
+	for _, id := range []string{"exhausted", "abandoned"} {
+		storage.SaveCrawlResult(ctx, &models.CrawlResult{ID: id, URL: "https://example.com/" + id, Status: models.CrawlStatusRunning})
+		storage.jobs[id] = &models.CrawlJob{
+			ID:              id,
+			URL:             "https://example.com/" + id,
+			Priority:        models.CrawlPriorityNormal,
+			Status:          models.CrawlStatusRunning,
+			Attempts:        4,
+			LeaseOwner:      &owner,
+			LeaseExpiresAt:  &expired,
+			CancelRequested: id == "abandoned",
+		}
+	}
+
+	notifier := &recordingNotifier{finished: make(chan string, 2)}
+	publisher := &recordingPublisher{events: make(chan *models.CrawlEvent, 2)}
+	queue := NewQueueServiceWithConfig(config.QueueConfig{
+		Workers:    1,
+		BufferSize: 10,
+		MaxRetries: 3,
+	}, &fakeCrawler{}, storage)
+	queue.SetNotifier(notifier)
+	queue.SetEventPublisher(publisher)
+	queue.Start()
+	defer queue.Stop()
+
+	events := make(map[string]*models.CrawlEvent)
+	for len(events) < 2 {
+		select {
+		case event := <-publisher.events:
+			events[event.CrawlID] = event
+		case <-time.After(5 * time.Second):
+			t.Fatalf("reclaimed jobs were not published, got %d events", len(events))
+		}
+	}
+	if event := events["exhausted"]; event.Type != models.CrawlEventError || event.Status != models.CrawlStatusError {
+		t.Errorf("exhausted job event = %s/%s, expected error", event.Type, event.Status)
+	}
+	if event := events["abandoned"]; event.Type != models.CrawlEventCancelled || event.Status != models.CrawlStatusCancelled {
+		t.Errorf("abandoned job event = %s/%s, expected cancelled", event.Type, event.Status)
+	}
+
+	select {
+	case id := <-notifier.finished:
+		if id != "exhausted" {
+			t.Errorf("notified about %s, expected exhausted", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed job was not notified")
+	}
+}
+
+func TestQueueStatsAggregateWorkers(t *testing.T) {
+	storage := newMemoryStorage()
+	ctx := context.Background()
+
+	// Two worker processes and an API-only process sharing one store
+	first := NewQueueServiceWithConfig(config.QueueConfig{Workers: 2, BufferSize: 10}, &fakeCrawler{}, storage)
+	second := NewQueueServiceWithConfig(config.QueueConfig{Workers: 3, BufferSize: 10}, &fakeCrawler{}, storage)
+	api := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 10}, &fakeCrawler{}, storage)
+	first.Start()
+	second.Start()
+	defer first.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if workers, _ := storage.GetLiveWorkers(ctx, time.Minute); len(workers) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("workers did not register")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stats := api.GetQueueStats(ctx)
+	if stats["workers"] != 5 {
+		t.Errorf("workers = %v, expected 5", stats["workers"])
+	}
+	if stats["running"] != true {
+		t.Errorf("running = %v, expected true", stats["running"])
+	}
+	if instances := stats["instances"].([]models.Worker); len(instances) != 2 {
+		t.Errorf("instances = %d, expected 2", len(instances))
+	}
+	if _, ok := stats["worker_id"]; ok {
+		t.Errorf("API-only queue reported a worker ID: %v", stats["worker_id"])
+	}
+
+	// A stopped worker deregisters
+	second.Stop()
+	stats = api.GetQueueStats(ctx)
+	if stats["workers"] != 2 {
+		t.Errorf("workers after Stop = %v, expected 2", stats["workers"])
+	}
+	if id := first.GetQueueStats(ctx)["worker_id"]; id != first.workerID {
+		t.Errorf("worker_id = %v, expected %s", id, first.workerID)
+	}
+}
+
+// countingCrawler titles each page with how many times it has been crawled
+type countingCrawler struct {
+	mu     sync.Mutex
+	crawls int
+}
+
+func (c *countingCrawler) AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error) {
+	c.mu.Lock()
+	c.crawls++
+	title := fmt.Sprintf("crawl %d", c.crawls)
+	c.mu.Unlock()
+
+	return &models.CrawlResult{
+		URL:           targetURL,
+		Title:         title,
+		HeadingCounts: models.HeadingCounts{},
+		BrokenLinks:   models.BrokenLinks{},
+	}, nil
+}
+
+func (c *countingCrawler) ValidateURL(ctx context.Context, targetURL string) error {
+	return nil
+}
+
+func TestQueueRequeueKeepsRunHistory(t *testing.T) {
+	storage := newMemoryStorage()
+	queue := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 10}, &countingCrawler{}, storage)
+	queue.Start()
+	defer queue.Stop()
+	ctx := context.Background()
+
+	result, err := queue.EnqueueURL(ctx, "https://example.com/", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+
+	waitForRuns := func(expected int) []models.CrawlRun {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if runs := storage.crawlRuns(result.ID); len(runs) >= expected {
+				return runs
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("crawl %s did not record %d runs", result.ID, expected)
+		return nil
+	}
+
+	waitForRuns(1)
+	waitForFinalStatus(t, queue, storage, result.ID)
+	if err := queue.RequeueTask(ctx, result.ID); err != nil {
+		t.Fatalf("RequeueTask() error = %v", err)
+	}
+	runs := waitForRuns(2)
+
+	for i, run := range runs {
+		if run.RunNumber != i+1 {
+			t.Errorf("run %d has number %d", i+1, run.RunNumber)
+		}
+		if expected := fmt.Sprintf("crawl %d", i+1); run.Title != expected {
+			t.Errorf("run %d title = %q, expected %q", i+1, run.Title, expected)
+		}
+		if run.Status != models.CrawlStatusCompleted {
+			t.Errorf("run %d status = %s, expected completed", i+1, run.Status)
+		}
+	}
+
+	latest, err := storage.GetCrawlResult(ctx, result.ID)
+	if err != nil {
+		t.Fatalf("GetCrawlResult() error = %v", err)
+	}
+	if latest.Title != "crawl 2" {
+		t.Errorf("latest result title = %q, expected %q", latest.Title, "crawl 2")
+	}
+}
+
+func TestQueueRequeueKeepsResultWhenQueueIsFull(t *testing.T) {
+	storage := newMemoryStorage()
+	queue := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 1}, &fakeCrawler{}, storage)
+	ctx := context.Background()
+
+	completed := &models.CrawlResult{
+		ID:        "completed",
+		URL:       "https://example.com/done",
+		Status:    models.CrawlStatusCompleted,
+		Title:     "done",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := storage.SaveCrawlResult(ctx, completed); err != nil {
+		t.Fatalf("SaveCrawlResult() error = %v", err)
+	}
+
+	// Without workers the only slot of the queue stays taken
+	if _, err := queue.EnqueueURL(ctx, "https://example.com/", EnqueueOptions{}); err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+
+	if err := queue.RequeueTask(ctx, completed.ID); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("RequeueTask() error = %v, expected ErrQueueFull", err)
+	}
+
+	result, err := storage.GetCrawlResult(ctx, completed.ID)
+	if err != nil {
+		t.Fatalf("GetCrawlResult() error = %v", err)
+	}
+	if result.Status != models.CrawlStatusCompleted || result.ErrorMessage != nil || result.Title != "done" {
+		t.Errorf("stored result = %s %v %q, expected the completed result to be kept", result.Status, result.ErrorMessage, result.Title)
+	}
+}
+
+func TestQueueRequeueRefusesActiveCrawls(t *testing.T) {
+	storage := newMemoryStorage()
+	queue := newTestQueue(&fakeCrawler{}, storage)
+	ctx := context.Background()
+
+	// Without workers the crawl stays queued
+	result, err := queue.EnqueueURL(ctx, "https://example.com/", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+	if err := queue.RequeueTask(ctx, result.ID); !errors.Is(err, ErrTaskActive) {
+		t.Fatalf("RequeueTask() of a queued crawl error = %v, expected ErrTaskActive", err)
+	}
+
+	// Another worker takes the job
+	if _, err := storage.ClaimJob(ctx, "other-worker", time.Minute, models.CrawlPriorities); err != nil {
+		t.Fatalf("ClaimJob() error = %v", err)
+	}
+	if err := storage.UpdateCrawlStatus(ctx, result.ID, models.CrawlStatusRunning, nil); err != nil {
+		t.Fatalf("UpdateCrawlStatus() error = %v", err)
+	}
+	if err := queue.RequeueTask(ctx, result.ID); !errors.Is(err, ErrTaskActive) {
+		t.Fatalf("RequeueTask() of a running crawl error = %v, expected ErrTaskActive", err)
+	}
+
+	job, err := storage.GetJob(ctx, result.ID)
+	if err != nil {
+		t.Fatalf("GetJob() error = %v", err)
+	}
+	if job.Status != models.CrawlStatusRunning || job.LeaseOwner == nil || *job.LeaseOwner != "other-worker" {
+		t.Errorf("job = %s leased by %v, expected it to stay with the worker running it", job.Status, job.LeaseOwner)
+	}
+}
+
+// slowCrawler takes a fixed time for every crawl
+type slowCrawler struct {
+	delay time.Duration
+}
+
+func (s *slowCrawler) AnalyzeURL(ctx context.Context, targetURL string) (*models.CrawlResult, error) {
+	time.Sleep(s.delay)
+	return &models.CrawlResult{URL: targetURL, HeadingCounts: models.HeadingCounts{}, BrokenLinks: models.BrokenLinks{}}, nil
+}
+
+func (s *slowCrawler) ValidateURL(ctx context.Context, targetURL string) error {
+	return nil
+}
+
+// deadlineStorage fails writes whose context has ended, like a database driver does
+type deadlineStorage struct {
+	*memoryStorage
+}
+
+func (d deadlineStorage) SaveCrawlResult(ctx context.Context, result *models.CrawlResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.memoryStorage.SaveCrawlResult(ctx, result)
+}
+
+func (d deadlineStorage) CompleteJob(ctx context.Context, id, owner string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.memoryStorage.CompleteJob(ctx, id, owner)
+}
+
+func TestQueueSavesCrawlsSlowerThanStorageTimeout(t *testing.T) {
+	storage := newMemoryStorage()
+	queue := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 10, TaskTimeout: time.Second}, &slowCrawler{delay: 100 * time.Millisecond}, deadlineStorage{storage})
+	queue.storageTimeout = 20 * time.Millisecond
+	queue.Start()
+	defer queue.Stop()
+
+	result, err := queue.EnqueueURL(context.Background(), "https://example.com/", EnqueueOptions{})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+
+	if final := waitForFinalStatus(t, queue, storage, result.ID); final.Status != models.CrawlStatusCompleted {
+		t.Fatalf("crawl status = %s, expected completed", final.Status)
+	}
+	if job, err := storage.GetJob(context.Background(), result.ID); err != nil || job.Status != models.CrawlStatusCompleted {
+		t.Errorf("job = %+v (err %v), expected it to be completed", job, err)
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// maxRetryBackoff caps the exponential backoff between attempts
+	maxRetryBackoff = 5 * time.Minute
+
+	// maxRetryAfter caps how long a server-provided Retry-After can postpone a retry
+	maxRetryAfter = 10 * time.Minute
+)
+
+// HTTPStatusError is returned when a page or upstream API answers with an error status
+type HTTPStatusError struct {
+	StatusCode int
+	Status     string
+	RetryAfter time.Duration
+}
+
+func (e *HTTPStatusError) Error() string {
+	return "received HTTP status " + e.Status
+}
+
+// newHTTPStatusError builds an HTTPStatusError from a response
+func newHTTPStatusError(resp *http.Response) *HTTPStatusError {
+	return &HTTPStatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
+
+// isTransient reports whether a crawl failure is worth retrying.
+// Timeouts, connection failures, 408/425/429 and 5xx responses are transient;
+// policy rejections, missing hosts and other 4xx responses are permanent.
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, ErrForbiddenTarget) || errors.Is(err, ErrDomainNotAllowed) || errors.Is(err, ErrDisallowedByRobots) {
+		return false
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusRequestTimeout,
+			statusErr.StatusCode == http.StatusTooEarly,
+			statusErr.StatusCode == http.StatusTooManyRequests,
+			statusErr.StatusCode >= 500:
+			return true
+		default:
+			return false
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		// NXDOMAIN will not fix itself; resolver timeouts and SERVFAIL might
+		return !dnsErr.IsNotFound && (dnsErr.IsTimeout || dnsErr.IsTemporary)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryAfter returns the server-requested delay carried by an error, if any
+func retryAfter(err error) time.Duration {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return min(statusErr.RetryAfter, maxRetryAfter)
+	}
+	return 0
+}
+
+// retryBackoff returns the jittered delay before the given retry (1-based).
+// The delay doubles with each retry, and half of it is randomized so that
+// tasks failing together do not retry in lockstep.
+func retryBackoff(base time.Duration, retry int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	delay := base
+	for i := 1; i < retry && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryBackoff)
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// statusErrorTransport turns retryable upstream API responses into HTTPStatusError values
+// so that clients which discard response headers still expose the status and Retry-After.
+// A client that retries 502 itself never sees one; the queue's retry policy takes over.
+type statusErrorTransport struct {
+	base http.RoundTripper
+}
+
+func (t *statusErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, newHTTPStatusError(resp))
+	}
+
+	return resp, nil
+}
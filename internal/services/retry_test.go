@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{"server error", &HTTPStatusError{StatusCode: 502, Status: "502 Bad Gateway"}, true},
+		{"rate limited", fmt.Errorf("scrape: %w", &HTTPStatusError{StatusCode: 429, Status: "429 Too Many Requests"}), true},
+		{"request timeout status", &HTTPStatusError{StatusCode: 408, Status: "408 Request Timeout"}, true},
+		{"not found", &HTTPStatusError{StatusCode: 404, Status: "404 Not Found"}, false},
+		{"forbidden", &HTTPStatusError{StatusCode: 403, Status: "403 Forbidden"}, false},
+		{"nxdomain", &net.DNSError{Err: "no such host", Name: "missing.example", IsNotFound: true}, false},
+		{"dns timeout", &net.DNSError{Err: "i/o timeout", Name: "slow.example", IsTimeout: true}, true},
+		{"deadline exceeded", fmt.Errorf("request failed: %w", context.DeadlineExceeded), true},
+		{"cancelled", fmt.Errorf("request failed: %w", context.Canceled), false},
+		{"connection refused", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"truncated body", io.ErrUnexpectedEOF, true},
+		{"forbidden target", fmt.Errorf("%w: 10.0.0.1", ErrForbiddenTarget), false},
+		{"robots", fmt.Errorf("page is %w", ErrDisallowedByRobots), false},
+		{"unsupported content", errors.New("unsupported content type: application/pdf"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.transient {
+				t.Errorf("isTransient(%v) = %v, expected %v", tt.err, got, tt.transient)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-5", 0},
+		{"Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.expected {
+			t.Errorf("parseRetryAfter(%q) = %s, expected %s", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	base := 100 * time.Millisecond
+
+	for retry := 1; retry <= 5; retry++ {
+		full := base << (retry - 1)
+		for i := 0; i < 20; i++ {
+			delay := retryBackoff(base, retry)
+			if delay < full/2 || delay > full {
+				t.Fatalf("retryBackoff(%s, %d) = %s, expected within [%s, %s]", base, retry, delay, full/2, full)
+			}
+		}
+	}
+
+	if delay := retryBackoff(time.Minute, 30); delay > maxRetryBackoff {
+		t.Errorf("retryBackoff() = %s, expected at most %s", delay, maxRetryBackoff)
+	}
+	if delay := retryBackoff(0, 3); delay != 0 {
+		t.Errorf("retryBackoff() with no base delay = %s, expected 0", delay)
+	}
+}
+
+func TestStatusErrorTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/limited":
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &statusErrorTransport{base: http.DefaultTransport}}
+
+	_, err := client.Get(srv.URL + "/limited")
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected an HTTPStatusError, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusTooManyRequests || retryAfter(err) != 7*time.Second {
+		t.Errorf("got status %d and Retry-After %s, expected 429 and 7s", statusErr.StatusCode, retryAfter(err))
+	}
+
+	// Non-retryable statuses are left for the API client to interpret
+	resp, err := client.Get(srv.URL + "/missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, expected 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"url-crawler/internal/config"
+)
+
+// ErrDisallowedByRobots is returned when robots.txt forbids crawling a URL
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+const (
+	// robotsCacheTTL controls how long a fetched robots.txt is reused per host
+	robotsCacheTTL = time.Hour
+
+	// robotsCacheMaxHosts caps how many hosts' robots.txt rules are cached at once
+	robotsCacheMaxHosts = 10000
+
+	// robotsSweepInterval is how often expired robots.txt rules are looked for
+	robotsSweepInterval = 10 * time.Minute
+
+	// robotsMaxSize caps the amount of robots.txt content that is parsed
+	robotsMaxSize = 512 * 1024
+)
+
+// RobotsChecker fetches, caches and evaluates robots.txt rules per host
+type RobotsChecker struct {
+	client    *http.Client
+	userAgent string
+	agentName string
+
+	mu        sync.Mutex
+	cache     map[string]*robotsEntry
+	maxHosts  int
+	lastSweep time.Time
+}
+
+// robotsEntry is a cached robots.txt evaluation for a single host. Until ready
+// is closed its robots.txt is still being fetched, and its rules are unset.
+type robotsEntry struct {
+	ready     chan struct{}
+	rules     *robotsRules
+	fetchedAt time.Time
+}
+
+// expired reports whether a fetched entry is too old to be reused
+func (e *robotsEntry) expired(now time.Time) bool {
+	return !e.fetchedAt.IsZero() && now.Sub(e.fetchedAt) >= robotsCacheTTL
+}
+
+// robotsRules holds the rules of the group that applies to our user agent
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	disallowed bool // set when robots.txt could not be retrieved due to a server error
+}
+
+// robotsRule is a single Allow or Disallow directive
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// robotsGroup is a user-agent group as it appears in robots.txt
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// NewRobotsChecker creates a new robots.txt checker using configuration
+func NewRobotsChecker(cfg config.CrawlerConfig, guard *TargetGuard) *RobotsChecker {
+	return &RobotsChecker{
+		client:    newCrawlerHTTPClient(cfg, guard),
+		userAgent: cfg.UserAgent,
+		agentName: robotsAgentName(cfg.UserAgent),
+		cache:     make(map[string]*robotsEntry),
+		maxHosts:  robotsCacheMaxHosts,
+		lastSweep: time.Now(),
+	}
+}
+
+// Allowed reports whether robots.txt permits crawling the given URL. It returns
+// ctx.Err() when ctx is done before the host's robots.txt has been fetched.
+func (rc *RobotsChecker) Allowed(ctx context.Context, targetURL string) (bool, error) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return false, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true, nil
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	rules, err := rc.rulesFor(ctx, u)
+	if err != nil {
+		return false, err
+	}
+	return rules.allowed(path), nil
+}
+
+// CrawlDelay returns the Crawl-delay declared for the URL's host, if any
+func (rc *RobotsChecker) CrawlDelay(ctx context.Context, targetURL string) time.Duration {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return 0
+	}
+	rules, err := rc.rulesFor(ctx, u)
+	if err != nil {
+		return 0
+	}
+	return rules.crawlDelay
+}
+
+// rulesFor returns the cached rules for the URL's host, fetching them if needed.
+// Concurrent lookups of a host that is not cached share a single fetch, which
+// carries on in the background when the lookup that started it is cancelled.
+func (rc *RobotsChecker) rulesFor(ctx context.Context, u *url.URL) (*robotsRules, error) {
+	key := u.Scheme + "://" + u.Host
+	now := time.Now()
+
+	rc.mu.Lock()
+	entry, exists := rc.cache[key]
+	if !exists || entry.expired(now) {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		rc.store(key, entry, now)
+		go rc.load(context.WithoutCancel(ctx), key, entry)
+	}
+	rc.mu.Unlock()
+
+	select {
+	case <-entry.ready:
+		return entry.rules, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// load fetches the rules of a cached entry and wakes up the lookups waiting for them
+func (rc *RobotsChecker) load(ctx context.Context, origin string, entry *robotsEntry) {
+	rules := rc.fetch(ctx, origin)
+
+	rc.mu.Lock()
+	entry.rules = rules
+	entry.fetchedAt = time.Now()
+	rc.mu.Unlock()
+	close(entry.ready)
+}
+
+// store caches an entry for a host. Expired entries are evicted at most once per
+// sweep interval, and when the cache is still full the oldest fetched entry makes room.
+// The caller must hold rc.mu.
+func (rc *RobotsChecker) store(key string, entry *robotsEntry, now time.Time) {
+	if now.Sub(rc.lastSweep) >= robotsSweepInterval {
+		rc.lastSweep = now
+		for host, cached := range rc.cache {
+			if cached.expired(now) {
+				delete(rc.cache, host)
+			}
+		}
+	}
+
+	if len(rc.cache) >= rc.maxHosts {
+		oldest := ""
+		for host, cached := range rc.cache {
+			// Fetches still in flight are kept, so their waiters are not duplicated
+			if cached.fetchedAt.IsZero() {
+				continue
+			}
+			if oldest == "" || cached.fetchedAt.Before(rc.cache[oldest].fetchedAt) {
+				oldest = host
+			}
+		}
+		if oldest != "" {
+			delete(rc.cache, oldest)
+		}
+	}
+
+	rc.cache[key] = entry
+}
+
+// fetch downloads and parses robots.txt for the given origin
+func (rc *RobotsChecker) fetch(ctx context.Context, origin string) *robotsRules {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return &robotsRules{}
+	}
+	req.Header.Set("User-Agent", rc.userAgent)
+
+	resp, err := rc.client.Do(req)
+	if err != nil {
+		// The page fetch will surface the network error, so don't block here
+		log.Printf("Warning: failed to fetch robots.txt for %s: %v", origin, err)
+		return &robotsRules{}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		// An unreachable robots.txt means the whole site is off limits
+		return &robotsRules{disallowed: true}
+	case resp.StatusCode >= 400:
+		// A missing robots.txt means there are no restrictions
+		return &robotsRules{}
+	}
+
+	return parseRobots(io.LimitReader(resp.Body, robotsMaxSize), rc.agentName)
+}
+
+// parseRobots parses robots.txt content and returns the rules for the given agent
+func parseRobots(r io.Reader, agentName string) *robotsRules {
+	var groups []*robotsGroup
+	var current *robotsGroup
+	inAgentLines := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share the same group
+			if !inAgentLines {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			if agent := robotsAgentName(value); agent != "" {
+				current.agents = append(current.agents, agent)
+			}
+			inAgentLines = true
+		case "allow", "disallow":
+			inAgentLines = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				length:  len(value),
+				pattern: compileRobotsPattern(value),
+			})
+		case "crawl-delay":
+			inAgentLines = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		default:
+			inAgentLines = false
+		}
+	}
+
+	group := selectRobotsGroup(groups, agentName)
+	return &robotsRules{
+		rules:      group.rules,
+		crawlDelay: group.crawlDelay,
+	}
+}
+
+// selectRobotsGroup merges the groups naming the agent's product token, falling
+// back to the groups for "*". Groups that repeat a user-agent are combined as if
+// they were written as one.
+func selectRobotsGroup(groups []*robotsGroup, agentName string) *robotsGroup {
+	matched, wildcard := &robotsGroup{}, &robotsGroup{}
+	found := false
+
+	for _, group := range groups {
+		if group.hasAgent(agentName) {
+			matched.merge(group)
+			found = true
+		}
+		if group.hasAgent("*") {
+			wildcard.merge(group)
+		}
+	}
+
+	if found {
+		return matched
+	}
+	return wildcard
+}
+
+// hasAgent reports whether the group lists the given product token
+func (g *robotsGroup) hasAgent(agentName string) bool {
+	for _, agent := range g.agents {
+		if agent == agentName {
+			return true
+		}
+	}
+	return false
+}
+
+// merge adds the rules of another group for the same user-agent
+func (g *robotsGroup) merge(other *robotsGroup) {
+	g.rules = append(g.rules, other.rules...)
+	g.crawlDelay = max(g.crawlDelay, other.crawlDelay)
+}
+
+// compileRobotsPattern converts a robots.txt path pattern with * and $ into a regexp
+func compileRobotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+
+	return regexp.MustCompile(expr)
+}
+
+// allowed evaluates the rules against a path; the longest match wins and Allow wins ties
+func (rr *robotsRules) allowed(path string) bool {
+	if rr.disallowed {
+		return false
+	}
+
+	allow := true
+	matchLength := -1
+
+	for _, rule := range rr.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > matchLength || (rule.length == matchLength && rule.allow) {
+			allow = rule.allow
+			matchLength = rule.length
+		}
+	}
+
+	return allow
+}
+
+// robotsAgentName extracts the lowercase product token from a User-Agent string
+func robotsAgentName(userAgent string) string {
+	name := strings.TrimSpace(userAgent)
+	if i := strings.IndexAny(name, "/ "); i >= 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(name)
+}
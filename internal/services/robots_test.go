@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+)
+
+const testRobotsTxt = `
+# Global rules
+User-agent: *
+Disallow: /private/
+Allow: /private/public-page
+Disallow: /*.pdf$
+
+User-agent: url-crawler-bot
+User-agent: other-bot
+Disallow: /search
+Allow: /search/about
+Crawl-delay: 2
+`
+
+func TestParseRobotsWildcardGroup(t *testing.T) {
+	rules := parseRobots(strings.NewReader(testRobotsTxt), "some-other-crawler")
+
+	tests := map[string]bool{
+		"/":                     true,
+		"/private/secret":       false,
+		"/private/public-page":  true,
+		"/docs/report.pdf":      false,
+		"/docs/report.pdf?x=1":  true,
+		"/search?q=crawler":     true,
+		"/privateer/index.html": true,
+	}
+
+	for path, expected := range tests {
+		if got := rules.allowed(path); got != expected {
+			t.Errorf("allowed(%q) = %v, expected %v", path, got, expected)
+		}
+	}
+
+	if rules.crawlDelay != 0 {
+		t.Errorf("expected no crawl delay for wildcard group, got %s", rules.crawlDelay)
+	}
+}
+
+func TestParseRobotsSpecificGroup(t *testing.T) {
+	rules := parseRobots(strings.NewReader(testRobotsTxt), robotsAgentName("URL-Crawler-Bot/1.0"))
+
+	tests := map[string]bool{
+		"/private/secret": true,
+		"/search":         false,
+		"/search?q=x":     false,
+		"/search/about":   true,
+	}
+
+	for path, expected := range tests {
+		if got := rules.allowed(path); got != expected {
+			t.Errorf("allowed(%q) = %v, expected %v", path, got, expected)
+		}
+	}
+
+	if rules.crawlDelay != 2*time.Second {
+		t.Errorf("expected crawl delay of 2s, got %s", rules.crawlDelay)
+	}
+}
+
+func TestParseRobotsMatchesProductToken(t *testing.T) {
+	robotsTxt := `
+User-agent: bot
+Disallow: /
+
+User-agent: URL-Crawler-Bot/2.0
+Disallow: /search
+
+User-agent: *
+Disallow: /private
+
+User-agent: url-crawler-bot
+Disallow: /admin
+`
+	rules := parseRobots(strings.NewReader(robotsTxt), robotsAgentName("url-crawler-bot/1.0"))
+
+	tests := map[string]bool{
+		"/":        true,
+		"/private": true,
+		"/search":  false,
+		"/admin":   false,
+	}
+
+	for path, expected := range tests {
+		if got := rules.allowed(path); got != expected {
+			t.Errorf("allowed(%q) = %v, expected %v", path, got, expected)
+		}
+	}
+}
+
+func TestRobotsCheckerFetch(t *testing.T) {
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fetches++
+			w.Write([]byte("User-agent: *\nDisallow: /admin\n"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rc := NewRobotsChecker(config.CrawlerConfig{Timeout: 5 * time.Second, UserAgent: "test-bot/1.0"}, nil)
+
+	if allowed, err := rc.Allowed(context.Background(), srv.URL+"/admin/users"); err != nil || allowed {
+		t.Errorf("expected /admin/users to be disallowed, got allowed=%v err=%v", allowed, err)
+	}
+	if allowed, err := rc.Allowed(context.Background(), srv.URL+"/index.html"); err != nil || !allowed {
+		t.Errorf("expected /index.html to be allowed, got allowed=%v err=%v", allowed, err)
+	}
+	if fetches != 1 {
+		t.Errorf("expected robots.txt to be fetched once, got %d", fetches)
+	}
+}
+
+func TestRobotsCheckerSharesConcurrentFetches(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("User-agent: *\nDisallow: /admin\n"))
+	}))
+	defer srv.Close()
+
+	rc := NewRobotsChecker(config.CrawlerConfig{Timeout: 5 * time.Second, UserAgent: "test-bot/1.0"}, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if allowed, err := rc.Allowed(context.Background(), srv.URL+"/admin"); err != nil || allowed {
+				t.Errorf("expected /admin to be disallowed, got allowed=%v err=%v", allowed, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fetches.Load() != 1 {
+		t.Errorf("expected concurrent lookups to fetch robots.txt once, got %d", fetches.Load())
+	}
+}
+
+func TestRobotsCheckerEvictsWhenFull(t *testing.T) {
+	var fetches atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte("User-agent: *\nDisallow: /admin\n"))
+	})
+	first := httptest.NewServer(handler)
+	defer first.Close()
+	second := httptest.NewServer(handler)
+	defer second.Close()
+
+	rc := NewRobotsChecker(config.CrawlerConfig{Timeout: 5 * time.Second, UserAgent: "test-bot/1.0"}, nil)
+	rc.maxHosts = 1
+
+	for _, origin := range []string{first.URL, second.URL, first.URL} {
+		if _, err := rc.Allowed(context.Background(), origin+"/index.html"); err != nil {
+			t.Fatalf("Allowed() error = %v", err)
+		}
+	}
+
+	if len(rc.cache) != 1 {
+		t.Errorf("expected the cache to hold 1 host, got %d", len(rc.cache))
+	}
+	if fetches.Load() != 3 {
+		t.Errorf("expected the evicted host to be fetched again, got %d fetches", fetches.Load())
+	}
+}
+
+func TestRobotsCheckerStopsWaitingWhenCancelled(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("User-agent: *\nDisallow: /admin\n"))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	rc := NewRobotsChecker(config.CrawlerConfig{Timeout: 5 * time.Second, UserAgent: "test-bot/1.0"}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := rc.Allowed(ctx, srv.URL+"/admin"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Allowed() error = %v, expected the context deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Allowed() returned after %s, expected it to stop when the context ended", elapsed)
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidTimezone is returned for schedules naming an unknown IANA timezone
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+const (
+	// defaultSchedulerInterval is how often due schedules are checked when no interval is configured
+	defaultSchedulerInterval = 15 * time.Second
+
+	// scheduleBatchSize caps how many due schedules a single check runs
+	scheduleBatchSize = 100
+)
+
+// ScheduleStore persists recurring crawl schedules
+type ScheduleStore interface {
+	SaveSchedule(ctx context.Context, schedule *models.Schedule) error
+	GetDueSchedules(ctx context.Context, now time.Time, limit int) ([]models.Schedule, error)
+	ClaimScheduleRun(ctx context.Context, id string, tick, next time.Time) (bool, error)
+	RecordScheduleRun(ctx context.Context, id string, crawlID *string, lastError *string) error
+}
+
+// Scheduler enqueues crawls for schedules whose cron expression has come due.
+// Any number of replicas may run a scheduler against the same store: a run is
+// claimed by advancing the schedule's next run time, which only one replica can do.
+type Scheduler struct {
+	storage  ScheduleStore
+	queue    *QueueService
+	interval time.Duration
+	running  bool
+	stopped  bool
+	wg       sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+}
+
+// NewScheduler creates a scheduler that enqueues through the given queue service
+func NewScheduler(cfg config.SchedulerConfig, storage ScheduleStore, queue *QueueService) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultSchedulerInterval
+	}
+
+	return &Scheduler{
+		storage:  storage,
+		queue:    queue,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start begins checking for due schedules
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running || s.stopped {
+		return
+	}
+	s.running = true
+
+	s.wg.Add(1)
+	go s.loop()
+
+	log.Printf("Scheduler started (interval: %s)", s.interval)
+}
+
+// Stop stops the scheduler, waiting for a check in progress to finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	s.stopped = true
+	s.cancel()
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	log.Println("Scheduler stopped")
+}
+
+// CreateSchedule validates and saves a new schedule for owner, computing its first run.
+// The crawls the schedule enqueues belong to owner.
+func (s *Scheduler) CreateSchedule(ctx context.Context, req models.ScheduleRequest, owner string) (*models.Schedule, error) {
+	req.ApplyDefaults()
+
+	if err := s.queue.crawler.ValidateURL(ctx, req.URL); err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	now := time.Now()
+	next, err := nextScheduleRun(req.Cron, req.Timezone, now)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &models.Schedule{
+		ID:        uuid.New().String(),
+		URL:       req.URL,
+		Cron:      req.Cron,
+		Timezone:  req.Timezone,
+		Priority:  req.Priority,
+		Owner:     owner,
+		NextRunAt: next,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.storage.SaveSchedule(ctx, schedule); err != nil {
+		return nil, fmt.Errorf("failed to save schedule: %w", err)
+	}
+
+	log.Printf("Created schedule %s for %s (%s %s, next run %s)", schedule.ID, schedule.URL, schedule.Cron, schedule.Timezone, next.Format(time.RFC3339))
+	return schedule, nil
+}
+
+// nextScheduleRun returns the first run of a cron expression after now,
+// evaluated in the given timezone and returned in UTC
+func nextScheduleRun(expr, timezone string, now time.Time) (time.Time, error) {
+	cron, err := ParseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidTimezone, timezone)
+	}
+
+	next := cron.Next(now.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: expression never matches", ErrInvalidCron)
+	}
+	return next.UTC(), nil
+}
+
+// loop checks for due schedules every interval until the scheduler stops
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runDue(s.ctx, time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// runDue enqueues a crawl for every schedule due at now. Ticks missed while no
+// scheduler was running are collapsed into a single run.
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	due, err := s.storage.GetDueSchedules(ctx, now, scheduleBatchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Failed to load due schedules: %v", err)
+		}
+		return
+	}
+
+	for _, schedule := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		s.run(ctx, schedule, now)
+	}
+}
+
+// run claims and performs one due run of a schedule
+func (s *Scheduler) run(ctx context.Context, schedule models.Schedule, now time.Time) {
+	next, err := nextScheduleRun(schedule.Cron, schedule.Timezone, now)
+	if err != nil {
+		log.Printf("Schedule %s: cannot compute next run: %v", schedule.ID, err)
+		return
+	}
+
+	claimed, err := s.storage.ClaimScheduleRun(ctx, schedule.ID, schedule.NextRunAt, next)
+	if err != nil {
+		log.Printf("Schedule %s: failed to claim run: %v", schedule.ID, err)
+		return
+	}
+	if !claimed {
+		// Another replica took this tick
+		return
+	}
+
+	var crawlID, lastError *string
+	result, err := s.queue.EnqueueURL(ctx, schedule.URL, EnqueueOptions{Priority: schedule.Priority, Owner: schedule.Owner})
+	if err != nil {
+		errorMsg := err.Error()
+		lastError = &errorMsg
+		log.Printf("Schedule %s: failed to enqueue %s: %v", schedule.ID, schedule.URL, err)
+	} else {
+		crawlID = &result.ID
+		log.Printf("Schedule %s: enqueued crawl %s for %s (next run %s)", schedule.ID, result.ID, schedule.URL, next.Format(time.RFC3339))
+	}
+
+	// The outcome is recorded even if the scheduler is stopping
+	recordCtx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+	defer cancel()
+	if err := s.storage.RecordScheduleRun(recordCtx, schedule.ID, crawlID, lastError); err != nil {
+		log.Printf("Schedule %s: %v", schedule.ID, err)
+	}
+}
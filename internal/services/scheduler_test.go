@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+// memoryScheduleStore is an in-memory ScheduleStore for tests
+type memoryScheduleStore struct {
+	mu        sync.Mutex
+	schedules map[string]*models.Schedule
+}
+
+func newMemoryScheduleStore() *memoryScheduleStore {
+	return &memoryScheduleStore{schedules: make(map[string]*models.Schedule)}
+}
+
+func (m *memoryScheduleStore) SaveSchedule(ctx context.Context, schedule *models.Schedule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copied := *schedule
+	m.schedules[schedule.ID] = &copied
+	return nil
+}
+
+func (m *memoryScheduleStore) GetDueSchedules(ctx context.Context, now time.Time, limit int) ([]models.Schedule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	due := []models.Schedule{}
+	for _, schedule := range m.schedules {
+		if !schedule.NextRunAt.After(now) && len(due) < limit {
+			due = append(due, *schedule)
+		}
+	}
+	return due, nil
+}
+
+func (m *memoryScheduleStore) ClaimScheduleRun(ctx context.Context, id string, tick, next time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok || !schedule.NextRunAt.Equal(tick) {
+		return false, nil
+	}
+	schedule.LastRunAt = &tick
+	schedule.NextRunAt = next
+	return true, nil
+}
+
+func (m *memoryScheduleStore) RecordScheduleRun(ctx context.Context, id string, crawlID *string, lastError *string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return errors.New("schedule not found")
+	}
+	if crawlID != nil {
+		schedule.LastCrawlID = crawlID
+	}
+	schedule.LastError = lastError
+	return nil
+}
+
+func (m *memoryScheduleStore) get(id string) models.Schedule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return *m.schedules[id]
+}
+
+func TestCreateSchedule(t *testing.T) {
+	store := newMemoryScheduleStore()
+	scheduler := NewScheduler(config.SchedulerConfig{}, store, newTestQueue(&fakeCrawler{}, newMemoryStorage()))
+
+	schedule, err := scheduler.CreateSchedule(context.Background(), models.ScheduleRequest{
+		URL:  "https://example.com/",
+		Cron: "*/5 * * * *",
+	}, "team-a")
+	if err != nil {
+		t.Fatalf("CreateSchedule() error = %v", err)
+	}
+	if schedule.Owner != "team-a" {
+		t.Errorf("owner = %q, expected team-a", schedule.Owner)
+	}
+	if schedule.Timezone != models.DefaultScheduleTimezone {
+		t.Errorf("timezone = %q, expected %q", schedule.Timezone, models.DefaultScheduleTimezone)
+	}
+	if schedule.Priority != models.CrawlPriorityNormal {
+		t.Errorf("priority = %q, expected normal", schedule.Priority)
+	}
+	if !schedule.NextRunAt.After(time.Now()) || schedule.NextRunAt.Minute()%5 != 0 {
+		t.Errorf("next run = %s, expected the next five minute mark", schedule.NextRunAt)
+	}
+
+	invalid := []struct {
+		req      models.ScheduleRequest
+		expected error
+	}{
+		{models.ScheduleRequest{URL: "https://example.com/", Cron: "every day"}, ErrInvalidCron},
+		{models.ScheduleRequest{URL: "https://example.com/", Cron: "0 0 31 FEB *"}, ErrInvalidCron},
+		{models.ScheduleRequest{URL: "https://example.com/", Cron: "@daily", Timezone: "Mars/Olympus_Mons"}, ErrInvalidTimezone},
+	}
+	for _, tt := range invalid {
+		if _, err := scheduler.CreateSchedule(context.Background(), tt.req, "team-a"); !errors.Is(err, tt.expected) {
+			t.Errorf("CreateSchedule(%+v) error = %v, expected %v", tt.req, err, tt.expected)
+		}
+	}
+}
+
+func TestSchedulerRunsEachTickOnce(t *testing.T) {
+	store := newMemoryScheduleStore()
+	storage := newMemoryStorage()
+	queue := newTestQueue(&fakeCrawler{}, storage)
+	ctx := context.Background()
+
+	tick := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	store.SaveSchedule(ctx, &models.Schedule{
+		ID:        "morning",
+		URL:       "https://example.com/",
+		Cron:      "0 9 * * *",
+		Timezone:  "UTC",
+		Priority:  models.CrawlPriorityHigh,
+		Owner:     "team-a",
+		NextRunAt: tick,
+	})
+
+	// Several replicas check the same due schedule at once
+	now := tick.Add(10 * time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		replica := NewScheduler(config.SchedulerConfig{}, store, queue)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replica.runDue(ctx, now)
+		}()
+	}
+	wg.Wait()
+
+	depth, err := storage.CountQueuedJobs(ctx)
+	if err != nil {
+		t.Fatalf("CountQueuedJobs() error = %v", err)
+	}
+	if depth[models.CrawlPriorityHigh] != 1 {
+		t.Fatalf("queued high priority crawls = %d, expected 1", depth[models.CrawlPriorityHigh])
+	}
+
+	schedule := store.get("morning")
+	if schedule.LastRunAt == nil || !schedule.LastRunAt.Equal(tick) {
+		t.Errorf("last run = %v, expected %s", schedule.LastRunAt, tick)
+	}
+	if expected := tick.Add(24 * time.Hour); !schedule.NextRunAt.Equal(expected) {
+		t.Errorf("next run = %s, expected %s", schedule.NextRunAt, expected)
+	}
+	if schedule.LastCrawlID == nil {
+		t.Fatal("last crawl ID was not recorded")
+	}
+	result, err := storage.GetCrawlResult(ctx, *schedule.LastCrawlID)
+	if err != nil {
+		t.Fatalf("recorded crawl %s does not exist: %v", *schedule.LastCrawlID, err)
+	}
+	if result.Owner == nil || *result.Owner != "team-a" {
+		t.Errorf("crawl owner = %v, expected the schedule's owner team-a", result.Owner)
+	}
+
+	// Nothing is due again until the next tick
+	NewScheduler(config.SchedulerConfig{}, store, queue).runDue(ctx, now.Add(time.Hour))
+	if depth, _ := storage.CountQueuedJobs(ctx); depth[models.CrawlPriorityHigh] != 1 {
+		t.Errorf("queued high priority crawls = %d, expected 1", depth[models.CrawlPriorityHigh])
+	}
+}
+
+func TestSchedulerCollapsesMissedTicks(t *testing.T) {
+	store := newMemoryScheduleStore()
+	storage := newMemoryStorage()
+	scheduler := NewScheduler(config.SchedulerConfig{}, store, newTestQueue(&fakeCrawler{}, storage))
+	ctx := context.Background()
+
+	// The scheduler was down for three hours of an hourly schedule
+	missed := time.Date(2025, 3, 10, 6, 0, 0, 0, time.UTC)
+	store.SaveSchedule(ctx, &models.Schedule{ID: "hourly", URL: "https://example.com/", Cron: "@hourly", Timezone: "UTC", NextRunAt: missed})
+
+	now := missed.Add(3*time.Hour + 20*time.Minute)
+	scheduler.runDue(ctx, now)
+
+	if depth, _ := storage.CountQueuedJobs(ctx); depth[models.CrawlPriorityNormal] != 1 {
+		t.Errorf("queued crawls = %d, expected 1", depth[models.CrawlPriorityNormal])
+	}
+	if next := store.get("hourly").NextRunAt; !next.Equal(time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("next run = %s, expected 10:00", next)
+	}
+}
+
+func TestSchedulerRecordsEnqueueFailures(t *testing.T) {
+	store := newMemoryScheduleStore()
+	queue := newTestQueue(&fakeCrawler{}, newMemoryStorage())
+	scheduler := NewScheduler(config.SchedulerConfig{}, store, queue)
+	ctx := context.Background()
+
+	tick := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	store.SaveSchedule(ctx, &models.Schedule{ID: "stopped", URL: "https://example.com/", Cron: "0 9 * * *", Timezone: "UTC", NextRunAt: tick})
+
+	queue.Stop()
+	scheduler.runDue(ctx, tick)
+
+	schedule := store.get("stopped")
+	if schedule.LastError == nil || *schedule.LastError != ErrQueueStopped.Error() {
+		t.Errorf("last error = %v, expected %q", schedule.LastError, ErrQueueStopped.Error())
+	}
+	if !schedule.NextRunAt.After(tick) {
+		t.Errorf("next run = %s was not advanced past the failed tick", schedule.NextRunAt)
+	}
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+const (
+	// sitemapMaxURLs is the protocol limit of URLs per sitemap file
+	sitemapMaxURLs = 50000
+
+	// sitemapMaxChildren caps how many sitemaps of an index are fetched
+	sitemapMaxChildren = 50
+
+	// sitemapDefaultPriority is the protocol default when <priority> is omitted
+	sitemapDefaultPriority = 0.5
+)
+
+// sitemapLastModLayouts are the W3C datetime formats allowed in <lastmod>
+var sitemapLastModLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// SitemapFetcher downloads and parses sitemaps and sitemap indexes
+type SitemapFetcher struct {
+	client         *http.Client
+	userAgent      string
+	maxContentSize int64
+	validator      *URLValidator
+}
+
+// sitemapDocument matches both <urlset> and <sitemapindex> roots
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLocation `xml:"url"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+// sitemapLocation is a <url> or <sitemap> element
+type sitemapLocation struct {
+	Loc      string `xml:"loc"`
+	LastMod  string `xml:"lastmod"`
+	Priority string `xml:"priority"`
+}
+
+// SitemapFilter restricts which sitemap URLs are crawled
+type SitemapFilter struct {
+	PathPrefix string
+	Pattern    *regexp.Regexp
+}
+
+// NewSitemapFetcher creates a new sitemap fetcher using configuration
+func NewSitemapFetcher(cfg config.CrawlerConfig, validator *URLValidator) *SitemapFetcher {
+	return &SitemapFetcher{
+		client:         newPolicyHTTPClient(cfg, validator),
+		userAgent:      cfg.UserAgent,
+		maxContentSize: cfg.MaxContentSize,
+		validator:      validator,
+	}
+}
+
+// Fetch returns the entries of a sitemap, following one level of sitemap index.
+// Entries are deduplicated and ordered by descending priority.
+func (sf *SitemapFetcher) Fetch(ctx context.Context, sitemapURL string) ([]models.SitemapEntry, error) {
+	if err := sf.validator.Validate(ctx, sitemapURL); err != nil {
+		return nil, fmt.Errorf("invalid sitemap URL: %w", err)
+	}
+
+	doc, err := sf.fetchDocument(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := doc.URLs
+	if doc.XMLName.Local == "sitemapindex" {
+		locations = nil
+		for i, child := range doc.Sitemaps {
+			if i >= sitemapMaxChildren {
+				log.Printf("Sitemap index %s lists more than %d sitemaps, ignoring the rest", sitemapURL, sitemapMaxChildren)
+				break
+			}
+
+			childURL := strings.TrimSpace(child.Loc)
+			if err := sf.validator.Validate(ctx, childURL); err != nil {
+				log.Printf("Skipping sitemap %s: %v", childURL, err)
+				continue
+			}
+
+			childDoc, err := sf.fetchDocument(ctx, childURL)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				log.Printf("Skipping sitemap %s: %v", childURL, err)
+				continue
+			}
+			locations = append(locations, childDoc.URLs...)
+		}
+	}
+
+	entries := make([]models.SitemapEntry, 0, len(locations))
+	seen := make(map[string]bool)
+	for _, location := range locations {
+		entry, ok := parseSitemapLocation(location)
+		if !ok || seen[entry.URL] {
+			continue
+		}
+		seen[entry.URL] = true
+		entries = append(entries, entry)
+		if len(entries) >= sitemapMaxURLs {
+			break
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Priority > entries[j].Priority
+	})
+
+	return entries, nil
+}
+
+// fetchDocument downloads a single sitemap file, decompressing gzip content
+func (sf *SitemapFetcher) fetchDocument(ctx context.Context, sitemapURL string) (*sitemapDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("User-Agent", sf.userAgent)
+	req.Header.Set("Accept", "application/xml,text/xml;q=0.9,*/*;q=0.8")
+
+	resp, err := sf.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sitemap: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to fetch sitemap: received HTTP status %s", resp.Status)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	reader := io.Reader(body)
+
+	// Sitemaps are often served as .xml.gz with a generic content type, so sniff the magic bytes
+	if magic, _ := body.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	if sf.maxContentSize > 0 {
+		// Limit the decompressed size as well so gzip bombs are cut off
+		reader = io.LimitReader(reader, sf.maxContentSize+1)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sitemap: %w", err)
+	}
+	if sf.maxContentSize > 0 && int64(len(data)) > sf.maxContentSize {
+		return nil, fmt.Errorf("sitemap exceeds maximum of %d bytes", sf.maxContentSize)
+	}
+
+	return parseSitemap(data)
+}
+
+// parseSitemap decodes a <urlset> or <sitemapindex> document
+func parseSitemap(data []byte) (*sitemapDocument, error) {
+	doc := &sitemapDocument{}
+	if err := xml.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+
+	switch doc.XMLName.Local {
+	case "urlset", "sitemapindex":
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("failed to parse sitemap: unexpected root element <%s>", doc.XMLName.Local)
+	}
+}
+
+// parseSitemapLocation converts a <url> element into an entry, dropping invalid locations
+func parseSitemapLocation(location sitemapLocation) (models.SitemapEntry, bool) {
+	loc := strings.TrimSpace(location.Loc)
+	u, err := url.Parse(loc)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.SitemapEntry{}, false
+	}
+	u.Fragment = ""
+
+	entry := models.SitemapEntry{
+		URL:      u.String(),
+		Priority: sitemapDefaultPriority,
+	}
+
+	if priority, err := strconv.ParseFloat(strings.TrimSpace(location.Priority), 64); err == nil && priority >= 0 && priority <= 1 {
+		entry.Priority = priority
+	}
+
+	lastMod := strings.TrimSpace(location.LastMod)
+	for _, layout := range sitemapLastModLayouts {
+		if t, err := time.Parse(layout, lastMod); err == nil {
+			entry.LastMod = &t
+			break
+		}
+	}
+
+	return entry, true
+}
+
+// Match reports whether a sitemap URL passes the filter
+func (f SitemapFilter) Match(entryURL string) bool {
+	if f.PathPrefix != "" {
+		u, err := url.Parse(entryURL)
+		if err != nil || !strings.HasPrefix(u.Path, f.PathPrefix) {
+			return false
+		}
+	}
+
+	if f.Pattern != nil && !f.Pattern.MatchString(entryURL) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+)
+
+func newTestSitemapFetcher() *SitemapFetcher {
+	cfg := config.CrawlerConfig{
+		Timeout:        5 * time.Second,
+		UserAgent:      "test-agent",
+		MaxRedirects:   5,
+		MaxContentSize: 1024 * 1024,
+	}
+	return NewSitemapFetcher(cfg, NewURLValidator(cfg))
+}
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatalf("failed to gzip: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSitemapFetch(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>` + srv.URL + `/low</loc><priority>0.2</priority></url>
+  <url><loc> ` + srv.URL + `/high </loc><lastmod>2024-05-01</lastmod><priority>0.9</priority></url>
+  <url><loc>` + srv.URL + `/default</loc><lastmod>2024-05-01T10:30:00+02:00</lastmod></url>
+  <url><loc>` + srv.URL + `/high</loc></url>
+  <url><loc>ftp://example.com/file</loc></url>
+</urlset>`))
+		case "/sitemap_index.xml":
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>` + srv.URL + `/posts.xml.gz</loc></sitemap>
+  <sitemap><loc>` + srv.URL + `/missing.xml</loc></sitemap>
+</sitemapindex>`))
+		case "/posts.xml.gz":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(gzipBytes(t, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>`+srv.URL+`/blog/one</loc></url>
+  <url><loc>`+srv.URL+`/blog/two</loc></url>
+  <url><loc>`+srv.URL+`/about</loc></url>
+</urlset>`))
+		case "/not-a-sitemap.xml":
+			w.Write([]byte(`<rss><channel></channel></rss>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	sf := newTestSitemapFetcher()
+
+	t.Run("urlset", func(t *testing.T) {
+		entries, err := sf.Fetch(context.Background(), srv.URL+"/sitemap.xml")
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+
+		expected := []string{srv.URL + "/high", srv.URL + "/default", srv.URL + "/low"}
+		if len(entries) != len(expected) {
+			t.Fatalf("got %d entries, expected %d: %+v", len(entries), len(expected), entries)
+		}
+		for i, url := range expected {
+			if entries[i].URL != url {
+				t.Errorf("entry %d = %s, expected %s", i, entries[i].URL, url)
+			}
+		}
+		if entries[0].Priority != 0.9 || entries[1].Priority != sitemapDefaultPriority {
+			t.Errorf("unexpected priorities: %+v", entries)
+		}
+		if entries[0].LastMod == nil || !entries[0].LastMod.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("lastmod = %v, expected 2024-05-01", entries[0].LastMod)
+		}
+		if entries[1].LastMod == nil || entries[1].LastMod.UTC().Hour() != 8 {
+			t.Errorf("lastmod = %v, expected 08:30 UTC", entries[1].LastMod)
+		}
+	})
+
+	t.Run("gzipped index", func(t *testing.T) {
+		entries, err := sf.Fetch(context.Background(), srv.URL+"/sitemap_index.xml")
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("got %d entries, expected 3: %+v", len(entries), entries)
+		}
+
+		filter := SitemapFilter{PathPrefix: "/blog/", Pattern: regexp.MustCompile(`two$`)}
+		var matched []string
+		for _, entry := range entries {
+			if filter.Match(entry.URL) {
+				matched = append(matched, entry.URL)
+			}
+		}
+		if len(matched) != 1 || matched[0] != srv.URL+"/blog/two" {
+			t.Errorf("filter matched %v, expected only /blog/two", matched)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		if _, err := sf.Fetch(context.Background(), srv.URL+"/not-a-sitemap.xml"); err == nil {
+			t.Error("expected an error for a non-sitemap document")
+		}
+		if _, err := sf.Fetch(context.Background(), srv.URL+"/missing.xml"); err == nil {
+			t.Error("expected an error for a missing sitemap")
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrForbiddenTarget is returned when a URL resolves to a private or reserved address
+var ErrForbiddenTarget = errors.New("target address is not allowed")
+
+// forbiddenNetworks lists the address ranges crawls may never reach unless allowlisted
+var forbiddenNetworks = mustParseCIDRs(
+	// IPv4
+	"0.0.0.0/8",       // "this" network
+	"10.0.0.0/8",      // RFC1918
+	"100.64.0.0/10",   // carrier-grade NAT, includes 100.100.100.200 metadata
+	"127.0.0.0/8",     // loopback
+	"169.254.0.0/16",  // link-local, includes 169.254.169.254 metadata
+	"172.16.0.0/12",   // RFC1918
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // TEST-NET-1
+	"192.168.0.0/16",  // RFC1918
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // TEST-NET-2
+	"203.0.113.0/24",  // TEST-NET-3
+	"224.0.0.0/4",     // multicast
+	"240.0.0.0/4",     // reserved, includes broadcast
+	// IPv6
+	"::/128",        // unspecified
+	"::1/128",       // loopback
+	"64:ff9b::/96",  // NAT64
+	"100::/64",      // discard
+	"2001:db8::/32", // documentation
+	"fc00::/7",      // unique local, includes fd00:ec2::254 metadata
+	"fe80::/10",     // link-local
+	"ff00::/8",      // multicast
+)
+
+// TargetGuard prevents crawls from reaching internal infrastructure (SSRF protection).
+// It checks resolved addresses at validation time and pins the checked address when
+// dialing, so a DNS answer cannot change between the check and the connection.
+type TargetGuard struct {
+	allowedNetworks []*net.IPNet
+	allowedHosts    map[string]bool
+	resolver        *net.Resolver
+	dialer          *net.Dialer
+}
+
+// NewTargetGuard creates a new target guard. The allowlist may contain IPs, CIDR
+// ranges or hostnames of internal targets that are legitimately crawled.
+func NewTargetGuard(allowlist []string) *TargetGuard {
+	guard := &TargetGuard{
+		allowedHosts: make(map[string]bool),
+		resolver:     net.DefaultResolver,
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+	}
+
+	for _, entry := range allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			guard.allowedNetworks = append(guard.allowedNetworks, network)
+			continue
+		}
+
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			guard.allowedNetworks = append(guard.allowedNetworks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		guard.allowedHosts[strings.TrimSuffix(entry, ".")] = true
+	}
+
+	return guard
+}
+
+// CheckHost resolves a hostname and returns ErrForbiddenTarget if any address is off limits
+func (g *TargetGuard) CheckHost(ctx context.Context, host string) error {
+	_, err := g.resolve(ctx, host)
+	return err
+}
+
+// DialContext resolves the address, verifies it and connects to the verified IP only
+func (g *TargetGuard) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := g.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := g.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// resolve looks up the host and verifies every returned address
+func (g *TargetGuard) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+
+	if ip := net.ParseIP(host); ip != nil {
+		if err := g.checkIP(host, ip); err != nil {
+			return nil, err
+		}
+		return []net.IP{ip}, nil
+	}
+
+	addrs, err := g.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		if !g.allowedHosts[host] {
+			if err := g.checkIP(host, addr.IP); err != nil {
+				return nil, err
+			}
+		}
+		ips = append(ips, addr.IP)
+	}
+
+	return ips, nil
+}
+
+// checkIP returns ErrForbiddenTarget for addresses in forbidden ranges that are not allowlisted
+func (g *TargetGuard) checkIP(host string, ip net.IP) error {
+	// Treat IPv4-mapped IPv6 addresses as IPv4
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	for _, network := range g.allowedNetworks {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+
+	for _, network := range forbiddenNetworks {
+		if network.Contains(ip) {
+			log.Printf("Blocked request to %s: resolves to %s in %s", host, ip, network)
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenTarget, host, ip)
+		}
+	}
+
+	return nil
+}
+
+// mustParseCIDRs parses a list of CIDR ranges, panicking on invalid input
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(fmt.Sprintf("invalid CIDR %q: %v", cidr, err))
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+)
+
+func TestTargetGuardCheckIP(t *testing.T) {
+	guard := NewTargetGuard(nil)
+
+	tests := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.20.0.5", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"fd00:ec2::254", false},
+		{"fe80::1", false},
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+	}
+
+	for _, tt := range tests {
+		err := guard.checkIP(tt.ip, net.ParseIP(tt.ip))
+		if tt.allowed && err != nil {
+			t.Errorf("checkIP(%s) returned %v, expected allowed", tt.ip, err)
+		}
+		if !tt.allowed && !errors.Is(err, ErrForbiddenTarget) {
+			t.Errorf("checkIP(%s) returned %v, expected ErrForbiddenTarget", tt.ip, err)
+		}
+	}
+}
+
+func TestTargetGuardAllowlist(t *testing.T) {
+	guard := NewTargetGuard([]string{"10.20.0.0/16", "192.168.1.10", "localhost"})
+
+	if err := guard.checkIP("10.20.5.5", net.ParseIP("10.20.5.5")); err != nil {
+		t.Errorf("expected allowlisted CIDR to pass, got %v", err)
+	}
+	if err := guard.checkIP("192.168.1.10", net.ParseIP("192.168.1.10")); err != nil {
+		t.Errorf("expected allowlisted IP to pass, got %v", err)
+	}
+	if err := guard.checkIP("192.168.1.11", net.ParseIP("192.168.1.11")); !errors.Is(err, ErrForbiddenTarget) {
+		t.Errorf("expected neighbouring IP to be rejected, got %v", err)
+	}
+	if err := guard.CheckHost(context.Background(), "localhost"); err != nil {
+		t.Errorf("expected allowlisted host to pass, got %v", err)
+	}
+}
+
+func TestTargetGuardDialer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg := config.CrawlerConfig{Timeout: 5 * time.Second, MaxRedirects: 5}
+
+	client := newCrawlerHTTPClient(cfg, NewTargetGuard(nil))
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrForbiddenTarget) {
+		t.Fatalf("expected loopback request to be blocked, got %v", err)
+	}
+
+	client = newCrawlerHTTPClient(cfg, NewTargetGuard([]string{"127.0.0.1"}))
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("expected allowlisted loopback request to succeed, got %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"url-crawler/internal/config"
+)
+
+// URLValidator applies the URL policies shared by all crawler backends
+type URLValidator struct {
+	domains *DomainPolicy
+	guard   *TargetGuard   // nil when private network protection is disabled
+	robots  *RobotsChecker // nil when robots.txt is not respected
+}
+
+// NewURLValidator creates a new URL validator using configuration
+func NewURLValidator(cfg config.CrawlerConfig) *URLValidator {
+	validator := &URLValidator{
+		domains: NewDomainPolicy(cfg.AllowedDomains, cfg.BlockedDomains),
+	}
+	if cfg.BlockPrivateNetworks {
+		validator.guard = NewTargetGuard(cfg.PrivateNetworkAllowlist)
+	}
+	if cfg.RespectRobotsTxt {
+		validator.robots = NewRobotsChecker(cfg, validator.guard)
+	}
+	return validator
+}
+
+// Validate checks that a URL may be submitted for crawling
+func (v *URLValidator) Validate(ctx context.Context, targetURL string) error {
+	if err := validateURLFormat(targetURL); err != nil {
+		return err
+	}
+
+	return v.CheckLink(ctx, targetURL)
+}
+
+// CheckLink applies the crawl policies to an already well-formed absolute URL.
+// The DNS lookup and robots.txt fetch it may need stop when ctx is done.
+func (v *URLValidator) CheckLink(ctx context.Context, targetURL string) error {
+	if err := v.domains.Check(targetURL); err != nil {
+		return err
+	}
+
+	if v.guard != nil {
+		u, err := url.Parse(targetURL)
+		if err != nil {
+			return fmt.Errorf("invalid URL: %w", err)
+		}
+		if err := v.guard.CheckHost(ctx, u.Hostname()); err != nil {
+			return err
+		}
+	}
+
+	if v.robots != nil {
+		allowed, err := v.robots.Allowed(ctx, targetURL)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("%s is %w for user agent %s", targetURL, ErrDisallowedByRobots, v.robots.userAgent)
+		}
+	}
+
+	return nil
+}
+
+// CrawlDelay returns the robots.txt Crawl-delay for the URL's host, if any
+func (v *URLValidator) CrawlDelay(ctx context.Context, targetURL string) time.Duration {
+	if v.robots == nil {
+		return 0
+	}
+	return v.robots.CrawlDelay(ctx, targetURL)
+}
+
+// validateURLFormat performs the scheme and pattern checks shared by all crawler backends
+func validateURLFormat(targetURL string) error {
+	if targetURL == "" {
+		return fmt.Errorf("URL cannot be empty")
+	}
+
+	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
+		return fmt.Errorf("URL must start with http:// or https://")
+	}
+
+	// Basic validation for malicious patterns
+	maliciousPatterns := []string{
+		"javascript:",
+		"data:",
+		"file:",
+		"ftp:",
+	}
+
+	lowerURL := strings.ToLower(targetURL)
+	for _, pattern := range maliciousPatterns {
+		if strings.Contains(lowerURL, pattern) {
+			return fmt.Errorf("potentially malicious URL pattern detected")
+		}
+	}
+
+	return nil
+}
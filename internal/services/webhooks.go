@@ -0,0 +1,415 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Webhook request headers
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	// Defaults used when the webhook configuration leaves a value unset
+	defaultWebhookMaxAttempts  = 5
+	defaultWebhookRetryDelay   = 10 * time.Second
+	defaultWebhookTimeout      = 10 * time.Second
+	defaultWebhookPollInterval = 5 * time.Second
+
+	// webhookSecretBytes is the length of generated signing secrets before hex encoding
+	webhookSecretBytes = 32
+
+	// maxWebhookResponseSize bounds how much of a response body is read before it is discarded
+	maxWebhookResponseSize = 64 * 1024
+)
+
+// WebhookStore persists webhooks and their delivery outbox
+type WebhookStore interface {
+	GetCrawlResult(ctx context.Context, id string) (*models.CrawlResult, error)
+	SaveWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetCrawlWebhooks(ctx context.Context, owner, webhookID *string) ([]models.Webhook, error)
+	CreateWebhookDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error
+	ClaimWebhookDelivery(ctx context.Context, lease time.Duration) (*models.WebhookDelivery, *models.Webhook, error)
+	RecordWebhookAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt, retryIn time.Duration) error
+	RedeliverWebhookDelivery(ctx context.Context, id, owner string) error
+}
+
+// WebhookService notifies registered webhooks when crawls finish. Notifications
+// are written to a delivery outbox and sent by a background loop, signed with
+// HMAC-SHA256 and retried with exponential backoff. Any number of replicas may
+// send from the same outbox: a delivery is leased while its request is in flight.
+type WebhookService struct {
+	storage      WebhookStore
+	client       *http.Client
+	guard        *TargetGuard // nil when private network protection is disabled
+	userAgent    string
+	maxAttempts  int
+	retryDelay   time.Duration
+	timeout      time.Duration
+	pollInterval time.Duration
+	wake         chan struct{}
+	running      bool
+	stopped      bool
+	wg           sync.WaitGroup
+	ctx          context.Context
+	cancel       context.CancelFunc
+	mu           sync.Mutex
+}
+
+// NewWebhookService creates a webhook service. Webhook endpoints are held to the
+// crawler's private network protection, since they are supplied by API clients.
+func NewWebhookService(cfg config.WebhookConfig, crawlerCfg config.CrawlerConfig, storage WebhookStore) *WebhookService {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	service := &WebhookService{
+		storage:      storage,
+		userAgent:    crawlerCfg.UserAgent,
+		maxAttempts:  cfg.MaxAttempts,
+		retryDelay:   cfg.RetryDelay,
+		timeout:      cfg.Timeout,
+		pollInterval: cfg.PollInterval,
+		wake:         make(chan struct{}, 1),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	if service.maxAttempts <= 0 {
+		service.maxAttempts = defaultWebhookMaxAttempts
+	}
+	if service.retryDelay <= 0 {
+		service.retryDelay = defaultWebhookRetryDelay
+	}
+	if service.timeout <= 0 {
+		service.timeout = defaultWebhookTimeout
+	}
+	if service.pollInterval <= 0 {
+		service.pollInterval = defaultWebhookPollInterval
+	}
+	if crawlerCfg.BlockPrivateNetworks {
+		service.guard = NewTargetGuard(crawlerCfg.PrivateNetworkAllowlist)
+	}
+
+	clientCfg := crawlerCfg
+	clientCfg.Timeout = service.timeout
+	service.client = newCrawlerHTTPClient(clientCfg, service.guard)
+	// A redirect is reported as a failed delivery rather than followed
+	service.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return service
+}
+
+// Start begins sending pending deliveries
+func (w *WebhookService) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.running || w.stopped {
+		return
+	}
+	w.running = true
+
+	w.wg.Add(1)
+	go w.loop()
+
+	log.Printf("Webhook delivery started (max attempts: %d)", w.maxAttempts)
+}
+
+// Stop stops sending deliveries, waiting for a request in flight to finish.
+// Deliveries left pending are sent by the next instance to start.
+func (w *WebhookService) Stop() {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return
+	}
+	w.running = false
+	w.stopped = true
+	w.cancel()
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	log.Println("Webhook delivery stopped")
+}
+
+// signal wakes the delivery loop to send newly queued deliveries
+func (w *WebhookService) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Register validates and saves a webhook for the given API key. A signing
+// secret is generated when the request does not provide one.
+func (w *WebhookService) Register(ctx context.Context, owner string, scope models.WebhookScope, req models.WebhookRequest) (*models.WebhookRegistration, error) {
+	if err := w.validateURL(ctx, req.URL); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	webhook := models.Webhook{
+		ID:        uuid.New().String(),
+		Owner:     owner,
+		URL:       req.URL,
+		Secret:    secret,
+		Scope:     scope,
+		CreatedAt: time.Now(),
+	}
+	if err := w.storage.SaveWebhook(ctx, &webhook); err != nil {
+		return nil, fmt.Errorf("failed to save webhook: %w", err)
+	}
+
+	log.Printf("Registered %s webhook %s for %s", scope, webhook.ID, webhook.URL)
+	return &models.WebhookRegistration{Webhook: webhook, Secret: secret}, nil
+}
+
+// validateURL checks that a webhook endpoint is a well-formed URL outside the private network
+func (w *WebhookService) validateURL(ctx context.Context, endpoint string) error {
+	if err := validateURLFormat(endpoint); err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if w.guard == nil {
+		return nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	return w.guard.CheckHost(ctx, u.Hostname())
+}
+
+// generateWebhookSecret returns a random hex-encoded signing secret
+func generateWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// SignWebhookPayload returns the X-Webhook-Signature value for a request body
+// sent at the given Unix timestamp: the hex HMAC-SHA256 of "<timestamp>.<body>"
+// keyed with the webhook secret, prefixed with "sha256=". Receivers recompute
+// it and should reject requests with stale timestamps to prevent replays.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NotifyCrawlFinished queues a delivery of a finished crawl's result to every
+// webhook subscribed to it
+func (w *WebhookService) NotifyCrawlFinished(ctx context.Context, id string) {
+	result, err := w.storage.GetCrawlResult(ctx, id)
+	if err != nil {
+		log.Printf("Webhooks: failed to load crawl %s: %v", id, err)
+		return
+	}
+
+	var event models.WebhookEvent
+	switch result.Status {
+	case models.CrawlStatusCompleted:
+		event = models.WebhookEventCrawlCompleted
+	case models.CrawlStatusError:
+		event = models.WebhookEventCrawlError
+	default:
+		return
+	}
+
+	if result.Owner == nil && result.WebhookID == nil {
+		return
+	}
+	webhooks, err := w.storage.GetCrawlWebhooks(ctx, result.Owner, result.WebhookID)
+	if err != nil {
+		log.Printf("Webhooks: failed to find webhooks for crawl %s: %v", id, err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("Webhooks: failed to encode crawl %s: %v", id, err)
+		return
+	}
+
+	now := time.Now()
+	deliveries := make([]*models.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			ID:            uuid.New().String(),
+			WebhookID:     webhook.ID,
+			CrawlID:       id,
+			Event:         event,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+
+	if err := w.storage.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		log.Printf("Webhooks: failed to queue deliveries for crawl %s: %v", id, err)
+		return
+	}
+	w.signal()
+}
+
+// Redeliver queues a delivery to a webhook of the given API key to be sent
+// again, with a fresh set of attempts
+func (w *WebhookService) Redeliver(ctx context.Context, id, owner string) error {
+	if err := w.storage.RedeliverWebhookDelivery(ctx, id, owner); err != nil {
+		return err
+	}
+	w.signal()
+
+	log.Printf("Queued redelivery of webhook delivery %s", id)
+	return nil
+}
+
+// loop sends due deliveries until the service stops, waking on new
+// deliveries and polling for retries and deliveries queued elsewhere
+func (w *WebhookService) loop() {
+	defer w.wg.Done()
+
+	for {
+		w.sendDue(w.ctx)
+
+		select {
+		case <-w.wake:
+		case <-time.After(w.pollInterval):
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
+// sendDue claims and sends deliveries until none are due
+func (w *WebhookService) sendDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		// The lease outlasts the request so a slow endpoint is not sent the delivery twice
+		delivery, webhook, err := w.storage.ClaimWebhookDelivery(ctx, 2*w.timeout)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Webhooks: failed to claim delivery: %v", err)
+			}
+			return
+		}
+		if delivery == nil {
+			return
+		}
+		w.deliver(ctx, delivery, webhook)
+	}
+}
+
+// deliver sends one attempt of a delivery and records its outcome
+func (w *WebhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery, webhook *models.Webhook) {
+	start := time.Now()
+	attempt := &models.WebhookDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+		CreatedAt:  start,
+	}
+
+	statusCode, err := w.send(ctx, delivery, webhook, start)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+	if err != nil {
+		errorMsg := err.Error()
+		attempt.Error = &errorMsg
+	}
+
+	delivery.LastStatusCode = attempt.StatusCode
+	delivery.LastError = attempt.Error
+
+	var retryIn time.Duration
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &start
+	case delivery.Attempts >= w.maxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		log.Printf("Webhook delivery %s to %s failed after %d attempts: %v", delivery.ID, webhook.URL, delivery.Attempts, err)
+	default:
+		delivery.Status = models.WebhookDeliveryPending
+		retryIn = retryBackoff(w.retryDelay, delivery.Attempts)
+		if serverDelay := retryAfter(err); serverDelay > retryIn {
+			retryIn = serverDelay
+		}
+		log.Printf("Webhook delivery %s to %s failed (attempt %d of %d), retrying in %s: %v", delivery.ID, webhook.URL, delivery.Attempts, w.maxAttempts, retryIn.Round(time.Millisecond), err)
+	}
+
+	// The outcome is recorded even if the service is stopping
+	recordCtx, cancel := context.WithTimeout(context.Background(), storageTimeout)
+	defer cancel()
+	if err := w.storage.RecordWebhookAttempt(recordCtx, delivery, attempt, retryIn); err != nil {
+		log.Printf("Webhooks: failed to record attempt of delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// send POSTs a delivery's payload to its webhook, returning the response status
+// code (0 when no response was received) and an error unless it was 2xx
+func (w *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery, webhook *models.Webhook, at time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", w.userAgent)
+	req.Header.Set(WebhookEventHeader, string(delivery.Event))
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookResponseSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, newHTTPStatusError(resp)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,423 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"url-crawler/internal/config"
+	"url-crawler/internal/models"
+)
+
+// memoryWebhookStore is an in-memory WebhookStore for tests
+type memoryWebhookStore struct {
+	mu         sync.Mutex
+	results    map[string]*models.CrawlResult
+	webhooks   map[string]*models.Webhook
+	deliveries map[string]*models.WebhookDelivery
+	attempts   map[string][]models.WebhookDeliveryAttempt
+}
+
+func newMemoryWebhookStore() *memoryWebhookStore {
+	return &memoryWebhookStore{
+		results:    make(map[string]*models.CrawlResult),
+		webhooks:   make(map[string]*models.Webhook),
+		deliveries: make(map[string]*models.WebhookDelivery),
+		attempts:   make(map[string][]models.WebhookDeliveryAttempt),
+	}
+}
+
+func (m *memoryWebhookStore) GetCrawlResult(ctx context.Context, id string) (*models.CrawlResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result, ok := m.results[id]
+	if !ok {
+		return nil, errors.New("crawl result not found")
+	}
+	copied := *result
+	return &copied, nil
+}
+
+func (m *memoryWebhookStore) SaveWebhook(ctx context.Context, webhook *models.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copied := *webhook
+	m.webhooks[webhook.ID] = &copied
+	return nil
+}
+
+func (m *memoryWebhookStore) GetCrawlWebhooks(ctx context.Context, owner, webhookID *string) ([]models.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhooks := []models.Webhook{}
+	for _, webhook := range m.webhooks {
+		ownerMatch := webhook.Scope == models.WebhookScopeKey && owner != nil && webhook.Owner == *owner
+		if ownerMatch || (webhookID != nil && webhook.ID == *webhookID) {
+			webhooks = append(webhooks, *webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *memoryWebhookStore) CreateWebhookDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		copied := *delivery
+		m.deliveries[delivery.ID] = &copied
+	}
+	return nil
+}
+
+func (m *memoryWebhookStore) ClaimWebhookDelivery(ctx context.Context, lease time.Duration) (*models.WebhookDelivery, *models.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, delivery := range m.deliveries {
+		if delivery.Status != models.WebhookDeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		delivery.Attempts++
+		delivery.NextAttemptAt = now.Add(lease)
+
+		claimed := *delivery
+		webhook := *m.webhooks[delivery.WebhookID]
+		return &claimed, &webhook, nil
+	}
+	return nil, nil, nil
+}
+
+func (m *memoryWebhookStore) RecordWebhookAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt, retryIn time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.deliveries[delivery.ID]
+	if !ok {
+		return errors.New("webhook delivery not found")
+	}
+	m.attempts[delivery.ID] = append(m.attempts[delivery.ID], *attempt)
+	stored.Status = delivery.Status
+	stored.LastStatusCode = delivery.LastStatusCode
+	stored.LastError = delivery.LastError
+	stored.DeliveredAt = delivery.DeliveredAt
+	stored.NextAttemptAt = time.Now().Add(retryIn)
+	return nil
+}
+
+func (m *memoryWebhookStore) RedeliverWebhookDelivery(ctx context.Context, id, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delivery, ok := m.deliveries[id]
+	if !ok || m.webhooks[delivery.WebhookID].Owner != owner {
+		return errors.New("webhook delivery not found")
+	}
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	return nil
+}
+
+// delivery returns a snapshot of a delivery and its attempt log
+func (m *memoryWebhookStore) delivery(id string) (models.WebhookDelivery, []models.WebhookDeliveryAttempt) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return *m.deliveries[id], append([]models.WebhookDeliveryAttempt(nil), m.attempts[id]...)
+}
+
+// deliveryIDs returns the IDs of all queued deliveries
+func (m *memoryWebhookStore) deliveryIDs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := []string{}
+	for id := range m.deliveries {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// newTestWebhookService creates a webhook service that may reach test servers on localhost
+func newTestWebhookService(store WebhookStore, maxAttempts int) *WebhookService {
+	return NewWebhookService(config.WebhookConfig{
+		MaxAttempts:  maxAttempts,
+		RetryDelay:   time.Millisecond,
+		Timeout:      time.Second,
+		PollInterval: 5 * time.Millisecond,
+	}, config.CrawlerConfig{UserAgent: "test-agent"}, store)
+}
+
+// waitForDelivery waits until a delivery leaves the pending state
+func waitForDelivery(t *testing.T, store *memoryWebhookStore, id string) (models.WebhookDelivery, []models.WebhookDeliveryAttempt) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		delivery, attempts := store.delivery(id)
+		if delivery.Status != models.WebhookDeliveryPending {
+			return delivery, attempts
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("delivery %s still pending", id)
+	return models.WebhookDelivery{}, nil
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"id":"abc"}`)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000.{\"id\":\"abc\"}"))
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := SignWebhookPayload("secret", "1700000000", body); got != expected {
+		t.Errorf("SignWebhookPayload() = %s, expected %s", got, expected)
+	}
+	if SignWebhookPayload("other", "1700000000", body) == expected {
+		t.Error("signature does not depend on the secret")
+	}
+	if SignWebhookPayload("secret", "1700000001", body) == expected {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestNotifyCrawlFinishedSelectsSubscribers(t *testing.T) {
+	store := newMemoryWebhookStore()
+	webhooks := newTestWebhookService(store, 3)
+	ctx := context.Background()
+
+	own, _ := webhooks.Register(ctx, "team-a", models.WebhookScopeKey, models.WebhookRequest{URL: "https://hooks.example.com/a"})
+	webhooks.Register(ctx, "team-b", models.WebhookScopeKey, models.WebhookRequest{URL: "https://hooks.example.com/b"})
+	crawl, _ := webhooks.Register(ctx, "team-a", models.WebhookScopeCrawl, models.WebhookRequest{URL: "https://hooks.example.com/crawl"})
+	webhooks.Register(ctx, "team-a", models.WebhookScopeCrawl, models.WebhookRequest{URL: "https://hooks.example.com/other-crawl"})
+
+	owner := "team-a"
+	store.results["done"] = &models.CrawlResult{ID: "done", Status: models.CrawlStatusError, Owner: &owner, WebhookID: &crawl.ID}
+	store.results["queued"] = &models.CrawlResult{ID: "queued", Status: models.CrawlStatusQueued, Owner: &owner}
+
+	webhooks.NotifyCrawlFinished(ctx, "queued")
+	if ids := store.deliveryIDs(); len(ids) != 0 {
+		t.Fatalf("unfinished crawl queued %d deliveries", len(ids))
+	}
+
+	webhooks.NotifyCrawlFinished(ctx, "done")
+
+	var targets []string
+	for _, id := range store.deliveryIDs() {
+		delivery, _ := store.delivery(id)
+		targets = append(targets, delivery.WebhookID)
+		if delivery.Event != models.WebhookEventCrawlError {
+			t.Errorf("delivery event = %s, expected %s", delivery.Event, models.WebhookEventCrawlError)
+		}
+	}
+	expected := []string{own.ID, crawl.ID}
+	sort.Strings(targets)
+	sort.Strings(expected)
+	if len(targets) != 2 || targets[0] != expected[0] || targets[1] != expected[1] {
+		t.Errorf("deliveries went to %v, expected %v", targets, expected)
+	}
+}
+
+func TestWebhookDeliveryRetriesUntilSuccess(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	signatureValid := true
+	var event, userAgent string
+
+	store := newMemoryWebhookStore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests++
+		expected := SignWebhookPayload("a-very-secret-secret", r.Header.Get(WebhookTimestampHeader), body)
+		signatureValid = signatureValid && r.Header.Get(WebhookSignatureHeader) == expected
+		event, userAgent = r.Header.Get(WebhookEventHeader), r.UserAgent()
+
+		if requests < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhooks := newTestWebhookService(store, 5)
+	ctx := context.Background()
+	webhook, err := webhooks.Register(ctx, "team-a", models.WebhookScopeKey, models.WebhookRequest{
+		URL:    server.URL,
+		Secret: "a-very-secret-secret",
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if webhook.Secret != "a-very-secret-secret" {
+		t.Errorf("registration secret = %q, expected the requested one", webhook.Secret)
+	}
+
+	owner := "team-a"
+	store.results["crawl"] = &models.CrawlResult{ID: "crawl", Title: "Example", Status: models.CrawlStatusCompleted, Owner: &owner}
+
+	webhooks.Start()
+	defer webhooks.Stop()
+	webhooks.NotifyCrawlFinished(ctx, "crawl")
+
+	ids := store.deliveryIDs()
+	if len(ids) != 1 {
+		t.Fatalf("queued %d deliveries, expected 1", len(ids))
+	}
+	delivery, attempts := waitForDelivery(t, store, ids[0])
+
+	if delivery.Status != models.WebhookDeliverySucceeded || delivery.DeliveredAt == nil {
+		t.Errorf("delivery = %+v, expected succeeded", delivery)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("logged %d attempts, expected 3", len(attempts))
+	}
+	for i, code := range []int{500, 500, 204} {
+		if attempts[i].Attempt != i+1 || attempts[i].StatusCode == nil || *attempts[i].StatusCode != code {
+			t.Errorf("attempt %d = %+v, expected status %d", i+1, attempts[i], code)
+		}
+	}
+
+	var payload models.CrawlResult
+	if err := json.Unmarshal(delivery.Payload, &payload); err != nil || payload.ID != "crawl" || payload.Title != "Example" {
+		t.Errorf("payload = %s, expected the crawl result", delivery.Payload)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !signatureValid {
+		t.Error("a request carried an invalid signature")
+	}
+	if event != string(models.WebhookEventCrawlCompleted) || userAgent != "test-agent" {
+		t.Errorf("headers: event %q, user agent %q", event, userAgent)
+	}
+}
+
+func TestWebhookDeliveryFailsAndRedelivers(t *testing.T) {
+	var mu sync.Mutex
+	healthy := false
+
+	store := newMemoryWebhookStore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !healthy {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	webhooks := newTestWebhookService(store, 2)
+	ctx := context.Background()
+	registration, err := webhooks.Register(ctx, "team-a", models.WebhookScopeCrawl, models.WebhookRequest{URL: server.URL})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if len(registration.Secret) != 2*webhookSecretBytes {
+		t.Errorf("generated secret %q, expected %d hex characters", registration.Secret, 2*webhookSecretBytes)
+	}
+
+	store.results["crawl"] = &models.CrawlResult{ID: "crawl", Status: models.CrawlStatusCompleted, WebhookID: &registration.ID}
+
+	webhooks.Start()
+	defer webhooks.Stop()
+	webhooks.NotifyCrawlFinished(ctx, "crawl")
+
+	id := store.deliveryIDs()[0]
+	delivery, attempts := waitForDelivery(t, store, id)
+	if delivery.Status != models.WebhookDeliveryFailed || len(attempts) != 2 {
+		t.Fatalf("delivery = %+v after %d attempts, expected failed after 2", delivery, len(attempts))
+	}
+	if delivery.LastStatusCode == nil || *delivery.LastStatusCode != http.StatusBadGateway {
+		t.Errorf("last status code = %v, expected 502", delivery.LastStatusCode)
+	}
+
+	if err := webhooks.Redeliver(ctx, id, "team-b"); err == nil {
+		t.Error("Redeliver() by another API key succeeded")
+	}
+
+	mu.Lock()
+	healthy = true
+	mu.Unlock()
+
+	if err := webhooks.Redeliver(ctx, id, "team-a"); err != nil {
+		t.Fatalf("Redeliver() error = %v", err)
+	}
+	delivery, attempts = waitForDelivery(t, store, id)
+	if delivery.Status != models.WebhookDeliverySucceeded || len(attempts) != 3 {
+		t.Errorf("delivery = %+v after %d attempts, expected redelivered on attempt 3", delivery, len(attempts))
+	}
+}
+
+func TestRegisterWebhookRejectsPrivateTargets(t *testing.T) {
+	webhooks := NewWebhookService(config.WebhookConfig{}, config.CrawlerConfig{BlockPrivateNetworks: true}, newMemoryWebhookStore())
+
+	_, err := webhooks.Register(context.Background(), "team-a", models.WebhookScopeKey, models.WebhookRequest{URL: "http://127.0.0.1:8080/hook"})
+	if !errors.Is(err, ErrForbiddenTarget) {
+		t.Errorf("Register() error = %v, expected ErrForbiddenTarget", err)
+	}
+}
+
+// recordingNotifier records the crawls it is told about
+type recordingNotifier struct {
+	finished chan string
+}
+
+func (n *recordingNotifier) NotifyCrawlFinished(ctx context.Context, id string) {
+	n.finished <- id
+}
+
+func TestQueueNotifiesFinishedCrawls(t *testing.T) {
+	storage := newMemoryStorage()
+	notifier := &recordingNotifier{finished: make(chan string, 1)}
+	queue := NewQueueServiceWithConfig(config.QueueConfig{Workers: 1, BufferSize: 10}, &fakeCrawler{}, storage)
+	queue.SetNotifier(notifier)
+	queue.Start()
+	defer queue.Stop()
+
+	result, err := queue.EnqueueURL(context.Background(), "https://example.com/", EnqueueOptions{Owner: "team-a", WebhookID: "hook"})
+	if err != nil {
+		t.Fatalf("EnqueueURL() error = %v", err)
+	}
+
+	select {
+	case id := <-notifier.finished:
+		if id != result.ID {
+			t.Errorf("notified about %s, expected %s", id, result.ID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("finished crawl was not notified")
+	}
+
+	finished, err := storage.GetCrawlResult(context.Background(), result.ID)
+	if err != nil {
+		t.Fatalf("GetCrawlResult() error = %v", err)
+	}
+	if finished.Status != models.CrawlStatusCompleted {
+		t.Errorf("status = %s, expected completed", finished.Status)
+	}
+	if finished.Owner == nil || *finished.Owner != "team-a" || finished.WebhookID == nil || *finished.WebhookID != "hook" {
+		t.Errorf("owner %v, webhook %v not kept on the finished result", finished.Owner, finished.WebhookID)
+	}
+}